@@ -3,5 +3,12 @@ package main
 import "github.com/rubenv/sitegen/sitegen"
 
 func main() {
+	sitegen.Version = Version
+	if VersionPrerelease != "" {
+		sitegen.Version += "-" + VersionPrerelease
+	}
+	if GitCommit != "" {
+		sitegen.Version += " (" + GitCommit + ")"
+	}
 	sitegen.Start()
 }
@@ -0,0 +1,131 @@
+package sitegen
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+)
+
+// archiveFormat returns the format of an archive from its name: zip, tar.gz
+// or tar, "" if it's none of them.
+func archiveFormat(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// archiveOutput packs the generated files into the configured archive
+// (.zip, .tar.gz, .tgz or .tar).
+func (s *Site) archiveOutput(files fs.FS) error {
+	target := s.Config.Archive
+	format := archiveFormat(target)
+	if format == "" {
+		return fmt.Errorf("Unknown archive format: %s, should be .zip, .tar.gz or .tar", target)
+	}
+
+	var add func(name string, info fs.FileInfo, data []byte) error
+	var finish func() error
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "zip":
+		w := zip.NewWriter(f)
+		add = func(name string, info fs.FileInfo, data []byte) error {
+			header, err := zip.FileInfoHeader(info)
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			header.Method = zip.Deflate
+			header.Modified = s.BuildInfo.Time
+			if info.IsDir() {
+				header.Name += "/"
+				header.Method = zip.Store
+			}
+			entry, err := w.CreateHeader(header)
+			if err != nil || info.IsDir() {
+				return err
+			}
+			_, err = entry.Write(data)
+			return err
+		}
+		finish = w.Close
+	default:
+		var z *gzip.Writer
+		var w *tar.Writer
+		if format == "tar" {
+			w = tar.NewWriter(f)
+		} else {
+			z = gzip.NewWriter(f)
+			w = tar.NewWriter(z)
+		}
+		add = func(name string, info fs.FileInfo, data []byte) error {
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = name
+			header.ModTime = s.BuildInfo.Time
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			err = w.WriteHeader(header)
+			if err != nil || info.IsDir() {
+				return err
+			}
+			_, err = w.Write(data)
+			return err
+		}
+		finish = func() error {
+			err := w.Close()
+			if err == nil && z != nil {
+				err = z.Close()
+			}
+			return err
+		}
+	}
+
+	log.Printf("==> Archiving to %s\n", target)
+	err = fs.WalkDir(files, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || name == "." {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		var data []byte
+		if !entry.IsDir() {
+			data, err = fs.ReadFile(files, name)
+			if err != nil {
+				return err
+			}
+		}
+		return add(name, info, data)
+	})
+	if err != nil {
+		return err
+	}
+	err = finish()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
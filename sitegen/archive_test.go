@@ -0,0 +1,74 @@
+package sitegen
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "blog"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "index.md"), []byte("home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "blog", "post.md"), []byte("post"), 0644))
+
+	// Left over from an earlier build.
+	out := filepath.Join(dir, "static")
+	ok(t, os.MkdirAll(out, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(out, "deleted.html"), []byte("old"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = out
+	ok(t, os.MkdirAll(config.TemplateDir, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "page.html"), []byte(`{{.Content}}`), 0644))
+	site := NewSite(config)
+
+	config.Archive = filepath.Join(dir, "site.zip")
+	ok(t, site.Build())
+	z, err := zip.OpenReader(config.Archive)
+	ok(t, err)
+	defer z.Close()
+	names := make([]string, 0)
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+	equals(t, []string{"blog/", "blog/post.html", "index.html"}, names)
+
+	_, err = os.Stat(filepath.Join(out, "index.html"))
+	assert(t, os.IsNotExist(err), "Archive builds shouldn't write to the output directory")
+
+	config.Archive = filepath.Join(dir, "site.tar.gz")
+	ok(t, site.Build())
+	f, err := os.Open(config.Archive)
+	ok(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	ok(t, err)
+	r := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		ok(t, err)
+		data, err := ioutil.ReadAll(r)
+		ok(t, err)
+		files[header.Name] = string(data)
+	}
+	equals(t, map[string]string{"blog/": "", "blog/post.html": "<p>post</p>\n", "index.html": "<p>home</p>\n"}, files)
+
+	config.Archive = filepath.Join(dir, "site.rar")
+	assert(t, site.Build() != nil, "Expected error for an unknown format")
+}
@@ -0,0 +1,111 @@
+package sitegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// AssetRule attaches processing to the assets matching a pattern.
+type AssetRule struct {
+	// Glob pattern, matched against the path in the content directory.
+	// ** matches any number of directories: photos/**/*.jpg.
+	Match string `yaml:"match"`
+
+	// Copy the files as-is, skipping minification and other processing.
+	Verbatim bool `yaml:"verbatim"`
+
+	// Scale images down so neither side exceeds this many pixels.
+	MaxSize int `yaml:"maxSize"`
+
+	// Remove EXIF and other metadata from images, apart from the tags in
+	// the imageMetadata keep list.
+	StripMetadata bool `yaml:"stripMetadata"`
+
+	// Write a sha256sum-style checksum file (name.sha256) next to the asset.
+	Checksum bool `yaml:"checksum"`
+}
+
+func (r *AssetRule) transforms() bool {
+	return r.MaxSize > 0 || r.StripMetadata
+}
+
+// apply runs the processing steps of the rule on an asset.
+func (r *AssetRule) apply(data []byte) ([]byte, error) {
+	if r.MaxSize > 0 {
+		return resizeImage(data, r.MaxSize)
+	}
+	return data, nil
+}
+
+// assetRule returns the first rule that matches an asset, or nil.
+func (s *Site) assetRule(c *ContentItem) *AssetRule {
+	for i, rule := range s.Config.AssetRules {
+		if matchGlob(rule.Match, c.Path) {
+			return &s.Config.AssetRules[i]
+		}
+	}
+	return nil
+}
+
+// addChecksums adds the checksum files requested by the asset rules.
+func (s *Site) addChecksums(root *ContentItem) {
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+				continue
+			}
+			if v.Type != Asset {
+				continue
+			}
+			if rule := s.assetRule(v); rule == nil || !rule.Checksum {
+				continue
+			}
+
+			asset := v
+			c.addGenerated(asset.Filename+".sha256", Metadata{}, func() ([]byte, error) {
+				data, err := s.assetData(asset)
+				if err != nil {
+					return nil, err
+				}
+				sum := sha256.Sum256(data)
+				return []byte(fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), asset.Filename)), nil
+			})
+		}
+	}
+	walk(root)
+}
+
+// matchGlob matches a slash-separated path against a glob pattern, where *
+// and ? don't cross directories but ** does.
+func matchGlob(pattern, name string) bool {
+	re := &strings.Builder{}
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				re.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				re.WriteString(".*")
+				i++
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+
+	match, err := regexp.MatchString(re.String(), path.Clean(name))
+	return err == nil && match
+}
@@ -0,0 +1,52 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	assert(t, matchGlob("photos/**", "photos/a.jpg"), "Expected match")
+	assert(t, matchGlob("photos/**", "photos/2024/a.jpg"), "Expected match")
+	assert(t, matchGlob("**/*.jpg", "a.jpg"), "Expected match")
+	assert(t, matchGlob("**/*.jpg", "photos/2024/a.jpg"), "Expected match")
+	assert(t, matchGlob("photos/*.jpg", "photos/a.jpg"), "Expected match")
+	assert(t, !matchGlob("photos/*.jpg", "photos/2024/a.jpg"), "Unexpected match")
+	assert(t, !matchGlob("photos/**", "downloads/a.zip"), "Unexpected match")
+	assert(t, matchGlob("file?.txt", "file1.txt"), "Expected match")
+	assert(t, !matchGlob("a.b", "axb"), "Unexpected match")
+}
+
+func TestAssetRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "downloads"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "downloads", "app.js"), []byte("var  a = 1;"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Minify = map[string]bool{"js": true}
+	config.AssetRules = []AssetRule{
+		{Match: "downloads/**", Verbatim: true, Checksum: true},
+	}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.addChecksums(root)
+
+	downloads := root.child("downloads")
+	app := downloads.child("app.js")
+	assert(t, !site.transformsAsset(app), "Verbatim asset should be copied")
+	data, err := site.assetData(app)
+	ok(t, err)
+	equals(t, string(data), "var  a = 1;")
+
+	sum, err := downloads.child("app.js.sha256").generate()
+	ok(t, err)
+	equals(t, string(sum), "b687c3257be0791cfac0ed07eba3fe3a3f12bab72a06c1a2efae988ca642d4df  app.js\n")
+}
@@ -0,0 +1,130 @@
+package sitegen
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// transformsAsset reports whether an asset is changed on its way to the
+// output. Other assets are copied (or linked) as-is.
+func (s *Site) transformsAsset(c *ContentItem) bool {
+	rule := s.assetRule(c)
+	if rule != nil && rule.Verbatim {
+		return c.processed != nil
+	}
+	return c.processed != nil || s.compiledAsset(c) || s.minifyFormat(c.Filename) != "" || (rule != nil && rule.transforms()) ||
+		len(s.transformersFor(c.Filename)) > 0 || s.stripsMetadata(c)
+}
+
+// stripsMetadata reports whether metadata is removed from an image, either
+// for all images or through an asset rule.
+func (s *Site) stripsMetadata(c *ContentItem) bool {
+	if !isTranscodableImage(c.Filename) {
+		return false
+	}
+	rule := s.assetRule(c)
+	return s.Config.ImageMetadata.Strip || (rule != nil && rule.StripMetadata)
+}
+
+// compiledAsset reports whether the asset is compiled from another format.
+func (s *Site) compiledAsset(c *ContentItem) bool {
+	return s.Config.Sass.Enabled && isSassFile(c.FullPath)
+}
+
+// assetData returns the contents an asset is written with.
+func (s *Site) assetData(c *ContentItem) ([]byte, error) {
+	if c.written != nil {
+		return c.written, nil
+	}
+	if c.Type == Generated {
+		data, err := c.generate()
+		if err != nil {
+			return nil, err
+		}
+		return s.minify(s.minifyFormat(c.Filename), data)
+	}
+
+	rule := s.assetRule(c)
+	if rule != nil && rule.Verbatim {
+		if c.processed != nil {
+			return c.processed, nil
+		}
+		return ioutil.ReadFile(c.FullPath)
+	}
+
+	var data []byte
+	var err error
+	if c.processed != nil {
+		data = c.processed
+	} else if s.compiledAsset(c) {
+		data, err = s.compileSass(c.FullPath)
+	} else {
+		data, err = ioutil.ReadFile(c.FullPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rule != nil {
+		data, err = rule.apply(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.FullPath, err)
+		}
+	}
+
+	if s.stripsMetadata(c) {
+		data, err = stripMetadata(data, s.Config.ImageMetadata.Keep)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.FullPath, err)
+		}
+	}
+
+	for _, t := range s.transformersFor(c.Filename) {
+		data, err = s.transform(t, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.FullPath, err)
+		}
+	}
+
+	if format := s.minifyFormat(c.Filename); format != "" {
+		data, err = s.minify(format, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// assetIntegrity returns the subresource integrity hash of an asset as it
+// is written to the output, given its path in the content directory. Used
+// as the integrity template function.
+func (s *Site) assetIntegrity(p string) (string, error) {
+	p = strings.TrimPrefix(p, "/")
+
+	s.integrityLock.Lock()
+	defer s.integrityLock.Unlock()
+
+	if hash, ok := s.integrity[p]; ok {
+		return hash, nil
+	}
+
+	asset, ok := s.assets[p]
+	if !ok {
+		return "", fmt.Errorf("Unknown asset: %s", p)
+	}
+	data, err := s.assetData(asset)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha512.Sum384(data)
+	hash := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+	if s.integrity == nil {
+		s.integrity = make(map[string]string)
+	}
+	s.integrity[p] = hash
+	return hash, nil
+}
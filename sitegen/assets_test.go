@@ -0,0 +1,32 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAssetIntegrity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("alert(1)"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.fingerprintAssets(root))
+
+	// printf 'alert(1)' | openssl dgst -sha384 -binary | base64
+	hash, err := site.assetIntegrity("/app.js")
+	ok(t, err)
+	equals(t, hash, "sha384-HT2E9NfWiuQ/w1PRai+hTyqW16NIoCGA/m8VQDUopfAtcz6YQjtsMmQd5uRbVDpW")
+
+	_, err = site.assetIntegrity("missing.js")
+	assert(t, err != nil, "Expected error for unknown asset")
+}
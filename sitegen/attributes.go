@@ -0,0 +1,327 @@
+package sitegen
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// A paragraph that is only an attribute list, or ends with one on a
+	// line of its own.
+	blockAttributesRegex     = regexp.MustCompile(`^\s*<p>\{([^{}\n]+)\}</p>\s*$`)
+	paragraphAttributesRegex = regexp.MustCompile(`\n\{([^{}\n]+)\}</p>\s*$`)
+
+	// An attribute list right after an inline element.
+	spanAttributesRegex = regexp.MustCompile(`(?:</(em|strong|del|code|a)>|<img\b[^>]*>)\{([^{}\n]+)\}`)
+
+	openingTagRegex     = regexp.MustCompile(`<[a-zA-Z][a-zA-Z0-9]*`)
+	attributeKeyRegex   = regexp.MustCompile(`^[a-zA-Z_:][-a-zA-Z0-9_:.]*$`)
+	attributeValueQuote = strings.NewReplacer("&ldquo;", `"`, "&rdquo;", `"`, "&lsquo;", `'`, "&rsquo;", `'`)
+)
+
+type attribute struct {
+	key   string
+	value string
+}
+
+// Attributes holding a URL, which attribute lists can't set: they'd allow
+// javascript: links that the Markdown renderer would refuse.
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"srcset":     true,
+	"action":     true,
+	"formaction": true,
+	"xlink:href": true,
+	"poster":     true,
+	"background": true,
+	"ping":       true,
+}
+
+// Attributes that can be set with unsafeHTML: false, besides data-* and
+// aria-* ones.
+var safeAttributes = map[string]bool{
+	"title":  true,
+	"lang":   true,
+	"dir":    true,
+	"alt":    true,
+	"width":  true,
+	"height": true,
+	"target": true,
+}
+
+// allowedAttribute reports whether an attribute list can set an attribute.
+// Event handlers and URLs are never set, without unsafe HTML only harmless
+// attributes are.
+func allowedAttribute(key string, unsafe bool) bool {
+	if strings.HasPrefix(key, "on") || urlAttributes[key] {
+		return false
+	}
+	if unsafe {
+		return true
+	}
+	return safeAttributes[key] || strings.HasPrefix(key, "data-") || strings.HasPrefix(key, "aria-")
+}
+
+// parseAttributeList parses the inside of an attribute list: .class, #id
+// and key=value (or key="some value"). Anything else means it's not an
+// attribute list. Attributes that aren't allowed (see allowedAttribute) are
+// left out.
+func parseAttributeList(in string, unsafe bool) ([]attribute, bool) {
+	in = html.UnescapeString(attributeValueQuote.Replace(in))
+
+	tokens := make([]string, 0)
+	var quote rune
+	start := -1
+	for i, r := range in {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			if start == -1 {
+				start = i
+			}
+		case r == ' ' || r == '\t':
+			if start != -1 {
+				tokens = append(tokens, in[start:i])
+				start = -1
+			}
+		default:
+			if start == -1 {
+				start = i
+			}
+		}
+	}
+	if quote != 0 {
+		return nil, false
+	}
+	if start != -1 {
+		tokens = append(tokens, in[start:])
+	}
+
+	attrs := make([]attribute, 0, len(tokens))
+	for _, token := range tokens {
+		switch {
+		case strings.HasPrefix(token, ".") && attributeKeyRegex.MatchString(token[1:]):
+			attrs = append(attrs, attribute{"class", token[1:]})
+		case strings.HasPrefix(token, "#") && attributeKeyRegex.MatchString(token[1:]):
+			attrs = append(attrs, attribute{"id", token[1:]})
+		case strings.Contains(token, "="):
+			kv := strings.SplitN(token, "=", 2)
+			if !attributeKeyRegex.MatchString(kv[0]) {
+				return nil, false
+			}
+			key := strings.ToLower(kv[0])
+			if allowedAttribute(key, unsafe) {
+				attrs = append(attrs, attribute{key, unquote(kv[1])})
+			}
+		default:
+			return nil, false
+		}
+	}
+	return attrs, len(tokens) > 0
+}
+
+// addAttributes adds attributes to the first tag in the given HTML. Classes
+// are added to the existing ones, other attributes replace them.
+func addAttributes(in []byte, attrs []attribute) []byte {
+	loc := openingTagRegex.FindIndex(in)
+	if loc == nil {
+		return in
+	}
+	end := bytes.IndexByte(in[loc[1]:], '>')
+	if end == -1 {
+		return in
+	}
+	end += loc[1]
+	if in[end-1] == '/' {
+		end--
+	}
+
+	tag := string(in[loc[0]:loc[1]])
+	existing := parseAttributes(string(in[loc[1]:end]))
+	order := make([]string, 0)
+	for _, m := range attrRegex.FindAllStringSubmatch(string(in[loc[1]:end]), -1) {
+		order = append(order, m[1])
+	}
+
+	for _, attr := range attrs {
+		value, ok := existing[attr.key]
+		switch {
+		case !ok:
+			order = append(order, attr.key)
+			existing[attr.key] = attr.value
+		case attr.key == "class":
+			existing[attr.key] = value + " " + attr.value
+		default:
+			existing[attr.key] = attr.value
+		}
+	}
+
+	out := &bytes.Buffer{}
+	out.Write(in[:loc[0]])
+	out.WriteString(tag)
+	for _, key := range order {
+		out.WriteString(" " + key + `="` + html.EscapeString(existing[key]) + `"`)
+	}
+	if in[end] == '/' {
+		out.WriteString(" ")
+	}
+	out.Write(in[end:])
+	return out.Bytes()
+}
+
+// markBlock remembers where the last block written to out starts, so an
+// attribute list after it can be added to it.
+func (r *renderer) markBlock(out *bytes.Buffer, start int) {
+	if r.lastBlock == nil {
+		r.lastBlock = make(map[*bytes.Buffer]int)
+	}
+	r.lastBlock[out] = start
+}
+
+// Paragraph adds attribute lists to the block before, or to the paragraph
+// when it ends with one.
+func (r *renderer) Paragraph(out *bytes.Buffer, text func() bool) {
+	start := out.Len()
+	r.Html.Paragraph(out, text)
+	if !r.attributes {
+		return
+	}
+
+	written := out.Bytes()[start:]
+	if m := blockAttributesRegex.FindSubmatch(written); m != nil {
+		attrs, ok := parseAttributeList(string(m[1]), r.config.unsafeHTML())
+		block, found := r.lastBlock[out]
+		if ok && found {
+			out.Truncate(start)
+			r.rewrite(out, block, func(b []byte) []byte { return addAttributes(b, attrs) })
+			return
+		}
+	}
+	if loc := paragraphAttributesRegex.FindSubmatchIndex(written); loc != nil {
+		if attrs, ok := parseAttributeList(string(written[loc[2]:loc[3]]), r.config.unsafeHTML()); ok {
+			paragraph := append(append([]byte{}, written[:loc[0]]...), written[loc[3]+1:]...)
+			out.Truncate(start)
+			out.Write(addAttributes(paragraph, attrs))
+		}
+	}
+	r.markBlock(out, start)
+}
+
+// rewrite replaces what's written to out from start.
+func (r *renderer) rewrite(out *bytes.Buffer, start int, fn func([]byte) []byte) {
+	written := fn(append([]byte{}, out.Bytes()[start:]...))
+	out.Truncate(start)
+	out.Write(written)
+}
+
+func (r *renderer) BlockQuote(out *bytes.Buffer, text []byte) {
+	start := out.Len()
+	r.Html.BlockQuote(out, text)
+	r.markBlock(out, start)
+}
+
+func (r *renderer) List(out *bytes.Buffer, text func() bool, flags int) {
+	start := out.Len()
+	r.Html.List(out, text, flags)
+	r.markBlock(out, start)
+}
+
+func (r *renderer) Table(out *bytes.Buffer, header []byte, body []byte, columnData []int) {
+	start := out.Len()
+	r.Html.Table(out, header, body, columnData)
+	r.markBlock(out, start)
+}
+
+func (r *renderer) HRule(out *bytes.Buffer) {
+	start := out.Len()
+	r.Html.HRule(out)
+	r.markBlock(out, start)
+}
+
+// addSpanAttributes adds attribute lists right after links, images,
+// emphasis and inline code to them. Code is left alone.
+func addSpanAttributes(in []byte, unsafe bool) []byte {
+	code := codeRanges(in)
+	matches := spanAttributesRegex.FindAllSubmatchIndex(in, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		if inRanges(code, m[0]) {
+			continue
+		}
+		attrs, ok := parseAttributeList(string(in[m[4]:m[5]]), unsafe)
+		if !ok {
+			continue
+		}
+
+		// The element starts at the image, or at the last opening tag
+		// before the closing one.
+		start := m[0]
+		if m[2] != -1 {
+			name := in[m[2]:m[3]]
+			start = -1
+			for _, open := range [][]byte{[]byte("<" + string(name) + ">"), []byte("<" + string(name) + " ")} {
+				if j := bytes.LastIndex(in[:m[0]], open); j > start {
+					start = j
+				}
+			}
+			if start == -1 {
+				continue
+			}
+		}
+
+		element := addAttributes(in[start:m[4]-1], attrs)
+		out := make([]byte, 0, len(in))
+		out = append(out, in[:start]...)
+		out = append(out, element...)
+		out = append(out, in[m[1]:]...)
+		in = out
+	}
+	return in
+}
+
+// codeRanges returns where the contents of code, pre and highlight elements
+// are in HTML, as start and end offsets.
+func codeRanges(in []byte) [][2]int {
+	ranges := make([][2]int, 0)
+	depth, start := 0, 0
+	for _, loc := range htmlTagRegex.FindAllSubmatchIndex(in, -1) {
+		tag := strings.ToLower(string(in[loc[4]:loc[5]]))
+		if tag != "code" && tag != "pre" && tag != "highlight" {
+			continue
+		}
+		if loc[3] > loc[2] {
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					ranges = append(ranges, [2]int{start, loc[0]})
+				}
+			}
+		} else {
+			if depth == 0 {
+				start = loc[1]
+			}
+			depth++
+		}
+	}
+	if depth > 0 {
+		ranges = append(ranges, [2]int{start, len(in)})
+	}
+	return ranges
+}
+
+// inRanges reports whether offset lies within one of the ranges.
+func inRanges(ranges [][2]int, offset int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestParseAttributeList(t *testing.T) {
+	attrs, ok := parseAttributeList(`.note #intro data-x="a b" .wide`, true)
+	equals(t, ok, true)
+	equals(t, attrs, []attribute{{"class", "note"}, {"id", "intro"}, {"data-x", "a b"}, {"class", "wide"}})
+
+	attrs, ok = parseAttributeList(`title=&ldquo;Say hi&rdquo;`, true)
+	equals(t, ok, true)
+	equals(t, attrs, []attribute{{"title", "Say hi"}})
+
+	_, ok = parseAttributeList("not an attribute list", true)
+	equals(t, ok, false)
+	_, ok = parseAttributeList(`.a "open`, true)
+	equals(t, ok, false)
+}
+
+func TestBlockAttributes(t *testing.T) {
+	input := []byte("> Careful!\n\n{.callout .warning}\n\n## Setup\n{#install}\n\nSome text.\n{.lead data-x=\"1\"}\n\nNot {.attributes} here.\n")
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<blockquote class=\"callout warning\">\n<p>Careful!</p>\n</blockquote>\n\n"+
+			"<h2 id=\"install\">Setup</h2>\n\n"+
+			"<p class=\"lead\" data-x=\"1\">Some text.</p>\n\n"+
+			"<p>Not {.attributes} here.</p>\n")
+
+	equals(t, string(renderMarkdown([]byte("Text\n\n{.x}\n"), MarkdownConfig{Extensions: map[string]bool{"attributes": false}})),
+		"<p>Text</p>\n\n<p>{.x}</p>\n")
+}
+
+func TestSpanAttributes(t *testing.T) {
+	input := []byte("A *b*{.hl} **c** [d](/d/ \"D\"){.button target=_blank} ![e](e.png){width=100} `f`{.x}\n")
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<p>A <em class=\"hl\">b</em> <strong>c</strong> <a href=\"/d/\" title=\"D\" class=\"button\" target=\"_blank\">d</a> "+
+			"<img src=\"e.png\" alt=\"e\" width=\"100\" /> <code class=\"x\">f</code></p>\n")
+}
+
+func TestSpanAttributesInCode(t *testing.T) {
+	input := []byte("```html\n<em>x</em>{.y}\n```\n\n`<em>a</em>{.b}`{.c}\n")
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<highlight language=\"html\"><em>x</em>{.y}</highlight>\n<p><code class=\"c\">&lt;em&gt;a&lt;/em&gt;{.b}</code></p>\n")
+
+	// Raw HTML in a code block isn't touched either.
+	out := addSpanAttributes([]byte("<pre><code><em>x</em>{.y}</code></pre><em>z</em>{.w}"), true)
+	equals(t, string(out), "<pre><code><em>x</em>{.y}</code></pre><em class=\"w\">z</em>")
+}
+
+func TestUnsafeAttributes(t *testing.T) {
+	input := []byte("[x](/){onmouseover=\"alert(1)\" .a} [y](/){href=\"javascript:alert(1)\"} ![z](z.png){src=evil.png style=\"color: red\"}\n")
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<p><a href=\"/\" class=\"a\">x</a> <a href=\"/\">y</a> <img src=\"z.png\" alt=\"z\" style=\"color: red\" /></p>\n")
+
+	safe := false
+	equals(t, string(renderMarkdown(input, MarkdownConfig{UnsafeHTML: &safe})),
+		"<p><a href=\"/\" class=\"a\">x</a> <a href=\"/\">y</a> <img src=\"z.png\" alt=\"z\" /></p>\n")
+	equals(t, string(renderMarkdown([]byte("Text\n{.lead onclick=x data-x=1 style=y}\n"), MarkdownConfig{UnsafeHTML: &safe})),
+		"<p class=\"lead\" data-x=\"1\">Text</p>\n")
+}
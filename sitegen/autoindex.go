@@ -0,0 +1,91 @@
+package sitegen
+
+import (
+	"path"
+	"strings"
+)
+
+// AutoIndexConfig configures the listing pages generated for directories
+// without an index page.
+type AutoIndexConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Template of the listing pages. By default, the template is looked
+	// up as for other listings (e.g. list or docs/list).
+	Template string `yaml:"template"`
+}
+
+// addIndexPages gives every directory with pages, but without an index
+// page, a listing of its pages and subdirectories.
+func (s *Site) addIndexPages(root *ContentItem) {
+	if !s.Config.AutoIndex.Enabled {
+		return
+	}
+
+	var walk func(dir *ContentItem)
+	walk = func(dir *ContentItem) {
+		for _, v := range dir.Children {
+			if v.Type == Directory {
+				walk(v)
+			}
+		}
+		items := dir.listItems()
+		if len(items) == 0 || dir.indexPage() != nil {
+			return
+		}
+
+		metadata := Metadata{
+			Title:    humanizeFilename(path.Join(dir.Path, "index")),
+			Template: s.Config.AutoIndex.Template,
+		}
+		if metadata.Title == "" {
+			metadata.Title = s.Config.Title
+		}
+		s.paginate(dir, metadata, items, 0)
+	}
+	walk(root)
+}
+
+// listItems returns what the index page of a directory lists: its pages and
+// the index pages of its subdirectories, newest first.
+func (c *ContentItem) listItems() []*ContentItem {
+	items := make([]*ContentItem, 0)
+	for _, v := range c.Children {
+		if v.Type == Directory {
+			if index := v.indexPage(); index != nil {
+				items = append(items, index)
+			}
+		} else if v.Type == Content && !isNotFoundPage(v) && path.Base(v.OutputPath()) != "index.html" {
+			items = append(items, v)
+		}
+	}
+	sortByDate(items)
+	return items
+}
+
+// isList reports whether a page lists other pages: index pages of
+// directories and paginated listings.
+func (c *ContentItem) isList() bool {
+	return c.Pager != nil || (c.Type == Content && path.Base(c.OutputPath()) == "index.html")
+}
+
+// Pages returns the pages listed on a list page: the items of the current
+// page for paginated listings, or the pages and subdirectories next to an
+// index page.
+func (c *ContentItem) Pages() []*ContentItem {
+	if c.Pager != nil {
+		return c.Pager.Items
+	}
+	if !c.isList() || c.Site == nil || c.Site.root == nil {
+		return nil
+	}
+	dir := c.Site.root
+	if d := path.Dir(c.OutputPath()); d != "." {
+		for _, part := range strings.Split(d, "/") {
+			if dir = dir.child(part); dir == nil {
+				return nil
+			}
+		}
+	}
+	return dir.listItems()
+}
@@ -0,0 +1,81 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "docs", "getting-started"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "docs", "reference"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "css"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.md"), []byte("# Home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "docs", "faq.md"), []byte("# FAQ"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "docs", "getting-started", "install.md"), []byte("# Install"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "docs", "reference", "index.md"), []byte("# Reference"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "css", "site.css"), []byte("body{}"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.AutoIndex.Enabled = true
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.addIndexPages(root)
+	root.Process()
+
+	docs := root.child("docs")
+	index := docs.child("index.html")
+	assert(t, index != nil, "Expected index page for docs")
+	equals(t, index.Metadata.Title, "Docs")
+	equals(t, index.Url, "/docs/")
+
+	started := docs.child("getting-started").child("index.html")
+	assert(t, started != nil, "Expected index page for getting-started")
+	equals(t, started.Metadata.Title, "Getting started")
+	equals(t, started.Pager.Items, []*ContentItem{docs.child("getting-started").child("install.html")})
+
+	equals(t, index.Pager.Items, []*ContentItem{
+		docs.child("faq.html"),
+		started,
+		docs.child("reference").child("index.html"),
+	})
+
+	assert(t, root.child("css").child("index.html") == nil, "Unexpected index page without pages")
+	equals(t, root.child("index.html").Metadata.Title, "Home")
+}
+
+func TestListPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog", "series"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "index.md"), []byte("# Blog"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "a.md"), []byte("---\ndate: 2024-01-01 00:00:00\n---\nA"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "b.md"), []byte("---\ndate: 2024-02-01 00:00:00\n---\nB"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "series", "index.md"), []byte("# Series"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	root.Process()
+	site.root = root
+
+	blog := root.child("blog")
+	index := blog.child("index.html")
+	assert(t, index.isList(), "Index page should be a list")
+	assert(t, !blog.child("a.html").isList(), "Leaf page should not be a list")
+	equals(t, index.Pages(), []*ContentItem{blog.child("b.html"), blog.child("a.html"), blog.child("series").child("index.html")})
+	equals(t, len(blog.child("a.html").Pages()), 0)
+}
@@ -0,0 +1,146 @@
+package sitegen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the generator, set by the sitegen binary.
+var Version string
+
+// BuildInfo describes the current build, available to templates as
+// .Site.BuildInfo.
+type BuildInfo struct {
+	// Version of sitegen that generated the site.
+	Version string
+
+	// Time at which the build started, or the pinned build time.
+	Time time.Time
+
+	// Git commit of the site sources, if they're in a git repository (or
+	// the CI says which one is built).
+	Commit string
+
+	// Version of the site, see the version setting.
+	SiteVersion string
+
+	// Build environment, from -env, $SITEGEN_ENV or the environment
+	// setting, production by default.
+	Environment string
+}
+
+func (s *Site) buildInfo() (BuildInfo, error) {
+	t, err := s.buildTime()
+	if err != nil {
+		return BuildInfo{}, err
+	}
+
+	env := s.env
+	if env == "" {
+		env = os.Getenv("SITEGEN_ENV")
+	}
+	if env == "" {
+		env = s.Config.Environment
+	}
+	if env == "" {
+		env = "production"
+	}
+
+	return BuildInfo{
+		Version:     Version,
+		Time:        t,
+		Commit:      gitCommit(),
+		SiteVersion: s.lookupSiteVersion(),
+		Environment: env,
+	}, nil
+}
+
+// BuildTime returns the time of the build, for templates as .Site.BuildTime.
+func (s *Site) BuildTime() time.Time {
+	return s.BuildInfo.Time
+}
+
+// Commit returns the git commit the site is built from, for templates as
+// .Site.Commit.
+func (s *Site) Commit() string {
+	return s.BuildInfo.Commit
+}
+
+// SiteVersion returns the version of the site, for templates as
+// .Site.SiteVersion. Not to be confused with BuildInfo.Version, the version
+// of sitegen.
+func (s *Site) SiteVersion() string {
+	return s.BuildInfo.SiteVersion
+}
+
+// lookupSiteVersion returns the version setting, $SITEGEN_VERSION or the
+// output of git describe, the first one that's there.
+func (s *Site) lookupSiteVersion() string {
+	if s.Config.Version != "" {
+		return s.Config.Version
+	}
+	if v := os.Getenv("SITEGEN_VERSION"); v != "" {
+		return v
+	}
+	out, err := exec.Command("git", "describe", "--tags", "--always").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (s *Site) isProduction() bool {
+	return s.BuildInfo.Environment == "production"
+}
+
+func (s *Site) isDevelopment() bool {
+	return s.BuildInfo.Environment == "development"
+}
+
+// Env returns the build environment, for templates as .Site.Env.
+func (s *Site) Env() string {
+	return s.BuildInfo.Environment
+}
+
+// buildTime returns the time to stamp the build with. It can be pinned with
+// $SOURCE_DATE_EPOCH or the buildTime setting for reproducible builds.
+func (s *Site) buildTime() (time.Time, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		sec, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid SOURCE_DATE_EPOCH: %w", err)
+		}
+		return time.Unix(sec, 0).In(location()), nil
+	}
+
+	if s.Config.BuildTime != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", s.Config.BuildTime, location())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid buildTime: %w", err)
+		}
+		return t, nil
+	}
+
+	return time.Now().In(location()), nil
+}
+
+// Variables CI systems put the commit that's built in, for builds outside a
+// git checkout.
+var commitVariables = []string{"GITHUB_SHA", "CI_COMMIT_SHA", "GIT_COMMIT", "COMMIT_REF"}
+
+func gitCommit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	for _, v := range commitVariables {
+		if commit := os.Getenv(v); commit != "" {
+			return commit
+		}
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package sitegen
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBuildTimePinned(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	site.Config.BuildTime = "2024-05-01 12:30:00"
+
+	bt, err := site.buildTime()
+	ok(t, err)
+	equals(t, bt.Format("2006-01-02 15:04:05"), "2024-05-01 12:30:00")
+
+	os.Setenv("SOURCE_DATE_EPOCH", "0")
+	defer os.Unsetenv("SOURCE_DATE_EPOCH")
+	bt, err = site.buildTime()
+	ok(t, err)
+	equals(t, bt.Unix(), int64(0))
+}
+
+func TestSiteVersion(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	site.Config.Version = "1.2.3"
+	equals(t, site.lookupSiteVersion(), "1.2.3")
+
+	site.Config.Version = ""
+	os.Setenv("SITEGEN_VERSION", "2.0.0")
+	defer os.Unsetenv("SITEGEN_VERSION")
+	equals(t, site.lookupSiteVersion(), "2.0.0")
+
+	site.BuildInfo, _ = site.buildInfo()
+	equals(t, site.SiteVersion(), "2.0.0")
+	equals(t, site.BuildTime(), site.BuildInfo.Time)
+	equals(t, site.Commit(), site.BuildInfo.Commit)
+}
+
+func TestBuildEnvironment(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	env := func() string {
+		info, err := site.buildInfo()
+		ok(t, err)
+		return info.Environment
+	}
+	equals(t, env(), "production")
+
+	site.Config.Environment = "development"
+	equals(t, env(), "development")
+
+	os.Setenv("SITEGEN_ENV", "production")
+	defer os.Unsetenv("SITEGEN_ENV")
+	equals(t, env(), "production")
+
+	site.env = "staging"
+	equals(t, env(), "staging")
+}
@@ -0,0 +1,60 @@
+package sitegen
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// Bundle is a JavaScript or TypeScript entry point that is bundled, with
+// everything it imports, into a single file.
+type Bundle struct {
+	// Entry point, relative to the site root (e.g. assets/js/main.ts).
+	Entry string `yaml:"entry"`
+
+	// Output file, relative to the output directory (e.g. js/main.js).
+	Output string `yaml:"output"`
+}
+
+// addBundles adds the configured bundles to the tree. They can be linked
+// to with the asset template function, using their output path.
+func (s *Site) addBundles(root *ContentItem) {
+	for _, b := range s.Config.Bundles {
+		bundle := b
+		item := root.addGenerated(bundle.Output, Metadata{}, func() ([]byte, error) {
+			return s.buildBundle(bundle)
+		})
+		s.addAsset(strings.TrimPrefix(bundle.Output, "/"), item)
+	}
+}
+
+// buildBundle runs esbuild on a bundle. Production builds are minified,
+// others get an inline source map.
+func (s *Site) buildBundle(b Bundle) ([]byte, error) {
+	options := api.BuildOptions{
+		EntryPoints: []string{b.Entry},
+		Bundle:      true,
+		Write:       false,
+		LogLevel:    api.LogLevelSilent,
+		Target:      api.ES2017,
+	}
+	if s.isProduction() {
+		options.MinifyWhitespace = true
+		options.MinifyIdentifiers = true
+		options.MinifySyntax = true
+	} else {
+		options.Sourcemap = api.SourceMapInline
+	}
+
+	result := api.Build(options)
+	if len(result.Errors) > 0 {
+		msgs := api.FormatMessages(result.Errors, api.FormatMessagesOptions{Kind: api.ErrorMessage})
+		return nil, fmt.Errorf("Bundling %s failed:\n%s", b.Entry, strings.Join(msgs, ""))
+	}
+	if len(result.OutputFiles) != 1 {
+		return nil, errors.New("Bundling " + b.Entry + " produced no output")
+	}
+	return result.OutputFiles[0].Contents, nil
+}
@@ -0,0 +1,36 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "util.ts"), []byte("export const greet = (name: string): string => 'Hello ' + name;\nexport const unused = 1;\n"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "main.ts"), []byte("import { greet } from './util';\nconsole.log(greet('world'));\n"), 0644))
+
+	config := DefaultConfig()
+	config.Bundles = []Bundle{{Entry: filepath.Join(dir, "main.ts"), Output: "js/main.js"}}
+	site := NewSite(config)
+	site.BuildInfo.Environment = "production"
+	site.assets = make(map[string]*ContentItem)
+
+	root := &ContentItem{Site: site, Filename: ".", Type: Directory}
+	site.addBundles(root)
+
+	url, err := site.assetURL("js/main.js")
+	ok(t, err)
+	equals(t, url, "/js/main.js")
+
+	out, err := root.child("js").child("main.js").generate()
+	ok(t, err)
+	assert(t, strings.Contains(string(out), `"Hello "`), "Missing bundled code: %s", out)
+	assert(t, !strings.Contains(string(out), "unused"), "Unused code not removed: %s", out)
+}
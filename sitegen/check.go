@@ -0,0 +1,338 @@
+package sitegen
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExternalLinksConfig configures the checks of links to other sites
+// (sitegen check --external).
+type ExternalLinksConfig struct {
+	// Number of links checked at the same time.
+	Concurrency int `yaml:"concurrency"`
+
+	// Minimum time between requests to the same host, e.g. 1s.
+	Delay string `yaml:"delay"`
+
+	// Time to wait for a response.
+	Timeout string `yaml:"timeout"`
+
+	// How long links that worked aren't checked again, e.g. 24h.
+	CacheDuration string `yaml:"cacheDuration"`
+
+	// URLs that are skipped, as prefixes (https://twitter.com/).
+	Ignore []string `yaml:"ignore"`
+}
+
+// Maximum number of redirects followed for an external link.
+const maxRedirects = 10
+
+// linkStatus is the result of checking an external link.
+type linkStatus struct {
+	Status    int       `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Redirects []string  `json:"redirects,omitempty"`
+	Checked   time.Time `json:"checked"`
+}
+
+func (l linkStatus) broken() bool {
+	return l.Error != "" || l.Status >= 400
+}
+
+// Check verifies the links in the generated site, without building it:
+// internal links always, links to other sites with --external. It prints a
+// report and fails when links are broken.
+func (s *Site) Check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	external := flags.Bool("external", false, "Also check links to other sites")
+	err := flags.Parse(args)
+	if err != nil {
+		return categorize(ConfigError, err)
+	}
+
+	log.Println("==> Checking links")
+	pages := make(map[string][]string)
+	err = filepath.Walk(s.Config.OutputDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) != ".html" {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Config.OutputDir, p)
+		if err != nil {
+			return err
+		}
+		links, err := readLinks(p)
+		if err != nil {
+			return err
+		}
+		pages[filepath.ToSlash(rel)] = links
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report := make([]string, 0)
+	broken := 0
+
+	// Pages each external link is on.
+	externalLinks := make(map[string][]string)
+	for page, links := range pages {
+		for _, link := range links {
+			target, local := s.localTarget(page, link)
+			if local {
+				if !s.linkExists(nil, target) {
+					report = append(report, fmt.Sprintf("Broken: %s (on /%s)", link, page))
+					broken++
+				}
+				continue
+			}
+			if *external && isExternalLink(link) && !s.ignoreLink(link) {
+				externalLinks[link] = appendUnique(externalLinks[link], "/"+page)
+			}
+		}
+	}
+
+	if len(externalLinks) > 0 {
+		results, err := s.checkExternalLinks(externalLinks)
+		if err != nil {
+			return err
+		}
+		for link, result := range results {
+			on := strings.Join(externalLinks[link], ", ")
+			switch {
+			case result.Error != "":
+				report = append(report, fmt.Sprintf("Broken: %s: %s (on %s)", link, result.Error, on))
+				broken++
+			case result.broken():
+				report = append(report, fmt.Sprintf("Broken: %s: %d %s (on %s)", link, result.Status, http.StatusText(result.Status), on))
+				broken++
+			case len(result.Redirects) > 0:
+				chain := append([]string{link}, result.Redirects...)
+				report = append(report, fmt.Sprintf("Redirect: %s (on %s)", strings.Join(chain, " -> "), on))
+			}
+		}
+	}
+
+	sort.Strings(report)
+	for _, line := range report {
+		log.Println(line)
+	}
+	if broken > 0 {
+		return categorize(LinkError, fmt.Errorf("%d broken links", broken))
+	}
+	return nil
+}
+
+func isExternalLink(link string) bool {
+	return strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") || strings.HasPrefix(link, "//")
+}
+
+func (s *Site) ignoreLink(link string) bool {
+	for _, prefix := range s.Config.ExternalLinks.Ignore {
+		if strings.HasPrefix(link, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, existing := range list {
+		if existing == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+// checkExternalLinks checks the given links concurrently, skipping the ones
+// that worked recently (kept in the cache directory).
+func (s *Site) checkExternalLinks(links map[string][]string) (map[string]linkStatus, error) {
+	config := s.Config.ExternalLinks
+	delay, err := parseDuration("delay", config.Delay)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseDuration("timeout", config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	cacheDuration, err := parseDuration("cacheDuration", config.CacheDuration)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	cacheFile := filepath.Join(s.Config.CacheDir, "external-links.json")
+	cache := make(map[string]linkStatus)
+	if data, err := ioutil.ReadFile(cacheFile); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+
+	results := make(map[string]linkStatus)
+	todo := make(chan string)
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	checker := &linkChecker{
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		limiter: &hostLimiter{delay: delay, next: make(map[string]time.Time)},
+	}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range todo {
+				result := checker.check(link)
+				lock.Lock()
+				results[link] = result
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for link := range links {
+		if cached, ok := cache[link]; ok && !cached.broken() && time.Since(cached.Checked) < cacheDuration {
+			lock.Lock()
+			results[link] = cached
+			lock.Unlock()
+			continue
+		}
+		log.Printf(" -> %s\n", link)
+		todo <- link
+	}
+	close(todo)
+	wg.Wait()
+
+	// Only working links are cached, broken ones get checked every time.
+	cache = make(map[string]linkStatus)
+	for link, result := range results {
+		if !result.broken() {
+			cache[link] = result
+		}
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(s.Config.CacheDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return results, ioutil.WriteFile(cacheFile, data, 0644)
+}
+
+func parseDuration(name, value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, categorize(ConfigError, fmt.Errorf("Invalid %s: %s", name, value))
+	}
+	return d, nil
+}
+
+// linkChecker requests external links, following redirects by hand to
+// record them.
+type linkChecker struct {
+	client  *http.Client
+	limiter *hostLimiter
+}
+
+func (l *linkChecker) check(link string) linkStatus {
+	result := linkStatus{Checked: time.Now()}
+	if strings.HasPrefix(link, "//") {
+		link = "https:" + link
+	}
+
+	for i := 0; i <= maxRedirects; i++ {
+		u, err := url.Parse(link)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+
+		resp, err := l.request("HEAD", u)
+		if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			// Not every server supports HEAD.
+			resp, err = l.request("GET", u)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && location != "" {
+			next, err := u.Parse(location)
+			if err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			link = next.String()
+			result.Redirects = append(result.Redirects, link)
+			continue
+		}
+
+		result.Status = resp.StatusCode
+		return result
+	}
+	result.Error = "Too many redirects"
+	return result
+}
+
+func (l *linkChecker) request(method string, u *url.URL) (*http.Response, error) {
+	l.limiter.wait(u.Host)
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "sitegen link checker")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// hostLimiter spaces out requests to the same host.
+type hostLimiter struct {
+	lock  sync.Mutex
+	delay time.Duration
+	next  map[string]time.Time
+}
+
+func (h *hostLimiter) wait(host string) {
+	h.lock.Lock()
+	now := time.Now()
+	t := h.next[host]
+	if t.Before(now) {
+		t = now
+	}
+	h.next[host] = t.Add(h.delay)
+	h.lock.Unlock()
+
+	time.Sleep(time.Until(t))
+}
@@ -0,0 +1,109 @@
+package sitegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheck(t *testing.T) {
+	lock := sync.Mutex{}
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		lock.Unlock()
+
+		switch r.URL.Path {
+		case "/ok":
+		case "/get-only":
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/moved":
+			http.Redirect(w, r, "/moved-again", http.StatusMovedPermanently)
+		case "/moved-again":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "output")
+	ok(t, os.MkdirAll(filepath.Join(output, "blog"), 0755))
+	pages := map[string]string{
+		"index.html": fmt.Sprintf(`<a href="/blog/">Blog</a> <a href="%[1]s/ok">OK</a> <a href="%[1]s/moved">Moved</a>
+<a href="%[1]s/get-only">GET</a> <a href="https://ignored.example.com/">Ignored</a>`, server.URL),
+		"blog/index.html": fmt.Sprintf(`<a href="%[1]s/ok">OK</a>`, server.URL),
+	}
+	for name, data := range pages {
+		ok(t, ioutil.WriteFile(filepath.Join(output, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.OutputDir = output
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.ExternalLinks.Delay = "0s"
+	config.ExternalLinks.Ignore = []string{"https://ignored.example.com/"}
+	site := NewSite(config)
+
+	ok(t, site.Check(nil))
+	equals(t, len(requests), 0)
+
+	ok(t, site.Check([]string{"--external"}))
+	equals(t, requests["/ok"], 2)
+	equals(t, requests["/get-only"], 2)
+
+	// Working links are cached.
+	ok(t, site.Check([]string{"--external"}))
+	equals(t, requests["/ok"], 2)
+
+	ok(t, ioutil.WriteFile(filepath.Join(output, "broken.html"),
+		[]byte(fmt.Sprintf(`<a href="%s/gone">Gone</a> <a href="/missing/">Missing</a>`, server.URL)), 0644))
+	err = site.Check([]string{"--external"})
+	assert(t, err != nil, "Expected broken links")
+	equals(t, err.Error(), "2 broken links")
+	equals(t, Category(err), LinkError)
+}
+
+func TestLinkChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/loop" {
+			http.Redirect(w, r, "/loop", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	checker := &linkChecker{
+		client: &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		limiter: &hostLimiter{next: make(map[string]time.Time)},
+	}
+
+	result := checker.check(server.URL + "/moved")
+	equals(t, result.Status, 200)
+	equals(t, result.Redirects, []string{server.URL + "/ok"})
+	assert(t, !result.broken(), "Unexpected broken link")
+
+	result = checker.check(server.URL + "/loop")
+	equals(t, result.Error, "Too many redirects")
+	assert(t, result.broken(), "Expected broken link")
+}
@@ -0,0 +1,141 @@
+package sitegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Clean removes the contents of the output directory, leaving files that
+// match the keep list in place.
+func (s *Site) Clean() error {
+	out, err := filepath.Abs(s.Config.OutputDir)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCleanTarget(out); err != nil {
+		return err
+	}
+	if _, err := os.Stat(out); os.IsNotExist(err) {
+		return nil
+	}
+
+	log.Println("==> Cleaning")
+	return s.cleanDir(out, "")
+}
+
+// cleanDir removes the files in a folder below the output directory (rel
+// is its slash-separated path, "" for the output directory itself), going
+// into the folders that hold files on the keep list.
+func (s *Site) cleanDir(out, rel string) error {
+	files, err := ioutil.ReadDir(filepath.Join(out, filepath.FromSlash(rel)))
+	if err != nil {
+		return err
+	}
+
+	for _, v := range files {
+		name := path.Join(rel, v.Name())
+		if s.keepFile(name) {
+			continue
+		}
+
+		if v.IsDir() && s.keepsBelow(name) {
+			err = s.cleanDir(out, name)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		log.Printf(" -> %s\n", name)
+		err = os.RemoveAll(filepath.Join(out, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepFile reports whether a file in the output directory, given by its
+// slash-separated path relative to it, is on the keep list, either itself
+// or through one of the folders it's in.
+func (s *Site) keepFile(name string) bool {
+	for _, pattern := range s.Config.Keep {
+		for p := name; p != "" && p != "."; p = path.Dir(p) {
+			if matchGlob(pattern, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keepOutput is keepFile for a path in the output directory out.
+func (s *Site) keepOutput(out, p string) bool {
+	rel, err := filepath.Rel(out, p)
+	if err != nil {
+		return false
+	}
+	return s.keepFile(filepath.ToSlash(rel))
+}
+
+// keepsBelow reports whether any pattern on the keep list could match a
+// file inside the given folder.
+func (s *Site) keepsBelow(dir string) bool {
+	depth := strings.Count(dir, "/") + 1
+	for _, pattern := range s.Config.Keep {
+		parts := strings.Split(pattern, "/")
+		if strings.Contains(pattern, "**") {
+			return true
+		}
+		if len(parts) > depth && matchGlob(strings.Join(parts[:depth], "/"), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCleanTarget guards against wiping out something that clearly isn't
+// an output directory: the filesystem root, the folder we're working in (or
+// one of its parents) and folders holding the sources of the site.
+func (s *Site) checkCleanTarget(out string) error {
+	if out == filepath.Dir(out) {
+		return fmt.Errorf("Refusing to clean %s: filesystem root", out)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if isWithin(cwd, out) {
+		return fmt.Errorf("Refusing to clean %s: contains the working directory", out)
+	}
+
+	sources := append([]string{s.Config.TemplateDir}, s.Config.ContentDirs...)
+	for _, dir := range sources {
+		if dir == "" {
+			continue
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		if isWithin(abs, out) {
+			return fmt.Errorf("Refusing to clean %s: contains %s", out, dir)
+		}
+	}
+	return nil
+}
+
+// isWithin reports whether path equals dir or lies below it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,90 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("x"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "CNAME"), []byte("x"), 0644))
+	ok(t, os.MkdirAll(filepath.Join(dir, "css"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, ".git", "objects"), 0755))
+
+	site := NewSite(DefaultConfig())
+	site.Config.OutputDir = dir
+	ok(t, site.Clean())
+
+	assert(t, !fileExists(filepath.Join(dir, "index.html")), "index.html not removed")
+	assert(t, !fileExists(filepath.Join(dir, "css")), "css not removed")
+	assert(t, fileExists(filepath.Join(dir, "CNAME")), "CNAME removed")
+	assert(t, fileExists(filepath.Join(dir, ".git", "objects")), ".git removed")
+}
+
+func TestCleanRefusesWorkingDir(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	site.Config.OutputDir = "."
+	assert(t, site.Clean() != nil, "Expected error when cleaning working directory")
+
+	site.Config.OutputDir = ".."
+	assert(t, site.Clean() != nil, "Expected error when cleaning parent directory")
+
+	site.Config.OutputDir = "/"
+	assert(t, site.Clean() != nil, "Expected error when cleaning root")
+}
+
+func TestIsWithin(t *testing.T) {
+	assert(t, isWithin("/a/b", "/a"), "Expected /a/b within /a")
+	assert(t, isWithin("/a", "/a"), "Expected /a within /a")
+	assert(t, isWithin("/a/..b", "/a"), "Expected /a/..b within /a")
+	assert(t, !isWithin("/ab", "/a"), "Expected /ab outside /a")
+	assert(t, !isWithin("/", "/a"), "Expected / outside /a")
+}
+
+func TestCleanKeepsPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "static", "img"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "static", "report.pdf"), []byte("x"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "static", "style.css"), []byte("x"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "static", "img", "logo.pdf"), []byte("x"), 0644))
+
+	site := NewSite(DefaultConfig())
+	site.Config.OutputDir = dir
+	site.Config.Keep = []string{"static/*.pdf"}
+	ok(t, site.Clean())
+
+	assert(t, fileExists(filepath.Join(dir, "static", "report.pdf")), "report.pdf removed")
+	assert(t, !fileExists(filepath.Join(dir, "static", "style.css")), "style.css not removed")
+	assert(t, !fileExists(filepath.Join(dir, "static", "img")), "img not removed")
+}
+
+func TestCleanRefusesSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(content, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "index.md"), []byte("x"), 0644))
+
+	site := NewSite(DefaultConfig())
+	site.Config.ContentDirs = []string{content}
+	site.Config.OutputDir = dir
+	assert(t, site.Clean() != nil, "Expected error when cleaning a folder holding the content")
+	site.Config.OutputDir = content
+	assert(t, site.Clean() != nil, "Expected error when cleaning the content folder")
+
+	site.Config.ContentDirs = nil
+	site.Config.TemplateDir = content
+	assert(t, site.Clean() != nil, "Expected error when cleaning the template folder")
+	assert(t, fileExists(filepath.Join(content, "index.md")), "index.md removed")
+}
@@ -0,0 +1,113 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseCodeInfo splits the info string of a fenced code block
+// (go {linenos=false, hl_lines=[3,7]}) into the language and its options.
+// List values ([3,7] or "3-5 7") are returned space separated.
+func parseCodeInfo(info string) (string, map[string]string) {
+	options := make(map[string]string)
+	lang := strings.TrimSpace(info)
+	start := strings.Index(lang, "{")
+	if start == -1 || !strings.HasSuffix(lang, "}") {
+		return lang, options
+	}
+
+	attrs := lang[start+1 : len(lang)-1]
+	lang = strings.TrimSpace(lang[:start])
+
+	depth := 0
+	var quote rune
+	last := 0
+	parts := make([]string, 0)
+	for i, r := range attrs {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case (r == ',' || r == ' ') && depth == 0:
+			parts = append(parts, attrs[last:i])
+			last = i + 1
+		}
+	}
+	parts = append(parts, attrs[last:])
+
+	for _, part := range parts {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if kv[0] == "" {
+			continue
+		}
+		if len(kv) == 1 {
+			options[kv[0]] = "true"
+			continue
+		}
+		value := unquote(strings.TrimSpace(kv[1]))
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			items := strings.Split(value[1:len(value)-1], ",")
+			for i, item := range items {
+				items[i] = unquote(strings.TrimSpace(item))
+			}
+			value = strings.Join(items, " ")
+		}
+		options[kv[0]] = value
+	}
+	return lang, options
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// writeCodeAttributes writes options as attributes of a <highlight> tag, in
+// the quoted form parseAttributes reads.
+func writeCodeAttributes(out *bytes.Buffer, options map[string]string) {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for _, key := range keys {
+		fmt.Fprintf(out, ` %s="%s"`, key, escape.Replace(options[key]))
+	}
+}
+
+// parseLineRanges parses a list of line numbers and ranges (3-5 7).
+func parseLineRanges(in string) (map[int]bool, error) {
+	lines := make(map[int]bool)
+	for _, part := range strings.Fields(strings.Replace(in, ",", " ", -1)) {
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid line range: %s", part)
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, err = strconv.Atoi(bounds[1])
+			if err != nil || to < from {
+				return nil, fmt.Errorf("Invalid line range: %s", part)
+			}
+		}
+		for i := from; i <= to; i++ {
+			lines[i] = true
+		}
+	}
+	return lines, nil
+}
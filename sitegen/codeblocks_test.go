@@ -0,0 +1,39 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestParseCodeInfo(t *testing.T) {
+	lang, options := parseCodeInfo("go")
+	equals(t, lang, "go")
+	equals(t, options, map[string]string{})
+
+	lang, options = parseCodeInfo(`go {linenos=false, hl_lines=[3, 7-9], title="main go"}`)
+	equals(t, lang, "go")
+	equals(t, options, map[string]string{"linenos": "false", "hl_lines": "3 7-9", "title": "main go"})
+
+	lang, options = parseCodeInfo(`{linenostart=10 hl_lines="2-3 5"}`)
+	equals(t, lang, "")
+	equals(t, options, map[string]string{"linenostart": "10", "hl_lines": "2-3 5"})
+}
+
+func TestParseLineRanges(t *testing.T) {
+	lines, err := parseLineRanges("2-4 7,9")
+	ok(t, err)
+	equals(t, lines, map[int]bool{2: true, 3: true, 4: true, 7: true, 9: true})
+
+	_, err = parseLineRanges("5-3")
+	assert(t, err != nil, "Expected an error for a reversed range")
+	_, err = parseLineRanges("a")
+	assert(t, err != nil, "Expected an error for a bad line number")
+}
+
+func TestCodeBlockOptions(t *testing.T) {
+	input := []byte("```go {linenos=false, hl_lines=[1], title='say \"hi\"'}\nx := 1\n```\n")
+	out := string(renderMarkdown(input, MarkdownConfig{}))
+	equals(t, out, "<highlight language=\"go\" hl_lines=\"1\" linenos=\"false\" title=\"say \\\"hi\\\"\">x := 1</highlight>")
+
+	attrs := parseAttributes(codeRegex.FindStringSubmatch(out)[1])
+	equals(t, attrs, map[string]string{"language": "go", "hl_lines": "1", "linenos": "false", "title": `say "hi"`})
+}
@@ -0,0 +1,153 @@
+package sitegen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// CompressConfig holds the settings for precompressed output.
+type CompressConfig struct {
+	// Write a .gz copy next to compressible files.
+	Gzip bool `yaml:"gzip"`
+
+	// Write a .br copy next to compressible files.
+	Brotli bool `yaml:"brotli"`
+
+	// Extensions of the files to compress, defaults to text formats (.html,
+	// .css, .js, ...).
+	Extensions []string `yaml:"extensions"`
+
+	// Files smaller than this (in bytes) aren't worth compressing, defaults
+	// to 256.
+	MinSize int64 `yaml:"minSize"`
+}
+
+type compressor struct {
+	ext   string
+	write func(w io.Writer, data []byte) error
+}
+
+var compressors = []compressor{
+	{".gz", func(w io.Writer, data []byte) error {
+		z, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+		if err != nil {
+			return err
+		}
+		_, err = z.Write(data)
+		if err != nil {
+			return err
+		}
+		return z.Close()
+	}},
+	{".br", func(w io.Writer, data []byte) error {
+		z := brotli.NewWriterLevel(w, brotli.BestCompression)
+		_, err := z.Write(data)
+		if err != nil {
+			return err
+		}
+		return z.Close()
+	}},
+}
+
+// compressOutput writes compressed copies of the output files (index.html.gz
+// and index.html.br), so servers can send them as is. Copies that are newer
+// than their file are left alone. Files on the keep list aren't touched.
+func (s *Site) compressOutput() error {
+	config := s.Config.Compress
+	if !config.Gzip && !config.Brotli {
+		return nil
+	}
+
+	log.Println("==> Compressing")
+	out := filepath.Clean(s.Config.OutputDir)
+	return filepath.Walk(out, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if s.keepOutput(out, p) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return s.compressFile(p, info, false)
+	})
+}
+
+// compressFiles writes fresh compressed copies of the given output files,
+// e.g. after watch mode rendered them again.
+func (s *Site) compressFiles(files []string) error {
+	config := s.Config.Compress
+	if !config.Gzip && !config.Brotli {
+		return nil
+	}
+
+	out := filepath.Clean(s.Config.OutputDir)
+	for _, p := range files {
+		if s.keepOutput(out, p) {
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		err = s.compressFile(p, info, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressFile writes the compressed copies of a file, if it's compressible.
+// Unless force is set, copies that are newer than the file are left alone.
+func (s *Site) compressFile(p string, info os.FileInfo, force bool) error {
+	config := s.Config.Compress
+	enabled := map[string]bool{".gz": config.Gzip, ".br": config.Brotli}
+	extensions := make(map[string]bool)
+	for _, ext := range config.Extensions {
+		extensions[strings.ToLower(ext)] = true
+	}
+	if !extensions[strings.ToLower(filepath.Ext(p))] || info.Size() < config.MinSize {
+		return nil
+	}
+
+	var data []byte
+	for _, c := range compressors {
+		if !enabled[c.ext] {
+			continue
+		}
+		if existing, err := os.Stat(p + c.ext); !force && err == nil && !existing.ModTime().Before(info.ModTime()) {
+			continue
+		}
+
+		if data == nil {
+			var err error
+			data, err = ioutil.ReadFile(p)
+			if err != nil {
+				return err
+			}
+		}
+		buf := &bytes.Buffer{}
+		err := c.write(buf, data)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(p+c.ext, buf.Bytes(), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package sitegen
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	page := strings.Repeat("<p>Hello</p>", 100)
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "index.html"), []byte(page), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "small.css"), []byte("a{}"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "photo.jpg"), []byte(page), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, ".git", "config.xml"), []byte(page), 0644))
+
+	config := DefaultConfig()
+	config.OutputDir = dir
+	config.Compress.Gzip = true
+	config.Compress.Brotli = true
+	site := NewSite(config)
+	ok(t, site.compressOutput())
+
+	f, err := os.Open(filepath.Join(dir, "blog", "index.html.gz"))
+	ok(t, err)
+	defer f.Close()
+	z, err := gzip.NewReader(f)
+	ok(t, err)
+	data, err := ioutil.ReadAll(z)
+	ok(t, err)
+	equals(t, page, string(data))
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "blog", "index.html.br"))
+	ok(t, err)
+	data, err = ioutil.ReadAll(brotli.NewReader(bytes.NewReader(b)))
+	ok(t, err)
+	equals(t, page, string(data))
+
+	for _, p := range []string{"small.css.gz", "photo.jpg.gz", ".git/config.xml.gz"} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(p)))
+		assert(t, os.IsNotExist(err), "Expected no %s", p)
+	}
+}
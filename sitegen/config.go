@@ -0,0 +1,378 @@
+package sitegen
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	configFile  string
+	outputDir   string
+	contentDirs string
+	templateDir string
+	serveAddr   string
+	archive     string
+	workers     int
+	baseURL     string
+	environment string
+	diffMode    bool
+	diffHTML    bool
+)
+
+func init() {
+	flag.StringVar(&configFile, "config", "sitegen.yaml", "Site configuration file")
+	flag.StringVar(&outputDir, "output", "", "Output directory (overrides the configuration file)")
+	flag.StringVar(&templateDir, "templates", "", "Template directory (overrides the configuration file)")
+	flag.StringVar(&serveAddr, "addr", "localhost:8080", "Address to listen on for serve")
+	flag.StringVar(&archive, "archive", "", "Archive to pack the generated site into, .zip, .tar.gz or .tar (overrides the configuration file)")
+	flag.StringVar(&contentDirs, "content", "", "Comma-separated content directories (overrides the configuration file)")
+	flag.StringVar(&baseURL, "baseURL", "", "Address the site is published at, e.g. for preview deploys (overrides the configuration file)")
+	flag.StringVar(&environment, "env", "", "Build environment, e.g. development (overrides $SITEGEN_ENV)")
+	flag.BoolVar(&diffMode, "diff", false, "Show what a build would add, change or remove in the output directory, without writing anything")
+	flag.BoolVar(&diffHTML, "diff-html", false, "With -diff, show unified diffs of the HTML files that changed")
+	flag.IntVar(&workers, "workers", 0, "Number of content files parsed at the same time (overrides the configuration file)")
+}
+
+// Config holds the site-wide settings, read from sitegen.yaml.
+type Config struct {
+	// Directories the content is read from. When there's more than one,
+	// they're merged into a single tree.
+	ContentDirs []string `yaml:"content"`
+
+	// Directory the templates are read from, including subdirectories.
+	TemplateDir string `yaml:"templates"`
+
+	// Name of the site.
+	Title string `yaml:"title"`
+
+	// Address the site is published at, e.g. https://example.com/. Used
+	// where full URLs are needed.
+	BaseURL string `yaml:"baseURL"`
+
+	// Twitter handle of the site (@example), for Twitter cards.
+	Twitter string `yaml:"twitter"`
+
+	// Directory the generated site is written to.
+	OutputDir string `yaml:"output"`
+
+	// Files and directories in the output folder that should survive a
+	// clean (glob patterns, relative to the output folder).
+	Keep []string `yaml:"keep"`
+
+	// Line that starts and ends the front matter of content files. The end
+	// can also be marked with "...".
+	FrontMatterDelimiter string `yaml:"frontMatterDelimiter"`
+
+	// Rendering of Markdown content.
+	Markdown MarkdownConfig `yaml:"markdown"`
+
+	// Remove the heading from the content when the title of a page is
+	// taken from it.
+	RemoveTitleHeading bool `yaml:"removeTitleHeading"`
+
+	// Pins the build time (2006-01-02 15:04:05) for reproducible builds.
+	BuildTime string `yaml:"buildTime"`
+
+	// Version of the site, for templates as .Site.SiteVersion. Defaults to
+	// $SITEGEN_VERSION or else git describe.
+	Version string `yaml:"version"`
+
+	// Front matter fields that group pages (e.g. tags), keyed by name.
+	Taxonomies map[string]*TaxonomyConfig `yaml:"taxonomies"`
+
+	// Maximum number of items in generated feeds.
+	FeedLimit int `yaml:"feedLimit"`
+
+	// Output formats for redirects: meta (HTML pages with a meta refresh),
+	// netlify (a _redirects file) and nginx (redirects.nginx.conf).
+	Redirects []string `yaml:"redirects"`
+
+	// Formats to minify (html, css, js, json, svg and xml), e.g.
+	// {html: true, css: true}.
+	Minify map[string]bool `yaml:"minify"`
+
+	// Assets that get a content hash in their filename, as glob patterns
+	// matched against the filename (or the full path if the pattern
+	// contains a slash).
+	Fingerprint []string `yaml:"fingerprint"`
+
+	// Processing rules for assets, the first matching rule applies.
+	AssetRules []AssetRule `yaml:"assetRules"`
+
+	// External commands that assets are piped through, by extension.
+	Transformers []Transformer `yaml:"transformers"`
+
+	// Directory for cached build results.
+	CacheDir string `yaml:"cacheDir"`
+
+	// Keep rendered Markdown in the cache folder, so pages that didn't
+	// change aren't rendered again on the next build.
+	RenderCache bool `yaml:"renderCache"`
+
+	// JavaScript/TypeScript bundles, built with esbuild.
+	Bundles []Bundle `yaml:"bundles"`
+
+	// Compilation of .scss and .sass assets.
+	Sass SassConfig `yaml:"sass"`
+
+	// Removal of EXIF and other metadata from images.
+	ImageMetadata ImageMetadataConfig `yaml:"imageMetadata"`
+
+	// WebP/AVIF versions of JPEG and PNG images.
+	ImageFormats ImageFormatsConfig `yaml:"imageFormats"`
+
+	// Listing pages for directories without an index page.
+	AutoIndex AutoIndexConfig `yaml:"autoIndex"`
+
+	// Gallery pages for directories of images.
+	Gallery GalleryConfig `yaml:"gallery"`
+
+	// Generated social card images.
+	OGImage OGImageConfig `yaml:"ogImage"`
+
+	// Check the internal links of the generated pages: warn logs broken
+	// links, error fails the build.
+	CheckLinks string `yaml:"checkLinks"`
+
+	// Checks of links to other sites, see sitegen check --external.
+	ExternalLinks ExternalLinksConfig `yaml:"externalLinks"`
+
+	// Screenshots of changed pages, taken after each build.
+	Screenshots ScreenshotConfig `yaml:"screenshots"`
+
+	// PDF versions of pages, made after each build.
+	PDF PDFConfig `yaml:"pdf"`
+
+	// Language of pages that don't say otherwise.
+	DefaultLanguage string `yaml:"defaultLanguage"`
+
+	// Languages of a multilingual site, keyed by language code (en, nl).
+	Languages map[string]*LanguageConfig `yaml:"languages"`
+
+	// Directory with the templates of new pages (sitegen new), one
+	// <section>.md per section and a default.md.
+	ArchetypeDir string `yaml:"archetypes"`
+
+	// Directory with the translation tables of the i18n template function,
+	// one <lang>.yaml file per language.
+	I18nDir string `yaml:"i18n"`
+
+	// Output formats of pages that don't list their own, defaults to html.
+	// Other formats (json, amp) are rendered with their own templates.
+	Outputs []string `yaml:"outputs"`
+
+	// Pages generated from the records in data files.
+	DataPages []DataPagesConfig `yaml:"dataPages"`
+
+	// Take the last modification time and author of pages from the git
+	// history of the content.
+	GitInfo bool `yaml:"gitInfo"`
+
+	// Cleaning of unsafe HTML in content.
+	Sanitize SanitizeConfig `yaml:"sanitize"`
+
+	// Archive the generated site is written to instead of the output
+	// directory (.zip, .tar.gz or .tar), e.g. for deploy pipelines that
+	// work with artifacts.
+	Archive string `yaml:"archive"`
+
+	// Settings of sitegen deploy.
+	Deploy DeployConfig `yaml:"deploy"`
+
+	// Hidden files and directories (starting with a dot) in the content
+	// that are part of the site, as glob patterns matched against their
+	// path. Others are skipped. Defaults to .well-known.
+	HiddenFiles []string `yaml:"hiddenFiles"`
+
+	// What to do with symlinks in the content: follow them (the default),
+	// copy them to the output as symlinks, or skip them.
+	Symlinks string `yaml:"symlinks"`
+
+	// Build environment: production (the default), development or any
+	// other name, e.g. staging. $SITEGEN_ENV and -env override it.
+	Environment string `yaml:"environment"`
+
+	// Number of content files that are parsed at the same time. Defaults
+	// to the number of CPUs.
+	Workers int `yaml:"workers"`
+
+	// Precompressed copies of output files (.gz, .br), for static hosts
+	// and nginx gzip_static.
+	Compress CompressConfig `yaml:"compress"`
+
+	// Per-section settings, keyed by the name of the top-level content
+	// directory.
+	Sections map[string]*SectionConfig `yaml:"sections"`
+}
+
+// SectionConfig holds the settings for one section of the site.
+type SectionConfig struct {
+	// Take the date from the filename (2006-01-02-title.md) or the
+	// directory structure (2006/01/title.md) when the front matter has
+	// none.
+	DateFromPath bool `yaml:"dateFromPath"`
+
+	// Template of the pages in the section that don't name one in their
+	// front matter, e.g. post for a blog and doc for the documentation.
+	// Index pages still use <section>/list or list.
+	Template string `yaml:"template"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		ContentDirs:          []string{"content"},
+		TemplateDir:          "templates",
+		FrontMatterDelimiter: "---",
+		OutputDir:            "static",
+		Keep:                 []string{"CNAME", ".git"},
+		HiddenFiles:          []string{".well-known"},
+		Taxonomies:           make(map[string]*TaxonomyConfig),
+		FeedLimit:            20,
+		Redirects:            []string{"meta"},
+		Sass:                 SassConfig{Command: "sass"},
+		CacheDir:             ".sitegen-cache",
+		Screenshots:          ScreenshotConfig{ReportDir: "reports"},
+		ExternalLinks:        ExternalLinksConfig{Concurrency: 8, Delay: "1s", Timeout: "10s", CacheDuration: "24h"},
+		OGImage:              OGImageConfig{BackgroundColor: "#1e293b", TextColor: "#ffffff"},
+		Gallery:              GalleryConfig{Template: "gallery", ThumbnailSize: "400x400 crop"},
+		ImageMetadata:        ImageMetadataConfig{Keep: []string{"Orientation"}},
+		Sections:             make(map[string]*SectionConfig),
+		Compress:             CompressConfig{Extensions: []string{".html", ".css", ".js", ".mjs", ".json", ".xml", ".svg", ".txt", ".map"}, MinSize: 256},
+		DefaultLanguage:      "en",
+		I18nDir:              "i18n",
+		ArchetypeDir:         "archetypes",
+		Languages:            make(map[string]*LanguageConfig),
+	}
+}
+
+// LoadConfig reads the configuration file. A missing file is not an error,
+// the defaults are used instead.
+func LoadConfig(filename string) (*Config, error) {
+	config := DefaultConfig()
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, categorize(ConfigError, err)
+	}
+
+	err = yaml.Unmarshal(data, config)
+	if err != nil {
+		return nil, categorize(ConfigError, err)
+	}
+	if config.OutputDir == "" {
+		return nil, categorize(ConfigError, errors.New("Output directory cannot be empty"))
+	}
+	if config.FrontMatterDelimiter == "" {
+		return nil, categorize(ConfigError, errors.New("Front matter delimiter cannot be empty"))
+	}
+	if config.TemplateDir == "" {
+		return nil, categorize(ConfigError, errors.New("Template directory cannot be empty"))
+	}
+	if len(config.ContentDirs) == 0 {
+		return nil, categorize(ConfigError, errors.New("No content directories configured"))
+	}
+	if config.BaseURL != "" {
+		err = checkBaseURL(config.BaseURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch config.CheckLinks {
+	case "", "warn", "error":
+	default:
+		return nil, categorize(ConfigError, fmt.Errorf("Invalid checkLinks: %s, should be warn or error", config.CheckLinks))
+	}
+	switch config.Symlinks {
+	case "", "follow", "copy", "skip":
+	default:
+		return nil, categorize(ConfigError, fmt.Errorf("Invalid symlinks: %s, should be follow, copy or skip", config.Symlinks))
+	}
+	if config.Workers < 0 {
+		return nil, categorize(ConfigError, fmt.Errorf("Invalid workers: %d", config.Workers))
+	}
+	if config.Archive != "" && archiveFormat(config.Archive) == "" {
+		return nil, categorize(ConfigError, fmt.Errorf("Unknown archive format: %s, should be .zip, .tar.gz or .tar", config.Archive))
+	}
+	if err := config.Markdown.validate(); err != nil {
+		return nil, categorize(ConfigError, err)
+	}
+	for _, tag := range config.ImageMetadata.Keep {
+		if _, ok := exifTags[tag]; !ok {
+			return nil, categorize(ConfigError, fmt.Errorf("Unknown EXIF tag: %s", tag))
+		}
+	}
+	config.OutputDir = filepath.Clean(config.OutputDir)
+	return config, nil
+}
+
+// checkBaseURL verifies that baseURL is a full URL.
+func checkBaseURL(baseURL string) error {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return categorize(ConfigError, fmt.Errorf("Invalid baseURL: %s", baseURL))
+	}
+	return nil
+}
+
+// ImageMetadataConfig holds the settings for removing metadata from images.
+type ImageMetadataConfig struct {
+	// Strip metadata (EXIF, GPS, XMP, comments) from all JPEG and PNG
+	// images. Use the stripMetadata asset rule for a subset.
+	Strip bool `yaml:"strip"`
+
+	// EXIF tags that are kept when stripping, defaults to Orientation so
+	// photos stay upright.
+	Keep []string `yaml:"keep"`
+}
+
+// SassConfig holds the settings for compiling Sass files.
+type SassConfig struct {
+	// Compile Sass files to CSS, rather than copying them.
+	Enabled bool `yaml:"enabled"`
+
+	// The sass executable, defaults to "sass" (Dart Sass).
+	Command string `yaml:"command"`
+
+	// Extra directories to look for imports in.
+	LoadPaths []string `yaml:"loadPaths"`
+}
+
+// TaxonomyConfig holds the settings for one taxonomy.
+type TaxonomyConfig struct {
+	// Template used for the term pages, defaults to "taxonomy".
+	Template string `yaml:"template"`
+
+	// Number of pages listed per term page, 0 lists all of them on one page.
+	Paginate int `yaml:"paginate"`
+}
+
+// Taxonomy returns the settings for the given taxonomy, with defaults
+// filled in.
+func (c *Config) Taxonomy(name string) TaxonomyConfig {
+	var taxonomy TaxonomyConfig
+	if t, ok := c.Taxonomies[name]; ok && t != nil {
+		taxonomy = *t
+	}
+	if taxonomy.Template == "" {
+		taxonomy.Template = "taxonomy"
+	}
+	return taxonomy
+}
+
+// Section returns the settings for the given section, never nil.
+func (c *Config) Section(name string) *SectionConfig {
+	if section, ok := c.Sections[name]; ok && section != nil {
+		return section
+	}
+	return &SectionConfig{}
+}
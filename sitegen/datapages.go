@@ -0,0 +1,126 @@
+package sitegen
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// DataPagesConfig generates a page for each record in a data file.
+type DataPagesConfig struct {
+	// A CSV (with a header row), JSON or YAML file with a list of records.
+	Source string `yaml:"source"`
+
+	// Template the pages are rendered with, it gets the fields of the
+	// record in .Metadata.Params.
+	Template string `yaml:"template"`
+
+	// URL of the pages, with fields of the record between braces
+	// (/team/{name}/). The values are slugified.
+	URL string `yaml:"url"`
+
+	// Field with the title of the page, defaults to "title".
+	Title string `yaml:"title"`
+}
+
+// addDataPages adds the pages generated from data files to the tree.
+func (s *Site) addDataPages(root *ContentItem) error {
+	for _, config := range s.Config.DataPages {
+		records, err := readRecords(config.Source)
+		if err != nil {
+			return fmt.Errorf("%s: %w", config.Source, err)
+		}
+
+		titleField := config.Title
+		if titleField == "" {
+			titleField = "title"
+		}
+
+		for i, record := range records {
+			u, err := expandPlaceholders(config.URL, record)
+			if err != nil {
+				return fmt.Errorf("%s: record %d: %w", config.Source, i+1, err)
+			}
+
+			metadata := Metadata{
+				Template: config.Template,
+				Params:   record,
+			}
+			metadata.Title = metadata.String(titleField)
+
+			out := urlPath(u)
+			page := &ContentItem{
+				Site:     s,
+				FullPath: config.Source,
+				Path:     out,
+				Type:     Content,
+				Metadata: metadata,
+			}
+			if existing := root.place(out, page); existing != nil {
+				return fmt.Errorf("%s: record %d: url %s conflicts with %s", config.Source, i+1, u, existing.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// readRecords reads a list of records from a CSV, JSON or YAML file.
+func readRecords(filename string) ([]map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]map[string]interface{}, 0)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			if i == 0 {
+				continue
+			}
+			record := make(map[string]interface{})
+			for j, field := range rows[0] {
+				record[field] = row[j]
+			}
+			records = append(records, record)
+		}
+	case ".json":
+		err = json.Unmarshal(data, &records)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &records)
+	default:
+		return nil, fmt.Errorf("Unknown data format: %s", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// expandPlaceholders fills in the fields of a record in a URL pattern.
+func expandPlaceholders(pattern string, record map[string]interface{}) (string, error) {
+	var err error
+	u := placeholderRegex.ReplaceAllStringFunc(pattern, func(m string) string {
+		field := m[1 : len(m)-1]
+		value, ok := record[field]
+		if !ok || value == nil {
+			err = fmt.Errorf("Missing field %s", field)
+			return ""
+		}
+		return slugify(fmt.Sprint(value))
+	})
+	return u, err
+}
@@ -0,0 +1,91 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataPages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "team"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "team", "index.md"), []byte("Our team"), 0644))
+
+	files := map[string]string{
+		"team.csv":      "name,role\nJane Doe,Founder\nJohn Smith,Engineer\n",
+		"products.json": `[{"id": 1, "name": "Widget", "price": 9.5}]`,
+		"api.yaml": `
+- method: GET
+  path: /users
+  summary: List users
+`,
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.DataPages = []DataPagesConfig{
+		{Source: filepath.Join(dir, "team.csv"), Template: "member", URL: "/team/{name}/", Title: "name"},
+		{Source: filepath.Join(dir, "products.json"), Template: "product", URL: "/products/{id}.html", Title: "name"},
+		{Source: filepath.Join(dir, "api.yaml"), Template: "endpoint", URL: "/api/{method}-{path}/", Title: "summary"},
+	}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.addDataPages(root))
+	root.Process()
+
+	jane := root.child("team").child("jane-doe").child("index.html")
+	assert(t, jane != nil, "Expected page for Jane")
+	equals(t, jane.Url, "/team/jane-doe/")
+	equals(t, jane.Metadata.Title, "Jane Doe")
+	equals(t, jane.Metadata.Template, "member")
+	equals(t, jane.Metadata.String("role"), "Founder")
+	assert(t, root.child("team").child("john-smith").child("index.html") != nil, "Expected page for John")
+	assert(t, root.child("team").child("index.html") != nil, "Expected existing index page")
+
+	widget := root.child("products").child("1.html")
+	equals(t, widget.Metadata.Title, "Widget")
+	equals(t, widget.Metadata.Params["price"], 9.5)
+
+	endpoint := root.child("api").child("get-users").child("index.html")
+	equals(t, endpoint.Metadata.Title, "List users")
+}
+
+func TestDataPagesErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(content, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "about.md"), []byte("About"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "pages.yaml"), []byte("- name: about\n- title: Missing\n"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.DataPages = []DataPagesConfig{
+		{Source: filepath.Join(dir, "pages.yaml"), URL: "/{name}.html"},
+	}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	err = site.addDataPages(root)
+	assert(t, err != nil, "Expected conflict")
+
+	config.DataPages[0].URL = "/pages/{name}/"
+	root, err = site.crawlContent()
+	ok(t, err)
+	err = site.addDataPages(root)
+	assert(t, err != nil, "Expected missing field")
+	equals(t, err.Error(), filepath.Join(dir, "pages.yaml")+": record 2: Missing field name")
+}
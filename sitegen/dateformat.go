@@ -0,0 +1,114 @@
+package sitegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLocale holds what's needed to write dates in a language.
+type dateLocale struct {
+	Months      [12]string
+	ShortMonths [12]string
+	Days        [7]string // Starting on Sunday
+	ShortDays   [7]string
+
+	// Layouts of the short, medium and long styles: {d} and {dd} are the
+	// day, {m} and {mm} the month number, {mon} and {month} the month name
+	// and {yyyy} the year.
+	Styles map[string]string
+}
+
+var dateLocales = map[string]*dateLocale{
+	"en": {
+		Months:      [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		Days:        [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		ShortDays:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		Styles:      map[string]string{"short": "{m}/{d}/{yyyy}", "medium": "{mon} {d}, {yyyy}", "long": "{month} {d}, {yyyy}"},
+	},
+	"nl": {
+		Months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		ShortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		Days:        [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		ShortDays:   [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		Styles:      map[string]string{"short": "{d}-{m}-{yyyy}", "medium": "{d} {mon} {yyyy}", "long": "{d} {month} {yyyy}"},
+	},
+	"fr": {
+		Months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		ShortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		Days:        [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		ShortDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		Styles:      map[string]string{"short": "{dd}/{mm}/{yyyy}", "medium": "{d} {mon} {yyyy}", "long": "{d} {month} {yyyy}"},
+	},
+	"de": {
+		Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:   [7]string{"So.", "Mo.", "Di.", "Mi.", "Do.", "Fr.", "Sa."},
+		Styles:      map[string]string{"short": "{dd}.{mm}.{yyyy}", "medium": "{d}. {mon} {yyyy}", "long": "{d}. {month} {yyyy}"},
+	},
+	"es": {
+		Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		Days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		Styles:      map[string]string{"short": "{d}/{m}/{yyyy}", "medium": "{d} {mon} {yyyy}", "long": "{d} de {month} de {yyyy}"},
+	},
+}
+
+// dateFormat formats a date for a language. The date is given as a page
+// (using its date and language) or as a time, followed by the language. The
+// format is a style (short, medium or long) or a Go layout, in which month
+// and day names are translated. Languages without date support fall back
+// to English. Used as the dateFormat template function.
+func (s *Site) dateFormat(format string, value interface{}, lang ...string) (string, error) {
+	var t time.Time
+	code := s.Config.DefaultLanguage
+	switch v := value.(type) {
+	case *ContentItem:
+		t, code = v.Metadata.Date, v.Lang
+	case time.Time:
+		t = v
+	default:
+		return "", fmt.Errorf("dateFormat: cannot format %v", value)
+	}
+	if len(lang) > 0 {
+		code = lang[0]
+	}
+	return formatDate(t, format, code), nil
+}
+
+func formatDate(t time.Time, format, lang string) string {
+	locale, ok := dateLocales[lang]
+	if !ok {
+		locale = dateLocales["en"]
+	}
+
+	if style, ok := locale.Styles[format]; ok {
+		return strings.NewReplacer(
+			"{dd}", fmt.Sprintf("%02d", t.Day()),
+			"{d}", strconv.Itoa(t.Day()),
+			"{mm}", fmt.Sprintf("%02d", int(t.Month())),
+			"{m}", strconv.Itoa(int(t.Month())),
+			"{month}", locale.Months[t.Month()-1],
+			"{mon}", locale.ShortMonths[t.Month()-1],
+			"{yyyy}", strconv.Itoa(t.Year()),
+		).Replace(style)
+	}
+
+	// Go layout: names are swapped for markers that survive formatting.
+	layout := strings.NewReplacer(
+		"January", "\x00M\x00",
+		"Jan", "\x00m\x00",
+		"Monday", "\x00D\x00",
+		"Mon", "\x00d\x00",
+	).Replace(format)
+	return strings.NewReplacer(
+		"\x00M\x00", locale.Months[t.Month()-1],
+		"\x00m\x00", locale.ShortMonths[t.Month()-1],
+		"\x00D\x00", locale.Days[t.Weekday()],
+		"\x00d\x00", locale.ShortDays[t.Weekday()],
+	).Replace(t.Format(layout))
+}
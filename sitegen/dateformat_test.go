@@ -0,0 +1,51 @@
+package sitegen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC) // A Tuesday
+
+	tests := []struct {
+		format, lang, exp string
+	}{
+		{"short", "en", "3/5/2024"},
+		{"medium", "en", "Mar 5, 2024"},
+		{"long", "en", "March 5, 2024"},
+		{"short", "nl", "5-3-2024"},
+		{"long", "nl", "5 maart 2024"},
+		{"short", "fr", "05/03/2024"},
+		{"medium", "de", "5. März 2024"},
+		{"long", "es", "5 de marzo de 2024"},
+		{"long", "xx", "March 5, 2024"},
+		{"Monday 2 January 2006", "nl", "dinsdag 5 maart 2024"},
+		{"Mon 02 Jan", "fr", "mar. 05 mars"},
+		{"2006-01-02", "de", "2024-03-05"},
+	}
+	for _, test := range tests {
+		equals(t, formatDate(date, test.format, test.lang), test.exp)
+	}
+}
+
+func TestDateFormatFunc(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	date := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	page := &ContentItem{Lang: "nl", Metadata: Metadata{Date: date}}
+	out, err := site.dateFormat("long", page)
+	ok(t, err)
+	equals(t, out, "5 maart 2024")
+
+	out, err = site.dateFormat("long", date)
+	ok(t, err)
+	equals(t, out, "March 5, 2024")
+
+	out, err = site.dateFormat("long", date, "fr")
+	ok(t, err)
+	equals(t, out, "5 mars 2024")
+
+	_, err = site.dateFormat("long", "2024-03-05")
+	assert(t, err != nil, "Expected error for string")
+}
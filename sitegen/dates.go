@@ -0,0 +1,66 @@
+package sitegen
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	filenameDateRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-`)
+	pathDateRegex     = regexp.MustCompile(`(?:^|/)(\d{4})/(\d{2})(?:/(\d{2}))?/[^/]+$`)
+)
+
+// inferDate fills in a missing date from the file path, if enabled for the
+// section the item lives in.
+func (s *Site) inferDate(c *ContentItem) {
+	if !c.Metadata.Date.IsZero() {
+		return
+	}
+
+	if !s.Config.Section(sectionName(c.Path)).DateFromPath {
+		return
+	}
+
+	if t, ok := dateFromPath(c.Path); ok {
+		c.Metadata.Date = t
+	}
+}
+
+// sectionName returns the top-level directory of a path relative to the
+// content folder, or "" for files in the root.
+func sectionName(rel string) string {
+	i := strings.Index(rel, "/")
+	if i == -1 {
+		return ""
+	}
+	return rel[:i]
+}
+
+// dateFromPath extracts a date from Jekyll-style filenames
+// (2006-01-02-title.md) or date-based directories (2006/01/title.md or
+// 2006/01/02/title.md).
+func dateFromPath(p string) (time.Time, bool) {
+	if m := filenameDateRegex.FindStringSubmatch(path.Base(p)); m != nil {
+		return parseDate(m[1])
+	}
+
+	if m := pathDateRegex.FindStringSubmatch(p); m != nil {
+		day := m[3]
+		if day == "" {
+			day = "01"
+		}
+		return parseDate(m[1] + "-" + m[2] + "-" + day)
+	}
+
+	return time.Time{}, false
+}
+
+func parseDate(s string) (time.Time, bool) {
+	t, err := time.ParseInLocation("2006-01-02", s, location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
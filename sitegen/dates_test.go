@@ -0,0 +1,44 @@
+package sitegen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateFromPath(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, location())
+	}
+
+	tests := map[string]time.Time{
+		"blog/2024-05-01-title.md":   date(2024, 5, 1),
+		"2024-05-01-title.md":        date(2024, 5, 1),
+		"blog/2024/05/post.md":       date(2024, 5, 1),
+		"blog/2024/05/17/post.md":    date(2024, 5, 17),
+		"2013/12/post.md":            date(2013, 12, 1),
+		"blog/2024-05-01/index.md":   time.Time{},
+		"blog/2024-13-01-title.md":   time.Time{},
+		"blog/post.md":               time.Time{},
+		"blog/2024/notamonth/doc.md": time.Time{},
+	}
+
+	for in, exp := range tests {
+		d, ok := dateFromPath(in)
+		equals(t, ok, !exp.IsZero())
+		equals(t, d.Equal(exp), true)
+	}
+}
+
+func TestInferDate(t *testing.T) {
+	config := DefaultConfig()
+	config.Sections["blog"] = &SectionConfig{DateFromPath: true}
+	site := NewSite(config)
+
+	c := &ContentItem{Path: "blog/2024-05-01-title.md"}
+	site.inferDate(c)
+	equals(t, c.Metadata.Date.Format("2006-01-02"), "2024-05-01")
+
+	c = &ContentItem{Path: "docs/2024-05-01-title.md"}
+	site.inferDate(c)
+	assert(t, c.Metadata.Date.IsZero(), "Date inferred outside of configured section")
+}
@@ -0,0 +1,178 @@
+package sitegen
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// DeployConfig holds the settings of sitegen deploy.
+type DeployConfig struct {
+	// Where to deploy to when the command line doesn't say, e.g.
+	// s3://bucket/prefix.
+	Target string `yaml:"target"`
+
+	// Cache-Control headers of uploaded files, the first matching rule
+	// applies.
+	CacheControl []CacheControlRule `yaml:"cacheControl"`
+
+	// Remove files from the target that aren't in the output anymore.
+	Delete bool `yaml:"delete"`
+
+	// Uploads to S3 and compatible storage (MinIO, R2).
+	S3 S3Config `yaml:"s3"`
+
+	// Deploys over SSH, with rsync.
+	SSH SSHConfig `yaml:"ssh"`
+
+	// Deploys to a git branch, for GitHub Pages.
+	GitHubPages GitHubPagesConfig `yaml:"githubPages"`
+}
+
+// CacheControlRule sets the Cache-Control header of matching files.
+type CacheControlRule struct {
+	// Glob pattern, matched against the path in the output directory.
+	Match string `yaml:"match"`
+
+	// Header value, e.g. "public, max-age=31536000, immutable".
+	Value string `yaml:"value"`
+}
+
+// Deploy uploads the output directory (as built before) to the target given
+// on the command line or configured: s3://bucket/prefix,
+// ssh://user@host/path (ssh for the configured server) or gh-pages.
+func (s *Site) Deploy(args []string) error {
+	flags := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "Show what would change without deploying")
+	err := flags.Parse(args)
+	if err != nil {
+		return categorize(ConfigError, err)
+	}
+
+	target := s.Config.Deploy.Target
+	if flags.NArg() > 0 {
+		target = flags.Arg(0)
+	}
+	if target == "" {
+		return categorize(ConfigError, errors.New("Usage: sitegen deploy <target>, e.g. s3://bucket/prefix"))
+	}
+	if _, err := os.Stat(s.Config.OutputDir); err != nil {
+		return categorize(ConfigError, fmt.Errorf("Nothing to deploy, build the site first: %w", err))
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return categorize(ConfigError, fmt.Errorf("Invalid deploy target %s: %w", target, err))
+	}
+
+	log.Printf("==> Deploying to %s\n", target)
+	switch u.Scheme {
+	case "s3":
+		err = s.deployS3(u.Host, u.Path, *dryRun)
+	case "ssh":
+		err = s.deploySSH(u, *dryRun)
+	case "":
+		switch target {
+		case "ssh":
+			err = s.deploySSH(&url.URL{}, *dryRun)
+		case "gh-pages":
+			err = s.deployGitHubPages(*dryRun)
+		default:
+			return categorize(ConfigError, fmt.Errorf("Unknown deploy target: %s", target))
+		}
+	default:
+		return categorize(ConfigError, fmt.Errorf("Unknown deploy target: %s", target))
+	}
+	return categorize(DeployError, err)
+}
+
+// deployFile is a file of the output directory, as deployed.
+type deployFile struct {
+	// Slash-separated path, relative to the output directory.
+	Path string
+
+	// Hex MD5 of the contents.
+	Hash string
+
+	Size int64
+}
+
+// outputFiles lists the files in the output directory, leaving out the
+// ones on the keep list.
+func (s *Site) outputFiles() ([]deployFile, error) {
+	out := filepath.Clean(s.Config.OutputDir)
+	files := make([]deployFile, 0)
+	err := filepath.Walk(out, func(p string, info os.FileInfo, err error) error {
+		if err != nil || p == out {
+			return err
+		}
+		if s.keepOutput(out, p) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(out, p)
+		if err != nil {
+			return err
+		}
+		hash, err := md5File(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, deployFile{
+			Path: filepath.ToSlash(rel),
+			Hash: hash,
+			Size: info.Size(),
+		})
+		return nil
+	})
+	return files, err
+}
+
+func md5File(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheControl returns the Cache-Control header of a deployed file, "" if
+// no rule matches.
+func (c DeployConfig) cacheControl(p string) string {
+	for _, rule := range c.CacheControl {
+		if matchGlob(rule.Match, p) {
+			return rule.Value
+		}
+	}
+	return ""
+}
+
+// contentType returns the media type of a deployed file.
+func contentType(p string) string {
+	if t := mime.TypeByExtension(path.Ext(p)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
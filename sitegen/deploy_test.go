@@ -0,0 +1,57 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "post.html"), []byte("post"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref"), 0644))
+
+	config := DefaultConfig()
+	config.OutputDir = dir
+	files, err := NewSite(config).outputFiles()
+	ok(t, err)
+	equals(t, []deployFile{
+		{Path: "blog/post.html", Hash: "42b90196b487c54069097a68fe98ab6f", Size: 4},
+		{Path: "index.html", Hash: "106a6c241b8797f52e1e77317b96a201", Size: 4},
+	}, files)
+}
+
+func TestDeployConfig(t *testing.T) {
+	config := DeployConfig{CacheControl: []CacheControlRule{
+		{Match: "**/*.html", Value: "max-age=300"},
+		{Match: "assets/**", Value: "max-age=31536000, immutable"},
+	}}
+	equals(t, "max-age=300", config.cacheControl("blog/post.html"))
+	equals(t, "max-age=31536000, immutable", config.cacheControl("assets/css/site.css"))
+	equals(t, "", config.cacheControl("robots.txt"))
+
+	equals(t, "text/css; charset=utf-8", contentType("site.css"))
+	equals(t, "application/octet-stream", contentType("LICENSE"))
+}
+
+func TestDeployErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.OutputDir = dir
+	site := NewSite(config)
+	equals(t, ConfigError, Category(site.Deploy(nil)))
+	equals(t, ConfigError, Category(site.Deploy([]string{"ftp://example.com/"})))
+
+	config.OutputDir = filepath.Join(dir, "missing")
+	equals(t, ConfigError, Category(site.Deploy([]string{"s3://bucket"})))
+}
@@ -0,0 +1,64 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+)
+
+var diagramRegex = regexp.MustCompile(`(?s)<pre class="([a-z]+)">(.*?)</pre>`)
+
+// DiagramConfig configures the rendering of one kind of diagram (mermaid,
+// plantuml), written as a code block in that language.
+type DiagramConfig struct {
+	// Render diagrams to SVG when building, with a command that reads the
+	// source on stdin and writes SVG to stdout (e.g. [plantuml, -tsvg,
+	// -pipe]). Without a command, the source is left for a script in the
+	// browser, in a <pre class="mermaid">.
+	Command []string `yaml:"command"`
+}
+
+// BlockDiagram writes the source of a diagram, in the markup that client
+// side renderers (e.g. mermaid.js) look for.
+func (r *renderer) BlockDiagram(out *bytes.Buffer, text []byte, lang string) {
+	out.WriteString(`<pre class="`)
+	out.WriteString(lang)
+	out.WriteString(`">`)
+	out.WriteString(html.EscapeString(string(bytes.TrimSpace(text))))
+	out.WriteString("</pre>\n")
+}
+
+// renderDiagrams renders the diagrams in a page that have a command to
+// SVG.
+func (s *Site) renderDiagrams(path string, in []byte, diagrams map[string]DiagramConfig) ([]byte, error) {
+	var renderErr error
+	out := diagramRegex.ReplaceAllFunc(in, func(m []byte) []byte {
+		parts := diagramRegex.FindSubmatch(m)
+		lang := string(parts[1])
+		config, ok := diagrams[lang]
+		if !ok || len(config.Command) == 0 {
+			return m
+		}
+
+		source := html.UnescapeString(string(parts[2]))
+		svg, err := s.transform(Transformer{Command: config.Command}, []byte(source))
+		if err != nil {
+			renderErr = fmt.Errorf("Rendering %s diagram in %s failed: %w", lang, path, err)
+			return m
+		}
+		if start := bytes.Index(svg, []byte("<svg")); start > 0 {
+			svg = svg[start:]
+		}
+
+		result := &bytes.Buffer{}
+		fmt.Fprintf(result, `<div class="diagram diagram-%s">`, lang)
+		result.Write(bytes.TrimSpace(svg))
+		result.WriteString("</div>")
+		return result.Bytes()
+	})
+	if renderErr != nil {
+		return nil, renderErr
+	}
+	return out, nil
+}
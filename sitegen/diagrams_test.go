@@ -0,0 +1,50 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagrams(t *testing.T) {
+	input := []byte("```mermaid\ngraph TD\n  A --> B\n```\n\n```go\nA --> B\n```\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<highlight language=\"mermaid\">graph TD\n  A --> B</highlight><highlight language=\"go\">A --> B</highlight>")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{Diagrams: map[string]DiagramConfig{"mermaid": {}}})),
+		"<pre class=\"mermaid\">graph TD\n  A --&gt; B</pre>\n<highlight language=\"go\">A --> B</highlight>")
+}
+
+func TestRenderDiagrams(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	command := filepath.Join(dir, "plantuml")
+	ok(t, ioutil.WriteFile(command, []byte("#!/bin/sh\necho '<?xml version=\"1.0\"?>'\necho \"<svg>$(cat)</svg>\"\n"), 0755))
+
+	config := DefaultConfig()
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.Markdown.Diagrams = map[string]DiagramConfig{
+		"plantuml": {Command: []string{command}},
+		"mermaid":  {},
+	}
+	site := NewSite(config)
+
+	out, err := site.markdown([]byte("```plantuml\nA -> B\n```\n\n```mermaid\nA --> B\n```\n"), "index.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<div class=\"diagram diagram-plantuml\"><svg>A -> B</svg></div>\n<pre class=\"mermaid\">A --&gt; B</pre>\n")
+
+	// Pages that list a diagram keep the site's command.
+	out, err = site.markdown([]byte("```plantuml\nA -> B\n```\n"), "index.md", Metadata{Params: map[string]interface{}{
+		"markup": map[interface{}]interface{}{"diagrams": map[interface{}]interface{}{"plantuml": map[interface{}]interface{}{}}},
+	}})
+	ok(t, err)
+	equals(t, string(out), "<div class=\"diagram diagram-plantuml\"><svg>A -> B</svg></div>\n")
+
+	config.Markdown.Diagrams["plantuml"] = DiagramConfig{Command: []string{filepath.Join(dir, "missing")}}
+	_, err = site.markdown([]byte("```plantuml\nA -> B\n```\n"), "index.md", Metadata{})
+	assert(t, err != nil, "Expected an error for a failing command")
+}
@@ -0,0 +1,229 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// outputChange is a difference between a fresh build and the output
+// directory: a file that would be added (A), modified (M) or removed (D).
+type outputChange struct {
+	Kind byte
+	Path string
+}
+
+// Diff builds the site in memory and writes what a build would change in the
+// output directory to w, without touching it. With showHTML, changed HTML
+// files are shown as unified diffs.
+func (s *Site) Diff(w io.Writer, showHTML bool) error {
+	built, err := s.BuildFS()
+	if err != nil {
+		return err
+	}
+	changes, err := s.diffOutput(built)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		fmt.Fprintf(w, "%c %s\n", c.Kind, c.Path)
+		if !showHTML || c.Kind != 'M' || path.Ext(c.Path) != ".html" {
+			continue
+		}
+		old, err := ioutil.ReadFile(filepath.Join(s.Config.OutputDir, filepath.FromSlash(c.Path)))
+		if err != nil {
+			return err
+		}
+		data, err := fs.ReadFile(built, c.Path)
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, unifiedDiff(c.Path, old, data))
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+	}
+	return nil
+}
+
+// diffOutput compares the files of a build with the output directory, by
+// path. Files that the build doesn't make itself are left out: the ones to
+// keep and the compressed copies.
+func (s *Site) diffOutput(built fs.FS) ([]outputChange, error) {
+	existing := make(map[string]bool)
+	if fileExists(s.Config.OutputDir) {
+		files, err := s.outputFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range files {
+			existing[v.Path] = true
+		}
+	}
+
+	changes := make([]outputChange, 0)
+	builtFiles := make(map[string]bool)
+	err := fs.WalkDir(built, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		builtFiles[p] = true
+		if !existing[p] {
+			changes = append(changes, outputChange{'A', p})
+			return nil
+		}
+		data, err := fs.ReadFile(built, p)
+		if err != nil {
+			return err
+		}
+		old, err := ioutil.ReadFile(filepath.Join(s.Config.OutputDir, filepath.FromSlash(p)))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(old, data) {
+			changes = append(changes, outputChange{'M', p})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for p := range existing {
+		if builtFiles[p] {
+			continue
+		}
+		if ext := path.Ext(p); (ext == ".gz" || ext == ".br") && builtFiles[strings.TrimSuffix(p, ext)] {
+			continue
+		}
+		changes = append(changes, outputChange{'D', p})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// Lines of context around the changes in a unified diff.
+const diffContext = 3
+
+// Files with more lines than this (multiplied) aren't diffed line by line.
+const maxDiffSize = 4000000
+
+// unifiedDiff returns the differences between two versions of a file, in
+// the format of diff -u.
+func unifiedDiff(name string, a, b []byte) string {
+	x, y := diffLines(a), diffLines(b)
+	if len(x)*len(y) > maxDiffSize {
+		return fmt.Sprintf("%s: too large to diff\n", name)
+	}
+
+	// Longest common subsequence of the lines, from the end.
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type op struct {
+		kind byte
+		line string
+		i, j int // Line numbers in a and b before this line.
+	}
+	ops := make([]op, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, op{' ', x[i], i, j})
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, op{'+', y[j], i, j})
+			j++
+		default:
+			ops = append(ops, op{'-', x[i], i, j})
+			i++
+		}
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "--- a/%s\n+++ b/%s\n", name, name)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// A hunk runs until there are more than twice the context lines
+		// without changes.
+		from := start - diffContext
+		if from < 0 {
+			from = 0
+		}
+		end, same := start, 0
+		for end < len(ops) && same <= 2*diffContext {
+			if ops[end].kind == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+			end++
+		}
+		if same > diffContext {
+			end -= same - diffContext
+		}
+
+		removed, added := 0, 0
+		for _, v := range ops[from:end] {
+			if v.kind != '+' {
+				removed++
+			}
+			if v.kind != '-' {
+				added++
+			}
+		}
+		fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(ops[from].i, removed), hunkRange(ops[from].j, added))
+		for _, v := range ops[from:end] {
+			fmt.Fprintf(out, "%c%s\n", v.kind, v.line)
+		}
+		start = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the start and length of a hunk, with the start counted
+// from 1 (or the line before an empty range).
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func diffLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
@@ -0,0 +1,72 @@
+package sitegen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"content/a.md":        "---\ntitle: A\n---\nA\n",
+		"content/b.md":        "---\ntitle: B\n---\nB\n",
+		"templates/page.html": "<h1>{{.Metadata.Title}}</h1>\n{{.Content}}",
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "out")
+	site := NewSite(config)
+
+	out := &bytes.Buffer{}
+	ok(t, site.Diff(out, false))
+	equals(t, out.String(), "A a.html\nA b.html\n")
+	assert(t, !fileExists(config.OutputDir), "Unexpected output directory")
+
+	ok(t, site.Build())
+	out.Reset()
+	ok(t, site.Diff(out, false))
+	equals(t, out.String(), "No changes\n")
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "content", "a.md"), []byte("---\ntitle: A\n---\nChanged\n"), 0644))
+	ok(t, os.Remove(filepath.Join(dir, "content", "b.md")))
+	ok(t, ioutil.WriteFile(filepath.Join(config.OutputDir, "b.html.gz"), []byte("x"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(config.OutputDir, "a.html.gz"), []byte("x"), 0644))
+	out.Reset()
+	ok(t, site.Diff(out, true))
+	equals(t, out.String(), "M a.html\n--- a/a.html\n+++ b/a.html\n@@ -1,2 +1,2 @@\n <h1>A</h1>\n-<p>A</p>\n+<p>Changed</p>\nD b.html\nD b.html.gz\n")
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n")
+	b := []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n")
+	equals(t, unifiedDiff("f", a, b), `--- a/f
++++ b/f
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -10,3 +10,4 @@
+ 10
+ 11
+ 12
++13
+`)
+	equals(t, unifiedDiff("f", nil, []byte("a\n")), "--- a/f\n+++ b/f\n@@ -0,0 +1 @@\n+a\n")
+}
@@ -0,0 +1,170 @@
+package sitegen
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var emojiRegex = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+
+// Emoji shortcodes, as used on GitHub and Slack.
+var emojiCodes = map[string]string{
+	"+1":                       "\U0001F44D",
+	"-1":                       "\U0001F44E",
+	"100":                      "\U0001F4AF",
+	"alien":                    "\U0001F47D",
+	"angry":                    "\U0001F620",
+	"arrow_down":               "⬇️",
+	"arrow_left":               "⬅️",
+	"arrow_right":              "➡️",
+	"arrow_up":                 "⬆️",
+	"art":                      "\U0001F3A8",
+	"beer":                     "\U0001F37A",
+	"bell":                     "\U0001F514",
+	"blush":                    "\U0001F60A",
+	"book":                     "\U0001F4D6",
+	"bookmark":                 "\U0001F516",
+	"books":                    "\U0001F4DA",
+	"boom":                     "\U0001F4A5",
+	"broken_heart":             "\U0001F494",
+	"bug":                      "\U0001F41B",
+	"bulb":                     "\U0001F4A1",
+	"cake":                     "\U0001F370",
+	"calendar":                 "\U0001F4C6",
+	"camera":                   "\U0001F4F7",
+	"cat":                      "\U0001F431",
+	"chart_with_upwards_trend": "\U0001F4C8",
+	"clap":                     "\U0001F44F",
+	"cloud":                    "☁️",
+	"coffee":                   "☕",
+	"computer":                 "\U0001F4BB",
+	"confetti_ball":            "\U0001F38A",
+	"confused":                 "\U0001F615",
+	"construction":             "\U0001F6A7",
+	"cry":                      "\U0001F622",
+	"date":                     "\U0001F4C5",
+	"dog":                      "\U0001F436",
+	"earth_americas":           "\U0001F30E",
+	"email":                    "\U0001F4E7",
+	"exclamation":              "❗",
+	"eyes":                     "\U0001F440",
+	"facepalm":                 "\U0001F926",
+	"fire":                     "\U0001F525",
+	"free":                     "\U0001F193",
+	"gear":                     "⚙️",
+	"ghost":                    "\U0001F47B",
+	"gift":                     "\U0001F381",
+	"globe_with_meridians":     "\U0001F310",
+	"grin":                     "\U0001F601",
+	"hammer":                   "\U0001F528",
+	"heart":                    "❤️",
+	"heart_eyes":               "\U0001F60D",
+	"heavy_check_mark":         "✔️",
+	"hourglass":                "⌛",
+	"house":                    "\U0001F3E0",
+	"iphone":                   "\U0001F4F1",
+	"joy":                      "\U0001F602",
+	"key":                      "\U0001F511",
+	"laughing":                 "\U0001F606",
+	"link":                     "\U0001F517",
+	"lock":                     "\U0001F512",
+	"mag":                      "\U0001F50D",
+	"medal":                    "\U0001F3C5",
+	"memo":                     "\U0001F4DD",
+	"muscle":                   "\U0001F4AA",
+	"musical_note":             "\U0001F3B5",
+	"neutral_face":             "\U0001F610",
+	"new":                      "\U0001F195",
+	"no_entry":                 "⛔",
+	"ok":                       "\U0001F197",
+	"ok_hand":                  "\U0001F44C",
+	"package":                  "\U0001F4E6",
+	"penguin":                  "\U0001F427",
+	"pencil2":                  "✏️",
+	"pizza":                    "\U0001F355",
+	"point_down":               "\U0001F447",
+	"point_left":               "\U0001F448",
+	"point_right":              "\U0001F449",
+	"point_up":                 "☝️",
+	"poop":                     "\U0001F4A9",
+	"pray":                     "\U0001F64F",
+	"question":                 "❓",
+	"rage":                     "\U0001F621",
+	"rainbow":                  "\U0001F308",
+	"raised_hands":             "\U0001F64C",
+	"raising_hand":             "\U0001F64B",
+	"recycle":                  "♻️",
+	"robot":                    "\U0001F916",
+	"rocket":                   "\U0001F680",
+	"rose":                     "\U0001F339",
+	"scream":                   "\U0001F631",
+	"see_no_evil":              "\U0001F648",
+	"seedling":                 "\U0001F331",
+	"shrug":                    "\U0001F937",
+	"skull":                    "\U0001F480",
+	"slightly_smiling_face":    "\U0001F642",
+	"smile":                    "\U0001F604",
+	"smiley":                   "\U0001F603",
+	"snake":                    "\U0001F40D",
+	"snowflake":                "❄️",
+	"sob":                      "\U0001F62D",
+	"sparkles":                 "✨",
+	"star":                     "⭐",
+	"star2":                    "\U0001F31F",
+	"stop_sign":                "\U0001F6D1",
+	"sunglasses":               "\U0001F60E",
+	"sunny":                    "☀️",
+	"sweat_smile":              "\U0001F605",
+	"tada":                     "\U0001F389",
+	"thinking":                 "\U0001F914",
+	"thumbsdown":               "\U0001F44E",
+	"thumbsup":                 "\U0001F44D",
+	"trophy":                   "\U0001F3C6",
+	"umbrella":                 "☔",
+	"unlock":                   "\U0001F513",
+	"upside_down_face":         "\U0001F643",
+	"warning":                  "⚠️",
+	"wave":                     "\U0001F44B",
+	"whale":                    "\U0001F433",
+	"white_check_mark":         "✅",
+	"wink":                     "\U0001F609",
+	"wrench":                   "\U0001F527",
+	"x":                        "❌",
+	"zap":                      "⚡",
+}
+
+// replaceEmoji replaces emoji shortcodes (:tada:) in the text of rendered
+// HTML, leaving code and tags alone. With an image URL pattern, they become
+// images instead: {name} is replaced by the shortcode, {code} by the code
+// points in hex (1f389, as used by Twemoji).
+func replaceEmoji(in []byte, imageURL string) []byte {
+	return replaceInText(in, func(text, code string) string {
+		if code != "" {
+			return text
+		}
+		return emojiRegex.ReplaceAllStringFunc(text, func(m string) string {
+			name := m[1 : len(m)-1]
+			emoji, ok := emojiCodes[name]
+			if !ok {
+				return m
+			}
+			if imageURL == "" {
+				return emoji
+			}
+			return emojiImage(imageURL, name, emoji)
+		})
+	})
+}
+
+func emojiImage(pattern, name, emoji string) string {
+	codes := make([]string, 0)
+	for _, r := range emoji {
+		if r != 0xFE0F {
+			codes = append(codes, fmt.Sprintf("%x", r))
+		}
+	}
+	src := strings.NewReplacer("{name}", name, "{code}", strings.Join(codes, "-")).Replace(pattern)
+	return fmt.Sprintf(`<img class="emoji" src="%s" alt="%s">`, html.EscapeString(src), emoji)
+}
@@ -0,0 +1,18 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestEmoji(t *testing.T) {
+	input := []byte("Released :tada: :+1: :unknown:\n\n`:tada:`\n\n    :tada:\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<p>Released :tada: :+1: :unknown:</p>\n\n<p><code>:tada:</code></p>\n<highlight language=\"\">:tada:</highlight>")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{Emoji: true})),
+		"<p>Released 🎉 👍 :unknown:</p>\n\n<p><code>:tada:</code></p>\n<highlight language=\"\">:tada:</highlight>")
+
+	equals(t, string(renderMarkdown([]byte("Nice :heart:\n"), MarkdownConfig{Emoji: true, EmojiImages: "/emoji/{code}.svg?{name}"})),
+		"<p>Nice <img class=\"emoji\" src=\"/emoji/2764.svg?heart\" alt=\"❤️\"></p>\n")
+}
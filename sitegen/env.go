@@ -0,0 +1,44 @@
+package sitegen
+
+// removeDrafts leaves out the pages with draft: true in their front matter,
+// except in development builds.
+func (s *Site) removeDrafts(c *ContentItem) {
+	if s.isDevelopment() {
+		return
+	}
+	children := c.Children[:0]
+	for _, v := range c.Children {
+		if v.Type == Content && v.isDraft() {
+			continue
+		}
+		if v.Type == Directory {
+			s.removeDrafts(v)
+		}
+		children = append(children, v)
+	}
+	c.Children = children
+}
+
+func (c *ContentItem) isDraft() bool {
+	draft, _ := c.Metadata.Params["draft"].(bool)
+	return draft
+}
+
+// addRobotsTxt keeps search engines away from builds that aren't for
+// production (previews, staging) with a robots.txt that disallows
+// everything, in place of the one in the content.
+func (s *Site) addRobotsTxt(root *ContentItem) {
+	if s.isProduction() {
+		return
+	}
+	children := root.Children[:0]
+	for _, v := range root.Children {
+		if v.Filename != "robots.txt" {
+			children = append(children, v)
+		}
+	}
+	root.Children = children
+	root.addGenerated("robots.txt", Metadata{}, func() ([]byte, error) {
+		return []byte("User-agent: *\nDisallow: /\n"), nil
+	})
+}
@@ -0,0 +1,64 @@
+package sitegen
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvironments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"content/a.md":        "---\ntitle: A\n---\nA\n",
+		"content/blog/b.md":   "---\ntitle: B\ndraft: true\n---\nB\n",
+		"content/robots.txt":  "User-agent: *\n",
+		"templates/page.html": "<p>  {{.Site.Env}}  </p>",
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte(data), 0644))
+	}
+
+	build := func(env string) map[string]string {
+		config := DefaultConfig()
+		config.ContentDirs = []string{filepath.Join(dir, "content")}
+		config.TemplateDir = filepath.Join(dir, "templates")
+		config.Minify = map[string]bool{"html": true}
+		config.Environment = env
+		site := NewSite(config)
+		built, err := site.BuildFS()
+		ok(t, err)
+		result := make(map[string]string)
+		ok(t, fs.WalkDir(built, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(built, p)
+			result[p] = string(data)
+			return err
+		}))
+		return result
+	}
+
+	built := build("")
+	equals(t, built["a.html"], "<p>production")
+	equals(t, built["robots.txt"], "User-agent: *\n")
+	_, found := built["blog/b.html"]
+	assert(t, !found, "Unexpected draft")
+
+	built = build("development")
+	equals(t, built["a.html"], "<p>  development  </p>")
+	equals(t, built["blog/b.html"], "<p>  development  </p>")
+	equals(t, built["robots.txt"], "User-agent: *\nDisallow: /\n")
+
+	built = build("staging")
+	equals(t, built["robots.txt"], "User-agent: *\nDisallow: /\n")
+	_, found = built["blog/b.html"]
+	assert(t, !found, "Unexpected draft")
+}
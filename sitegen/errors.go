@@ -0,0 +1,100 @@
+package sitegen
+
+import "errors"
+
+// ErrorCategory tells what kind of problem made a command fail, so callers
+// can react differently to each.
+type ErrorCategory int
+
+const (
+	// Anything not covered below, e.g. I/O errors.
+	OtherError ErrorCategory = iota
+
+	// Invalid configuration or command line.
+	ConfigError
+
+	// Content that cannot be read, e.g. broken front matter.
+	ParseError
+
+	// Templates that fail to load or render.
+	TemplateError
+
+	// Links or redirects pointing at pages that don't exist.
+	LinkError
+
+	// Failed deploys.
+	DeployError
+)
+
+// Exit codes used by the sitegen command, by category.
+var exitCodes = map[ErrorCategory]int{
+	OtherError:    1,
+	ConfigError:   2,
+	ParseError:    3,
+	TemplateError: 4,
+	LinkError:     5,
+	DeployError:   6,
+}
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case ConfigError:
+		return "config error"
+	case ParseError:
+		return "parse error"
+	case TemplateError:
+		return "template error"
+	case LinkError:
+		return "link check failure"
+	case DeployError:
+		return "deploy failure"
+	}
+	return "error"
+}
+
+// Error is an error with a category.
+type Error struct {
+	Category ErrorCategory
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// categorize marks err as belonging to a category, unless it already has
+// one.
+func categorize(category ErrorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Category: category, Err: err}
+}
+
+// Category returns the category of an error returned by sitegen.
+func Category(err error) ErrorCategory {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Category
+	}
+	return OtherError
+}
+
+// ExitCode returns the exit code the sitegen command uses for an error: 0
+// for nil, 1 for uncategorized errors, 2 for config errors, 3 for parse
+// errors, 4 for template errors, 5 for link check failures and 6 for
+// deploy failures.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return exitCodes[Category(err)]
+}
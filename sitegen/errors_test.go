@@ -0,0 +1,70 @@
+package sitegen
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestErrorCategories(t *testing.T) {
+	equals(t, ExitCode(nil), 0)
+	equals(t, ExitCode(errors.New("boom")), 1)
+
+	err := categorize(ParseError, errors.New("bad front matter"))
+	equals(t, Category(err), ParseError)
+	equals(t, ExitCode(err), 3)
+
+	// Wrapping keeps the category, categorizing again doesn't change it.
+	err = fmt.Errorf("write failed: %w", categorize(TemplateError, errors.New("no template")))
+	equals(t, Category(categorize(ParseError, err)), TemplateError)
+	equals(t, ExitCode(err), 4)
+}
+
+func TestConfigErrorCategory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "sitegen.yaml")
+	ok(t, ioutil.WriteFile(filename, []byte("output: \"\"\n"), 0644))
+
+	_, err = LoadConfig(filename)
+	equals(t, Category(err), ConfigError)
+	equals(t, ExitCode(err), 2)
+}
+
+func TestGeneratedErrorCategory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	templates := filepath.Join(dir, "templates")
+	ok(t, os.MkdirAll(content, 0755))
+	ok(t, os.MkdirAll(templates, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "post.md"), []byte("---\noutputs: [html, json]\n---\nHello"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(templates, "single.html"), []byte("{{.Content}}"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.TemplateDir = templates
+	site := NewSite(config)
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+	site.textTemplates, err = site.loadTextTemplates()
+	ok(t, err)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.addOutputs(root)
+	root.Process()
+
+	// No single.json template, so writing post.json fails.
+	err = root.child("post.json").write(filepath.Join(dir, "post.json"))
+	assert(t, err != nil, "Expected missing template")
+	equals(t, Category(err), TemplateError)
+	equals(t, ExitCode(err), 4)
+}
@@ -0,0 +1,110 @@
+package sitegen
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF tags (of the main image directory) that can be kept when stripping
+// metadata.
+var exifTags = map[string]uint16{
+	"ImageDescription": 0x010e,
+	"Make":             0x010f,
+	"Model":            0x0110,
+	"Orientation":      0x0112,
+	"XResolution":      0x011a,
+	"YResolution":      0x011b,
+	"ResolutionUnit":   0x0128,
+	"Software":         0x0131,
+	"DateTime":         0x0132,
+	"Artist":           0x013b,
+	"Copyright":        0x8298,
+}
+
+// Size in bytes of the TIFF field types.
+var tiffTypeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// filterExif rebuilds EXIF data (a TIFF structure) with only the given tags
+// of the main image directory. Everything else, including the GPS and
+// camera settings directories, is dropped. Returns nil when nothing is
+// left, or when the data can't be read.
+func filterExif(data []byte, keep []string) []byte {
+	if len(keep) == 0 || len(data) < 8 {
+		return nil
+	}
+
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	wanted := make(map[uint16]bool)
+	for _, name := range keep {
+		if tag, ok := exifTags[name]; ok {
+			wanted[tag] = true
+		}
+	}
+
+	ifd := int(order.Uint32(data[4:]))
+	if ifd+2 > len(data) {
+		return nil
+	}
+	count := int(order.Uint16(data[ifd:]))
+	if ifd+2+count*12 > len(data) {
+		return nil
+	}
+
+	entries := make([][]byte, 0)
+	for i := 0; i < count; i++ {
+		entry := data[ifd+2+i*12 : ifd+14+i*12]
+		if wanted[order.Uint16(entry)] {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	header := make([]byte, 8)
+	copy(header, data[:4])
+	order.PutUint32(header[4:], 8)
+
+	table := &bytes.Buffer{}
+	values := &bytes.Buffer{}
+	valuesStart := 8 + 2 + len(entries)*12 + 4
+
+	n := make([]byte, 2)
+	order.PutUint16(n, uint16(len(entries)))
+	table.Write(n)
+	for _, entry := range entries {
+		e := make([]byte, 12)
+		copy(e, entry)
+
+		size := tiffTypeSizes[order.Uint16(entry[2:])] * int(order.Uint32(entry[4:]))
+		if size > 4 {
+			// Values that don't fit in the entry are stored elsewhere.
+			offset := int(order.Uint32(entry[8:]))
+			if size == 0 || offset+size > len(data) {
+				return nil
+			}
+			order.PutUint32(e[8:], uint32(valuesStart+values.Len()))
+			values.Write(data[offset : offset+size])
+			if values.Len()%2 == 1 {
+				values.WriteByte(0)
+			}
+		}
+		table.Write(e)
+	}
+	table.Write([]byte{0, 0, 0, 0})
+
+	return append(append(header, table.Bytes()...), values.Bytes()...)
+}
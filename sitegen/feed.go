@@ -0,0 +1,77 @@
+package sitegen
+
+import (
+	"encoding/xml"
+	"path"
+	"time"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Description string `xml:"description"`
+}
+
+// addFeed adds an RSS feed (index.xml) of the given items to dir. Items are
+// expected to be sorted already, only the first FeedLimit are included.
+func (s *Site) addFeed(dir *ContentItem, title string, items []*ContentItem) {
+	if s.Config.FeedLimit > 0 && len(items) > s.Config.FeedLimit {
+		items = items[:s.Config.FeedLimit]
+	}
+
+	var feed *ContentItem
+	feed = dir.addGenerated("index.xml", Metadata{Title: title}, func() ([]byte, error) {
+		return s.renderFeed(feed, items)
+	})
+}
+
+func (s *Site) renderFeed(feed *ContentItem, items []*ContentItem) ([]byte, error) {
+	channel := rssChannel{
+		Title:       feed.Metadata.Title,
+		Link:        s.absoluteURL(path.Dir(feed.Url) + "/"),
+		Description: feed.Metadata.Title,
+		Items:       make([]rssItem, 0, len(items)),
+	}
+
+	var latest time.Time
+	for _, v := range items {
+		item := rssItem{
+			Title:       v.Metadata.Title,
+			Link:        v.Permalink,
+			GUID:        v.Permalink,
+			Description: string(v.Content),
+		}
+		if !v.Metadata.Date.IsZero() {
+			item.PubDate = v.Metadata.Date.Format(time.RFC1123Z)
+			if v.Metadata.Date.After(latest) {
+				latest = v.Metadata.Date
+			}
+		}
+		channel.Items = append(channel.Items, item)
+	}
+	if !latest.IsZero() {
+		channel.LastBuildDate = latest.Format(time.RFC1123Z)
+	}
+
+	out, err := xml.MarshalIndent(rssFeed{Version: "2.0", Channel: channel}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
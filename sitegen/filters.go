@@ -0,0 +1,26 @@
+package sitegen
+
+// PageFilter changes the HTML of a rendered page before it's written, for
+// use by programs that embed sitegen (e.g. to add an analytics snippet or a
+// banner on staging builds).
+type PageFilter func(c *ContentItem, html []byte) ([]byte, error)
+
+var pageFilters []PageFilter
+
+// RegisterPageFilter adds a filter for rendered pages. Filters run in the
+// order they're registered, after code highlighting and before minification.
+// Pages are rendered concurrently, so filters can run at the same time.
+func RegisterPageFilter(f PageFilter) {
+	pageFilters = append(pageFilters, f)
+}
+
+func filterPage(c *ContentItem, html []byte) ([]byte, error) {
+	for _, f := range pageFilters {
+		var err error
+		html, err = f(c, html)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return html, nil
+}
@@ -0,0 +1,45 @@
+package sitegen
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPageFilters(t *testing.T) {
+	defer func(old []PageFilter) { pageFilters = old }(pageFilters)
+	pageFilters = nil
+
+	RegisterPageFilter(func(c *ContentItem, html []byte) ([]byte, error) {
+		return bytes.Replace(html, []byte("</body>"), []byte("<script src=\"/stats.js\"></script></body>"), 1), nil
+	})
+	RegisterPageFilter(func(c *ContentItem, html []byte) ([]byte, error) {
+		return append([]byte("<!-- "+c.Path+" -->"), html...), nil
+	})
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.TemplateDir = dir
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(`<body>{{.Content}}</body>`), 0644))
+	site := NewSite(config)
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+
+	page := &ContentItem{Site: site, Path: "about.md", Type: Content, Content: "Hi"}
+	out := filepath.Join(dir, "about.html")
+	ok(t, page.WriteContent(out))
+	data, err := ioutil.ReadFile(out)
+	ok(t, err)
+	equals(t, `<!-- about.md --><body>Hi<script src="/stats.js"></script></body>`, string(data))
+
+	RegisterPageFilter(func(c *ContentItem, html []byte) ([]byte, error) {
+		return nil, errors.New("Broken")
+	})
+	assert(t, page.WriteContent(out) != nil, "Expected the error of the filter")
+}
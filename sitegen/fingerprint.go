@@ -0,0 +1,141 @@
+package sitegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// manifestFile lists the fingerprinted assets, written to the output root.
+const manifestFile = "manifest.json"
+
+// fingerprintAssets renames the assets matching the fingerprint patterns to
+// include a hash of their contents as written (style.css becomes
+// style.1a2b3c4d.css) and records where every asset ends up, for the asset
+// template function. Bundles, added before, are fingerprinted as well.
+func (s *Site) fingerprintAssets(root *ContentItem) error {
+	manifest := make(map[string]string)
+	fingerprint := func(v *ContentItem) error {
+		if !s.shouldFingerprint(v.Path) {
+			return nil
+		}
+
+		var hash string
+		if v.Type == Generated || s.transformsAsset(v) {
+			data, err := s.assetData(v)
+			if err != nil {
+				return err
+			}
+			// Kept, so the asset isn't worked out again when it's written.
+			v.written = data
+			sum := sha256.Sum256(data)
+			hash = hex.EncodeToString(sum[:])
+		} else {
+			var err error
+			hash, err = hashFile(v.FullPath)
+			if err != nil {
+				return err
+			}
+		}
+		ext := path.Ext(v.Filename)
+		v.Filename = fmt.Sprintf("%s.%s%s", strings.TrimSuffix(v.Filename, ext), hash[:8], ext)
+		manifest[v.Path] = v.OutputPath()
+		return nil
+	}
+
+	for _, v := range s.assets {
+		if v.Type == Generated {
+			err := fingerprint(v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var walk func(c *ContentItem) error
+	walk = func(c *ContentItem) error {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				err := walk(v)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if v.Type != Asset {
+				continue
+			}
+
+			s.addAsset(v.Path, v)
+			err := fingerprint(v)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := walk(root)
+	if err != nil {
+		return err
+	}
+
+	if len(manifest) > 0 {
+		root.addGenerated(manifestFile, Metadata{}, func() ([]byte, error) {
+			return json.MarshalIndent(manifest, "", "  ")
+		})
+	}
+	return nil
+}
+
+// shouldFingerprint reports whether an asset matches a fingerprint pattern.
+// Patterns without a slash match the filename in any folder.
+func (s *Site) shouldFingerprint(p string) bool {
+	for _, pattern := range s.Config.Fingerprint {
+		name := path.Base(p)
+		if strings.Contains(pattern, "/") {
+			name = p
+		}
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// addAsset registers an asset under its path in the content directory.
+func (s *Site) addAsset(p string, item *ContentItem) {
+	if s.assets == nil {
+		s.assets = make(map[string]*ContentItem)
+	}
+	s.assets[p] = item
+}
+
+// assetURL returns the URL of an asset, given its path in the content
+// directory. Used as the asset template function.
+func (s *Site) assetURL(p string) (string, error) {
+	asset, ok := s.assets[strings.TrimPrefix(p, "/")]
+	if !ok {
+		return "", fmt.Errorf("Unknown asset: %s", p)
+	}
+	return "/" + asset.OutputPath(), nil
+}
+
+func hashFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
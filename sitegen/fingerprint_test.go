@@ -0,0 +1,96 @@
+package sitegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprintAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "css"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "css", "style.css"), []byte("body {}"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "robots.txt"), []byte(""), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Fingerprint = []string{"*.css"}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.fingerprintAssets(root))
+
+	// sha256("body {}") = 62368a1a...
+	style := root.child("css").Children[0]
+	equals(t, style.Filename, "style.62368a1a.css")
+
+	url, err := site.assetURL("css/style.css")
+	ok(t, err)
+	equals(t, url, "/css/style.62368a1a.css")
+
+	url, err = site.assetURL("/robots.txt")
+	ok(t, err)
+	equals(t, url, "/robots.txt")
+
+	_, err = site.assetURL("missing.js")
+	assert(t, err != nil, "Expected error for unknown asset")
+
+	manifest, err := root.child(manifestFile).generate()
+	ok(t, err)
+	equals(t, string(manifest), "{\n  \"css/style.css\": \"css/style.62368a1a.css\"\n}")
+}
+
+func TestFingerprintWrittenAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "style.css"), []byte("body {\n  color: red;\n}\n"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "main.js"), []byte("console.log('hello');\n"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Fingerprint = []string{"*.css", "*.js"}
+	config.Minify = map[string]bool{"css": true}
+	config.Bundles = []Bundle{{Entry: filepath.Join(dir, "main.js"), Output: "js/app.js"}}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	style := root.child("style.css")
+	site.addBundles(root)
+	ok(t, site.fingerprintAssets(root))
+
+	// Named after the minified output, not the source.
+	data, err := site.assetData(style)
+	ok(t, err)
+	equals(t, string(data), "body{color:red}")
+	sum := sha256.Sum256(data)
+	equals(t, style.Filename, "style."+hex.EncodeToString(sum[:])[:8]+".css")
+
+	bundle := root.child("js").Children[0]
+	data, err = site.assetData(bundle)
+	ok(t, err)
+	sum = sha256.Sum256(data)
+	url, err := site.assetURL("js/app.js")
+	ok(t, err)
+	equals(t, url, "/js/app."+hex.EncodeToString(sum[:])[:8]+".js")
+}
+
+func TestShouldFingerprint(t *testing.T) {
+	config := DefaultConfig()
+	config.Fingerprint = []string{"*.css", "js/**/*.js"}
+	site := NewSite(config)
+
+	assert(t, site.shouldFingerprint("css/style.css"), "Expected css/style.css")
+	assert(t, site.shouldFingerprint("js/app.js"), "Expected js/app.js")
+	assert(t, site.shouldFingerprint("js/vendor/lib/x.js"), "Expected js/vendor/lib/x.js")
+	assert(t, !site.shouldFingerprint("vendor/x.js"), "Unexpected vendor/x.js")
+}
@@ -0,0 +1,83 @@
+package sitegen
+
+import (
+	"fmt"
+	"path"
+)
+
+// FormatHandler renders the content files of a format to HTML, for use by
+// programs that embed sitegen to add formats (e.g. AsciiDoc). The front
+// matter is split off before rendering, as for the built-in formats.
+type FormatHandler interface {
+	// Extensions returns the file extensions of the format, with the dot
+	// (".adoc").
+	Extensions() []string
+
+	// Render turns the body of a content file into HTML.
+	Render(in []byte) ([]byte, error)
+}
+
+var formatHandlers = map[string]FormatHandler{}
+
+func init() {
+	RegisterFormat(htmlFormat{})
+	RegisterFormat(markdownFormat{})
+}
+
+// RegisterFormat adds a handler for content files. It replaces the handler
+// of an extension that's already handled, including the built-in ones for
+// .md and .html.
+func RegisterFormat(h FormatHandler) {
+	for _, ext := range h.Extensions() {
+		formatHandlers[ext] = h
+	}
+}
+
+func formatFor(filename string) FormatHandler {
+	return formatHandlers[path.Ext(filename)]
+}
+
+func isContentFile(filename string) bool {
+	return formatFor(filename) != nil
+}
+
+// renderContent renders the body of a content file to HTML, with the given
+// handler.
+func (s *Site) renderContent(h FormatHandler, body []byte, sourcePath string, metadata Metadata) ([]byte, error) {
+	switch h := h.(type) {
+	case nil:
+		return nil, fmt.Errorf("%s: not a content file", sourcePath)
+	case markdownFormat:
+		// With the settings of the site and page.
+		return s.markdown(body, sourcePath, metadata)
+	default:
+		out, err := h.Render(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sourcePath, err)
+		}
+		return out, nil
+	}
+}
+
+// htmlFormat is used as is.
+type htmlFormat struct{}
+
+func (htmlFormat) Extensions() []string {
+	return []string{".html"}
+}
+
+func (htmlFormat) Render(in []byte) ([]byte, error) {
+	return in, nil
+}
+
+// markdownFormat renders Markdown, with the default settings when used as a
+// FormatHandler.
+type markdownFormat struct{}
+
+func (markdownFormat) Extensions() []string {
+	return []string{".md"}
+}
+
+func (markdownFormat) Render(in []byte) ([]byte, error) {
+	return RenderMarkdown(in), nil
+}
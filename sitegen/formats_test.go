@@ -0,0 +1,57 @@
+package sitegen
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type shoutFormat struct{}
+
+func (shoutFormat) Extensions() []string {
+	return []string{".shout", ".yell"}
+}
+
+func (shoutFormat) Render(in []byte) ([]byte, error) {
+	if len(in) == 0 {
+		return nil, errors.New("Nothing to shout")
+	}
+	return append(append([]byte("<p>"), bytes.ToUpper(bytes.TrimSpace(in))...), "</p>"...), nil
+}
+
+func TestFormatHandler(t *testing.T) {
+	defer func(old map[string]FormatHandler) { formatHandlers = old }(formatHandlers)
+	formatHandlers = map[string]FormatHandler{}
+	RegisterFormat(htmlFormat{})
+	RegisterFormat(markdownFormat{})
+
+	assert(t, isContentFile("index.md"), "Expected Markdown to be content")
+	assert(t, !isContentFile("hello.shout"), "Expected unregistered formats to be assets")
+	RegisterFormat(shoutFormat{})
+	assert(t, isContentFile("hello.shout"), "Expected registered formats to be content")
+	assert(t, isContentFile("hello.nl.yell"), "Expected all extensions to be registered")
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "hello.shout"), []byte("---\ntitle: Hi\n---\nhello\n"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	page := root.child("hello.html")
+	assert(t, page != nil, "Expected the page to be rendered to hello.html")
+	equals(t, Content, page.Type)
+	equals(t, "Hi", page.Metadata.Title)
+	equals(t, "<p>HELLO</p>", string(page.Content))
+
+	_, err = site.renderContent(shoutFormat{}, nil, "empty.shout", Metadata{})
+	equals(t, "empty.shout: Nothing to shout", err.Error())
+}
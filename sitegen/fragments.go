@@ -0,0 +1,53 @@
+package sitegen
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"strings"
+)
+
+// collectFragments takes the pages marked with "fragment: true" out of the
+// tree. They're rendered, but not written on their own: templates include
+// them by name (their path without extension, e.g. home/intro) with the
+// fragment function.
+func (s *Site) collectFragments(root *ContentItem) {
+	s.fragments = make(map[string]*ContentItem)
+
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		children := c.Children[:0]
+		for _, v := range c.Children {
+			if v.Type == Content && v.Metadata.Fragment {
+				s.fragments[strings.TrimSuffix(v.Path, path.Ext(v.Path))] = v
+				continue
+			}
+			if v.Type == Directory {
+				walk(v)
+			}
+			children = append(children, v)
+		}
+		c.Children = children
+	}
+	walk(root)
+}
+
+// fragment returns the rendered content of a fragment. Used as the fragment
+// template function: {{fragment "home/intro" .}} resolves the references in
+// it relative to the including page, without a page they're relative to the
+// fragment itself.
+func (s *Site) fragment(name string, from ...*ContentItem) (template.HTML, error) {
+	f, ok := s.fragments[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return "", fmt.Errorf("Unknown fragment: %s", name)
+	}
+	page := f
+	if len(from) > 0 && from[0] != nil {
+		page = from[0]
+	}
+	content, err := s.resolveRefsIn(page, f.Content)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", f.FullPath, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,35 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFragments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "home"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.md"), []byte("# Home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "home", "intro.md"), []byte("---\nfragment: true\n---\n\nHello *there*"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.collectFragments(root)
+
+	equals(t, len(root.child("home").Children), 0)
+
+	html, err := site.fragment("home/intro")
+	ok(t, err)
+	equals(t, string(html), "<p>Hello <em>there</em></p>\n")
+
+	_, err = site.fragment("index")
+	assert(t, err != nil, "Expected error for regular page")
+}
@@ -0,0 +1,48 @@
+package sitegen
+
+import (
+	"encoding/json"
+	"html"
+	"html/template"
+)
+
+// templateFuncs returns the functions available in templates.
+func (s *Site) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"jsonify":    jsonify,
+		"dataIsland": dataIsland,
+		"asset":      s.assetURL,
+		"integrity":  s.assetIntegrity,
+		"fragment":   s.fragment,
+		"image":      s.image,
+		"picture":    s.picture,
+		"socialMeta": s.socialMeta,
+		"jsonLD":     s.jsonLD,
+		"absURL":     s.absoluteURL,
+		"relURL":     s.relativeURL,
+		"i18n":       s.i18n,
+		"dateFormat": s.dateFormat,
+		"ref":        s.ref,
+		"relref":     s.relref,
+	}
+}
+
+// jsonify encodes v as JSON that is safe to use inside a script element:
+// <, > and & are escaped, so a string can never close the element.
+func jsonify(v interface{}) (template.JS, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(data), nil
+}
+
+// dataIsland wraps v in a <script type="application/json"> element with the
+// given id, for client-side code to pick up with JSON.parse.
+func dataIsland(id string, v interface{}) (template.HTML, error) {
+	data, err := jsonify(v)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(`<script type="application/json" id="` + html.EscapeString(id) + `">` + string(data) + `</script>`), nil
+}
@@ -0,0 +1,22 @@
+package sitegen
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+)
+
+func TestJsonify(t *testing.T) {
+	out, err := jsonify(map[string]string{"a": "</script><b>&"})
+	ok(t, err)
+	equals(t, string(out), `{"a":"\u003c/script\u003e\u003cb\u003e\u0026"}`)
+}
+
+func TestDataIsland(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	tmpl := template.Must(template.New("").Funcs(site.templateFuncs()).Parse(`{{dataIsland "data" .}}`))
+
+	buf := &bytes.Buffer{}
+	ok(t, tmpl.Execute(buf, []string{"</script>"}))
+	equals(t, buf.String(), `<script type="application/json" id="data">["\u003c/script\u003e"]</script>`)
+}
@@ -0,0 +1,224 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+const galleryFile = "_gallery.yaml"
+
+// GalleryConfig configures the gallery pages generated for directories of
+// images.
+type GalleryConfig struct {
+	// Directories that become galleries, as glob patterns matched against
+	// their path in the content directory. Directories with a _gallery.yaml
+	// file are galleries too.
+	Dirs []string `yaml:"dirs"`
+
+	// Template of gallery pages, defaults to "gallery".
+	Template string `yaml:"template"`
+
+	// Size of the thumbnails, as for the image template function. Defaults
+	// to "400x400 crop".
+	ThumbnailSize string `yaml:"thumbnailSize"`
+}
+
+// galleryMetadata is the contents of a _gallery.yaml file.
+type galleryMetadata struct {
+	Title    string `yaml:"title"`
+	Template string `yaml:"template"`
+
+	// Captions and order of the images. Images that aren't listed come
+	// after the listed ones, sorted by name.
+	Images []struct {
+		File    string `yaml:"file"`
+		Caption string `yaml:"caption"`
+	} `yaml:"images"`
+}
+
+// Gallery is the list of images on a gallery page.
+type Gallery struct {
+	Images []*GalleryImage
+
+	site          *Site
+	thumbnailSize string
+}
+
+// GalleryImage is one image of a gallery.
+type GalleryImage struct {
+	Image   *ContentItem
+	Caption string
+	Width   int
+	Height  int
+
+	gallery *Gallery
+}
+
+// Url returns the URL of the full image.
+func (i *GalleryImage) Url() string {
+	return "/" + i.Image.OutputPath()
+}
+
+// Thumbnail returns the URL of the thumbnail of the image.
+func (i *GalleryImage) Thumbnail() (string, error) {
+	return i.gallery.site.image(i.Image.Path, i.gallery.thumbnailSize)
+}
+
+// HTML returns lightbox-ready markup for the gallery: a figure per image,
+// with a thumbnail linking to the full image.
+func (g *Gallery) HTML() (template.HTML, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(`<div class="gallery">`)
+	for _, img := range g.Images {
+		thumbnail, err := img.Thumbnail()
+		if err != nil {
+			return "", err
+		}
+		caption := html.EscapeString(img.Caption)
+		fmt.Fprintf(buf, `<figure class="gallery-item"><a href="%s" data-lightbox="gallery" data-caption="%s" data-width="%d" data-height="%d">`,
+			html.EscapeString(img.Url()), caption, img.Width, img.Height)
+		fmt.Fprintf(buf, `<img src="%s" alt="%s" loading="lazy"></a>`, html.EscapeString(thumbnail), caption)
+		if img.Caption != "" {
+			fmt.Fprintf(buf, `<figcaption>%s</figcaption>`, caption)
+		}
+		buf.WriteString(`</figure>`)
+	}
+	buf.WriteString(`</div>`)
+	return template.HTML(buf.String()), nil
+}
+
+// buildGalleries adds a gallery page (index.html) to every gallery
+// directory, unless it already has one.
+func (s *Site) buildGalleries(root *ContentItem) error {
+	var walk func(c *ContentItem) error
+	walk = func(c *ContentItem) error {
+		var metadataFile *ContentItem
+		children := c.Children[:0]
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				err := walk(v)
+				if err != nil {
+					return err
+				}
+			}
+			if v.Type == Asset && v.Filename == galleryFile {
+				metadataFile = v
+				continue
+			}
+			children = append(children, v)
+		}
+		c.Children = children
+
+		if metadataFile == nil && !s.isGalleryDir(c.Path) {
+			return nil
+		}
+		return s.addGallery(c, metadataFile)
+	}
+	return walk(root)
+}
+
+func (s *Site) isGalleryDir(p string) bool {
+	for _, pattern := range s.Config.Gallery.Dirs {
+		if matchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Site) addGallery(dir *ContentItem, metadataFile *ContentItem) error {
+	meta := galleryMetadata{}
+	if metadataFile != nil {
+		data, err := ioutil.ReadFile(metadataFile.FullPath)
+		if err != nil {
+			return err
+		}
+		err = yaml.Unmarshal(data, &meta)
+		if err != nil {
+			return categorize(ParseError, fmt.Errorf("%s: %w", metadataFile.FullPath, err))
+		}
+	}
+	if dir.child("index.html") != nil {
+		// Don't overwrite a page that was written by hand.
+		return nil
+	}
+
+	gallery := &Gallery{
+		site:          s,
+		thumbnailSize: s.Config.Gallery.ThumbnailSize,
+	}
+
+	images := make(map[string]*ContentItem)
+	names := make([]string, 0)
+	for _, v := range dir.Children {
+		if v.Type == Asset && isTranscodableImage(v.Filename) {
+			images[v.Filename] = v
+			names = append(names, v.Filename)
+		}
+	}
+	sort.Strings(names)
+
+	add := func(name, caption string) error {
+		item := images[name]
+		delete(images, name)
+
+		img := &GalleryImage{Image: item, Caption: caption, gallery: gallery}
+		f, err := os.Open(item.FullPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if cfg, _, err := image.DecodeConfig(f); err == nil {
+			img.Width, img.Height = cfg.Width, cfg.Height
+		}
+		gallery.Images = append(gallery.Images, img)
+		return nil
+	}
+	for _, v := range meta.Images {
+		if images[v.File] == nil {
+			return categorize(ParseError, fmt.Errorf("%s: unknown image %s", metadataFile.FullPath, v.File))
+		}
+		err := add(v.File, v.Caption)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if images[name] != nil {
+			err := add(name, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	metadata := Metadata{
+		Title:    meta.Title,
+		Template: meta.Template,
+	}
+	if metadata.Title == "" {
+		metadata.Title = humanizeFilename(path.Join(dir.Path, "index"))
+	}
+	if metadata.Template == "" {
+		metadata.Template = s.Config.Gallery.Template
+	}
+
+	dir.Children = append(dir.Children, &ContentItem{
+		Site:     s,
+		Filename: "index.html",
+		Path:     path.Join(dir.Path, "index.html"),
+		Type:     Content,
+		Metadata: metadata,
+		Gallery:  gallery,
+	})
+	return nil
+}
@@ -0,0 +1,76 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGallery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	holiday := filepath.Join(content, "photos", "holiday")
+	ok(t, os.MkdirAll(holiday, 0755))
+	ok(t, os.MkdirAll(filepath.Join(content, "photos", "misc"), 0755))
+	for _, name := range []string{"a.jpg", "b.jpg", "c.png"} {
+		format := "jpeg"
+		if strings.HasSuffix(name, ".png") {
+			format = "png"
+		}
+		ok(t, ioutil.WriteFile(filepath.Join(holiday, name), testImage(t, format, 40, 20), 0644))
+	}
+	ok(t, ioutil.WriteFile(filepath.Join(holiday, galleryFile), []byte(`
+title: Holiday
+images:
+  - file: c.png
+    caption: Sunset <3
+`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "photos", "misc", "d.jpg"), testImage(t, "jpeg", 10, 10), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.OutputDir = filepath.Join(dir, "output")
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.Gallery.ThumbnailSize = "10x10 crop"
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.fingerprintAssets(root))
+	ok(t, site.buildGalleries(root))
+	root.Process()
+
+	photos := root.child("photos")
+	assert(t, photos.child("holiday").child(galleryFile) == nil, "Gallery metadata should not be published")
+	assert(t, photos.child("misc").child("index.html") == nil, "Unexpected gallery without configuration")
+
+	page := photos.child("holiday").child("index.html")
+	assert(t, page != nil, "Expected gallery page")
+	equals(t, page.Metadata.Title, "Holiday")
+	equals(t, page.Metadata.Template, "gallery")
+	equals(t, page.Url, "/photos/holiday/")
+
+	images := page.Gallery.Images
+	equals(t, len(images), 3)
+	equals(t, []string{images[0].Url(), images[1].Url(), images[2].Url()},
+		[]string{"/photos/holiday/c.png", "/photos/holiday/a.jpg", "/photos/holiday/b.jpg"})
+	equals(t, []int{images[0].Width, images[0].Height}, []int{40, 20})
+
+	html, err := page.Gallery.HTML()
+	ok(t, err)
+	assert(t, strings.HasPrefix(string(html), `<div class="gallery"><figure class="gallery-item"><a href="/photos/holiday/c.png" data-lightbox="gallery" data-caption="Sunset &lt;3" data-width="40" data-height="20"><img src="/photos/holiday/c_10x10_crop.png" alt="Sunset &lt;3" loading="lazy"></a><figcaption>Sunset &lt;3</figcaption></figure>`), "Unexpected markup: %s", html)
+
+	// Directories can also be configured as galleries.
+	config.Gallery.Dirs = []string{"photos/*"}
+	root, err = site.crawlContent()
+	ok(t, err)
+	ok(t, site.buildGalleries(root))
+	page = root.child("photos").child("misc").child("index.html")
+	assert(t, page != nil, "Expected gallery page")
+	equals(t, page.Metadata.Title, "Misc")
+}
@@ -0,0 +1,208 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitHubPagesConfig holds the settings for deploying to a git branch, as
+// served by GitHub Pages.
+type GitHubPagesConfig struct {
+	// Branch the site is committed to, defaults to gh-pages.
+	Branch string `yaml:"branch"`
+
+	// Remote the branch is pushed to, defaults to origin.
+	Remote string `yaml:"remote"`
+
+	// Commit message, defaults to "Update site".
+	Message string `yaml:"message"`
+
+	// Write a CNAME file and an empty .nojekyll file (so files starting
+	// with an underscore are served) into the output on every build.
+	WriteFiles bool `yaml:"writeFiles"`
+
+	// Custom domain written to CNAME, defaults to the host of baseURL.
+	// Sites on github.io don't get a CNAME.
+	Domain string `yaml:"domain"`
+}
+
+// Files of the branch that are kept when the output doesn't have them, as
+// they're usually made by hand (CNAME holds the custom domain).
+var ghPagesKeep = []string{"CNAME", ".nojekyll"}
+
+// addGitHubPagesFiles adds the CNAME and .nojekyll files GitHub Pages
+// looks for, unless the content has them.
+func (s *Site) addGitHubPagesFiles(root *ContentItem) error {
+	config := s.Config.Deploy.GitHubPages
+	if !config.WriteFiles {
+		return nil
+	}
+
+	domain := config.Domain
+	if domain == "" && s.Config.BaseURL != "" {
+		base, err := url.Parse(s.Config.BaseURL)
+		if err != nil {
+			return err
+		}
+		domain = base.Hostname()
+	}
+	if domain != "" && !strings.HasSuffix(domain, ".github.io") && root.child("CNAME") == nil {
+		root.addGenerated("CNAME", Metadata{}, func() ([]byte, error) {
+			return []byte(domain + "\n"), nil
+		})
+	}
+	if root.child(".nojekyll") == nil {
+		root.addGenerated(".nojekyll", Metadata{}, func() ([]byte, error) {
+			return []byte{}, nil
+		})
+	}
+	return nil
+}
+
+// deployGitHubPages commits the output directory to a branch of the git
+// repository it's in and pushes it. The branch only ever holds the site, the
+// commit is made without touching the working tree or the index.
+func (s *Site) deployGitHubPages(dryRun bool) error {
+	config := s.Config.Deploy.GitHubPages
+	if config.Branch == "" {
+		config.Branch = "gh-pages"
+	}
+	if config.Remote == "" {
+		config.Remote = "origin"
+	}
+	out, err := filepath.Abs(s.Config.OutputDir)
+	if err != nil {
+		return err
+	}
+	g := &gitRunner{dir: filepath.Dir(out)}
+
+	// The branch may not exist yet.
+	_, _ = g.run(nil, "fetch", "-q", config.Remote, config.Branch)
+	parent, _ := g.run(nil, "rev-parse", "-q", "--verify", "refs/remotes/"+config.Remote+"/"+config.Branch)
+
+	tmp, err := ioutil.TempDir("", "sitegen")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	g.env = []string{"GIT_INDEX_FILE=" + filepath.Join(tmp, "index")}
+
+	files, err := s.outputFiles()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Path)
+	}
+	for _, name := range ghPagesKeep {
+		if info, err := os.Stat(filepath.Join(out, name)); err == nil && info.Mode().IsRegular() && s.keepFile(name) {
+			names = append(names, name)
+		}
+	}
+
+	paths := &bytes.Buffer{}
+	for _, name := range names {
+		paths.WriteString(filepath.Join(out, filepath.FromSlash(name)) + "\n")
+	}
+	hashes, err := g.run(paths, "hash-object", "-w", "--no-filters", "--stdin-paths")
+	if err != nil {
+		return err
+	}
+	entries := &bytes.Buffer{}
+	for i, hash := range strings.Fields(hashes) {
+		fmt.Fprintf(entries, "100644 %s\t%s\n", hash, names[i])
+	}
+	if parent != "" {
+		// Carried over from the previous version, unless there's a new one.
+		for _, name := range ghPagesKeep {
+			if !containsString(names, name) {
+				kept, err := g.run(nil, "ls-tree", parent, name)
+				if err != nil {
+					return err
+				}
+				if kept != "" {
+					entries.WriteString(kept + "\n")
+				}
+			}
+		}
+	}
+	_, err = g.run(entries, "update-index", "--add", "--index-info")
+	if err != nil {
+		return err
+	}
+	tree, err := g.run(nil, "write-tree")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"commit-tree", tree}
+	if parent != "" {
+		previous, err := g.run(nil, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return err
+		}
+		if previous == tree {
+			log.Println("No changes")
+			return nil
+		}
+		args = append(args, "-p", parent)
+	}
+	message := config.Message
+	if message == "" {
+		message = "Update site"
+	}
+	args = append(args, "-m", message)
+	commit, err := g.run(nil, args...)
+	if err != nil {
+		return err
+	}
+
+	log.Printf(" -> %s %s\n", config.Branch, commit)
+	if dryRun {
+		return nil
+	}
+	_, err = g.run(nil, "push", "-q", config.Remote, commit+":refs/heads/"+config.Branch)
+	return err
+}
+
+// gitRunner runs git commands in a directory.
+type gitRunner struct {
+	dir string
+	env []string
+}
+
+// run runs a git command and returns its output, trimmed.
+func (g *gitRunner) run(stdin *bytes.Buffer, args ...string) (string, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = g.dir
+	cmd.Env = append(os.Environ(), g.env...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s\n%s", args[0], err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
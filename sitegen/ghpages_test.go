@@ -0,0 +1,113 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeployGitHubPages(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	remote := filepath.Join(dir, "remote.git")
+	site := filepath.Join(dir, "site")
+	ok(t, os.MkdirAll(filepath.Join(site, "static", "blog"), 0755))
+	env := []string{
+		"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+	}
+	git := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git(dir, "init", "-q", "--bare", remote)
+	git(site, "init", "-q")
+	git(site, "remote", "add", "origin", remote)
+	// For the commits made by the deploy.
+	for _, v := range env {
+		kv := strings.SplitN(v, "=", 2)
+		defer os.Unsetenv(kv[0])
+		os.Setenv(kv[0], kv[1])
+	}
+
+	out := filepath.Join(site, "static")
+	ok(t, ioutil.WriteFile(filepath.Join(out, "index.html"), []byte("home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(out, "blog", "post.html"), []byte("post"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(out, "CNAME"), []byte("example.com"), 0644))
+
+	config := DefaultConfig()
+	config.OutputDir = out
+	s := NewSite(config)
+	ok(t, s.Deploy([]string{"gh-pages"}))
+	equals(t, "CNAME\nblog/post.html\nindex.html", git(remote, "ls-tree", "-r", "--name-only", "gh-pages"))
+	first := git(remote, "rev-parse", "gh-pages")
+
+	// Nothing changed, nothing to commit.
+	ok(t, s.Deploy([]string{"gh-pages"}))
+	equals(t, first, git(remote, "rev-parse", "gh-pages"))
+
+	// CNAME is kept, even when it's gone from the output.
+	ok(t, os.Remove(filepath.Join(out, "CNAME")))
+	ok(t, os.Remove(filepath.Join(out, "blog", "post.html")))
+	ok(t, ioutil.WriteFile(filepath.Join(out, "index.html"), []byte("new home"), 0644))
+	ok(t, s.Deploy([]string{"gh-pages"}))
+	equals(t, "CNAME\nindex.html", git(remote, "ls-tree", "-r", "--name-only", "gh-pages"))
+	equals(t, "new home", git(remote, "show", "gh-pages:index.html"))
+	equals(t, first, git(remote, "rev-parse", "gh-pages^"))
+
+	// Dry runs don't push.
+	ok(t, ioutil.WriteFile(filepath.Join(out, "index.html"), []byte("draft"), 0644))
+	ok(t, s.Deploy([]string{"--dry-run", "gh-pages"}))
+	equals(t, "new home", git(remote, "show", "gh-pages:index.html"))
+}
+
+func TestGitHubPagesFiles(t *testing.T) {
+	config := DefaultConfig()
+	config.BaseURL = "https://www.example.com/"
+	site := NewSite(config)
+
+	root := &ContentItem{Site: site, Type: Directory}
+	ok(t, site.addGitHubPagesFiles(root))
+	equals(t, 0, len(root.Children))
+
+	config.Deploy.GitHubPages.WriteFiles = true
+	ok(t, site.addGitHubPagesFiles(root))
+	data, err := root.child("CNAME").generate()
+	ok(t, err)
+	equals(t, "www.example.com\n", string(data))
+	data, err = root.child(".nojekyll").generate()
+	ok(t, err)
+	equals(t, "", string(data))
+
+	// Files in the content win.
+	root = &ContentItem{Site: site, Type: Directory, Children: []*ContentItem{{Filename: "CNAME", Type: Asset}}}
+	ok(t, site.addGitHubPagesFiles(root))
+	equals(t, 2, len(root.Children))
+	equals(t, Asset, root.child("CNAME").Type)
+
+	config.BaseURL = "https://example.github.io/project/"
+	root = &ContentItem{Site: site, Type: Directory}
+	ok(t, site.addGitHubPagesFiles(root))
+	assert(t, root.child("CNAME") == nil, "Expected no CNAME for github.io")
+
+	config.Deploy.GitHubPages.Domain = "docs.example.com"
+	ok(t, site.addGitHubPagesFiles(root))
+	data, err = root.child("CNAME").generate()
+	ok(t, err)
+	equals(t, "docs.example.com\n", string(data))
+}
@@ -0,0 +1,90 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitFileInfo is the last commit that touched a file.
+type gitFileInfo struct {
+	Commit  string
+	Author  string
+	Lastmod time.Time
+}
+
+// addGitInfo sets the last modification time, author and commit of content
+// files from the git history, if enabled. Files that aren't committed (or
+// content that isn't in a repository) keep their modification time.
+func (s *Site) addGitInfo(root *ContentItem) error {
+	if !s.Config.GitInfo {
+		return nil
+	}
+
+	files := make(map[string]gitFileInfo)
+	for _, dir := range s.Config.ContentDirs {
+		err := readGitLog(dir, files)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, page := range root.allPages() {
+		if info, ok := files[page.FullPath]; ok {
+			page.Lastmod = info.Lastmod
+			page.GitAuthor = info.Author
+			page.GitCommit = info.Commit
+		}
+	}
+	return nil
+}
+
+// readGitLog adds the last commit of each file in dir to files, keyed by
+// their path (joined with dir).
+func readGitLog(dir string, files map[string]gitFileInfo) error {
+	cmd := exec.Command("git", "-c", "core.quotepath=off", "log",
+		"--format=%x1e%H%x1f%an%x1f%ct", "--name-only", "--relative", "--", ".")
+	cmd.Dir = dir
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if strings.Contains(stderr.String(), "not a git repository") {
+			return nil
+		}
+		return fmt.Errorf("%s: git log failed: %s\n%s", dir, err, stderr.String())
+	}
+
+	for _, record := range strings.Split(string(out), "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		header := strings.Split(lines[0], "\x1f")
+		if len(header) != 3 {
+			continue
+		}
+		sec, err := strconv.ParseInt(header[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: unexpected git log output: %s", dir, lines[0])
+		}
+		info := gitFileInfo{
+			Commit:  header[0],
+			Author:  header[1],
+			Lastmod: time.Unix(sec, 0).In(location()),
+		}
+
+		// Newest commits come first.
+		for _, name := range lines[1:] {
+			if name == "" {
+				continue
+			}
+			p := filepath.Join(dir, filepath.FromSlash(name))
+			if _, ok := files[p]; !ok {
+				files[p] = info
+			}
+		}
+	}
+	return nil
+}
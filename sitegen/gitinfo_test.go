@@ -0,0 +1,96 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGitInfo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "blog"), 0755))
+
+	git := func(env []string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %s\n%s", args, err, out)
+		}
+	}
+	commit := func(date, author string) {
+		git(nil, "add", ".")
+		git([]string{
+			"GIT_AUTHOR_NAME=" + author, "GIT_AUTHOR_EMAIL=author@example.com",
+			"GIT_COMMITTER_NAME=" + author, "GIT_COMMITTER_EMAIL=author@example.com",
+			"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date,
+		}, "commit", "-q", "-m", "Update")
+	}
+	write := func(name, data string) {
+		ok(t, ioutil.WriteFile(filepath.Join(content, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	git(nil, "init", "-q")
+	write("index.md", "Home")
+	write("blog/post.md", "Post")
+	commit("2024-01-01T10:00:00Z", "Alice")
+	write("blog/post.md", "Post, updated")
+	commit("2024-02-01T10:00:00Z", "Bob")
+	write("draft.md", "Not committed")
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.GitInfo = true
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.addGitInfo(root))
+
+	index := root.child("index.html")
+	equals(t, index.GitAuthor, "Alice")
+	assert(t, index.Lastmod.Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)), "Unexpected lastmod: %s", index.Lastmod)
+	equals(t, len(index.GitCommit), 40)
+
+	post := root.child("blog").child("post.html")
+	equals(t, post.GitAuthor, "Bob")
+	assert(t, post.Lastmod.Equal(time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC)), "Unexpected lastmod: %s", post.Lastmod)
+	assert(t, post.GitCommit != index.GitCommit, "Expected different commits")
+
+	draft := root.child("draft.html")
+	equals(t, draft.GitAuthor, "")
+	assert(t, !draft.Lastmod.IsZero(), "Expected modification time")
+}
+
+func TestGitInfoOutsideRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.md"), []byte("Home"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.GitInfo = true
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.addGitInfo(root))
+
+	index := root.child("index.html")
+	equals(t, index.GitCommit, "")
+	assert(t, !index.Lastmod.IsZero(), "Expected modification time")
+}
@@ -0,0 +1,21 @@
+package sitegen
+
+import (
+	"path"
+	"strings"
+)
+
+// includeFile reports whether a file or directory in the content is part of
+// the site: hidden ones (.DS_Store, .git) are left out, unless they're in
+// the hiddenFiles list.
+func (s *Site) includeFile(p string) bool {
+	if !strings.HasPrefix(path.Base(p), ".") {
+		return true
+	}
+	for _, pattern := range s.Config.HiddenFiles {
+		if matchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHiddenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, p := range []string{".well-known/security.txt", ".git/HEAD", "blog/.DS_Store", "blog/.post.md.swp", "blog/post.md", ".htaccess"} {
+		file := filepath.Join(dir, filepath.FromSlash(p))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte("x"), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	names := func(c *ContentItem) []string {
+		list := make([]string, 0)
+		for _, v := range c.Children {
+			list = append(list, v.Filename)
+		}
+		return list
+	}
+	equals(t, []string{".well-known", "blog"}, names(root))
+	equals(t, []string{"security.txt"}, names(root.child(".well-known")))
+	equals(t, []string{"post.html"}, names(root.child("blog")))
+
+	config.HiddenFiles = append(config.HiddenFiles, ".htaccess")
+	root, err = site.crawlContent()
+	ok(t, err)
+	equals(t, []string{".htaccess", ".well-known", "blog"}, names(root))
+}
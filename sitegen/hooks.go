@@ -0,0 +1,63 @@
+package sitegen
+
+import "sync"
+
+// BuildHook runs around a build, for use by programs that embed sitegen
+// (e.g. to run an asset pipeline first or upload the result afterwards).
+// Hooks don't run for BuildFS, page hooks don't run for archive builds
+// either: those pages aren't written to a file.
+type BuildHook func(s *Site) error
+
+// PageHook runs for each page, with the file it was written to.
+type PageHook func(c *ContentItem, path string) error
+
+var (
+	beforeBuildHooks []BuildHook
+	afterBuildHooks  []BuildHook
+	pageHooks        []PageHook
+	pageHooksLock    sync.Mutex
+)
+
+// OnBeforeBuild adds a function that runs at the start of every build,
+// before anything is read. An error stops the build.
+func OnBeforeBuild(f BuildHook) {
+	beforeBuildHooks = append(beforeBuildHooks, f)
+}
+
+// OnAfterBuild adds a function that runs after a successful build, once
+// everything is written.
+func OnAfterBuild(f BuildHook) {
+	afterBuildHooks = append(afterBuildHooks, f)
+}
+
+// OnPageRendered adds a function that runs after each page is written.
+// Pages are written concurrently, but the hooks run one at a time.
+func OnPageRendered(f PageHook) {
+	pageHooks = append(pageHooks, f)
+}
+
+func runBuildHooks(hooks []BuildHook, s *Site) error {
+	for _, f := range hooks {
+		err := f(s)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPageHooks(c *ContentItem, path string) error {
+	if len(pageHooks) == 0 {
+		return nil
+	}
+
+	pageHooksLock.Lock()
+	defer pageHooksLock.Unlock()
+	for _, f := range pageHooks {
+		err := f(c, path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package sitegen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildHooks(t *testing.T) {
+	defer func(before, after []BuildHook, pages []PageHook) {
+		beforeBuildHooks, afterBuildHooks, pageHooks = before, after, pages
+	}(beforeBuildHooks, afterBuildHooks, pageHooks)
+	beforeBuildHooks, afterBuildHooks, pageHooks = nil, nil, nil
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "static")
+	ok(t, os.MkdirAll(config.TemplateDir, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "page.html"), []byte(`{{.Content}}`), 0644))
+	site := NewSite(config)
+
+	events := make([]string, 0)
+	OnBeforeBuild(func(s *Site) error {
+		// Content can be generated before it's read.
+		events = append(events, "before")
+		ok(t, os.MkdirAll(config.ContentDirs[0], 0755))
+		return ioutil.WriteFile(filepath.Join(config.ContentDirs[0], "index.md"), []byte("Hi"), 0644)
+	})
+	OnPageRendered(func(c *ContentItem, path string) error {
+		events = append(events, "page "+c.Path)
+		_, err := os.Stat(path)
+		return err
+	})
+	OnAfterBuild(func(s *Site) error {
+		events = append(events, "after")
+		return nil
+	})
+
+	ok(t, site.Build())
+	equals(t, []string{"before", "page index.md", "after"}, events)
+
+	OnBeforeBuild(func(s *Site) error {
+		return errors.New("Not today")
+	})
+	events = events[:0]
+	err = site.Build()
+	equals(t, "Not today", err.Error())
+	equals(t, []string{"before"}, events)
+}
+
+func TestBuildHooksInMemory(t *testing.T) {
+	defer func(before, after []BuildHook, pages []PageHook) {
+		beforeBuildHooks, afterBuildHooks, pageHooks = before, after, pages
+	}(beforeBuildHooks, afterBuildHooks, pageHooks)
+	beforeBuildHooks, afterBuildHooks, pageHooks = nil, nil, nil
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "static")
+	ok(t, os.MkdirAll(config.ContentDirs[0], 0755))
+	ok(t, os.MkdirAll(config.TemplateDir, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(config.ContentDirs[0], "index.md"), []byte("Hi"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "page.html"), []byte(`{{.Content}}`), 0644))
+	site := NewSite(config)
+
+	events := make([]string, 0)
+	OnBeforeBuild(func(s *Site) error {
+		events = append(events, "before")
+		return nil
+	})
+	OnPageRendered(func(c *ContentItem, path string) error {
+		events = append(events, "page "+c.Path)
+		return nil
+	})
+	OnAfterBuild(func(s *Site) error {
+		events = append(events, "after")
+		return nil
+	})
+
+	_, err = site.BuildFS()
+	ok(t, err)
+	equals(t, []string{}, events)
+}
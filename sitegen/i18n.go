@@ -0,0 +1,114 @@
+package sitegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// translation is one entry of a translation table: a plain string, or the
+// plural forms (zero, one, other) keyed by name.
+type translation struct {
+	Text   string
+	Plural map[string]string
+}
+
+func (t *translation) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&t.Text); err == nil {
+		return nil
+	}
+	return unmarshal(&t.Plural)
+}
+
+// loadTranslations reads the translation tables, i18n/<lang>.yaml.
+func (s *Site) loadTranslations() (map[string]map[string]translation, error) {
+	result := make(map[string]map[string]translation)
+
+	files, err := ioutil.ReadDir(s.Config.I18nDir)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		filename := filepath.Join(s.Config.I18nDir, f.Name())
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		table := make(map[string]translation)
+		err = yaml.Unmarshal(data, &table)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		result[strings.TrimSuffix(f.Name(), ext)] = table
+	}
+	return result, nil
+}
+
+// i18n looks up a string in the translation table of a language. The
+// arguments can be a page, whose language is used (the default language
+// otherwise), and a number, which picks the plural form and replaces
+// {count}. Missing strings fall back to the default language. Used as the
+// i18n template function.
+func (s *Site) i18n(key string, args ...interface{}) (string, error) {
+	lang := s.Config.DefaultLanguage
+	count := -1
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case *ContentItem:
+			lang = v.Lang
+		case int, int64, float64:
+			count = toInt(v)
+		default:
+			return "", fmt.Errorf("i18n %s: unexpected argument %v", key, arg)
+		}
+	}
+
+	t, ok := s.translationTables[lang][key]
+	if !ok {
+		t, ok = s.translationTables[s.Config.DefaultLanguage][key]
+	}
+	if !ok {
+		return "", fmt.Errorf("Missing translation: %s (%s)", key, lang)
+	}
+
+	text := t.Text
+	if t.Plural != nil {
+		text = t.Plural[pluralForm(lang, count, t.Plural)]
+	}
+	if count >= 0 {
+		text = strings.Replace(text, "{count}", strconv.Itoa(count), -1)
+	}
+	return text, nil
+}
+
+// pluralForm picks the plural form for a count: zero (if given), one or
+// other. Some languages, like French, use the singular for zero.
+func pluralForm(lang string, count int, forms map[string]string) string {
+	if _, ok := forms["zero"]; ok && count == 0 {
+		return "zero"
+	}
+	one := count == 1
+	switch lang {
+	case "fr", "pt":
+		one = count == 0 || count == 1
+	}
+	if one {
+		return "one"
+	}
+	return "other"
+}
@@ -0,0 +1,67 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestI18n(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "en.yaml"), []byte(`
+readMore: Read more
+home: Home
+comments:
+  zero: No comments
+  one: "{count} comment"
+  other: "{count} comments"
+`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "fr.yaml"), []byte(`
+readMore: Lire la suite
+comments:
+  one: "{count} commentaire"
+  other: "{count} commentaires"
+`), 0644))
+
+	config := DefaultConfig()
+	config.I18nDir = dir
+	site := NewSite(config)
+	site.translationTables, err = site.loadTranslations()
+	ok(t, err)
+
+	fr := &ContentItem{Lang: "fr"}
+	tests := []struct {
+		key  string
+		args []interface{}
+		exp  string
+	}{
+		{"readMore", nil, "Read more"},
+		{"readMore", []interface{}{fr}, "Lire la suite"},
+		{"home", []interface{}{fr}, "Home"},
+		{"comments", []interface{}{0}, "No comments"},
+		{"comments", []interface{}{1}, "1 comment"},
+		{"comments", []interface{}{5}, "5 comments"},
+		{"comments", []interface{}{0, fr}, "0 commentaire"},
+		{"comments", []interface{}{fr, 2}, "2 commentaires"},
+	}
+	for _, test := range tests {
+		out, err := site.i18n(test.key, test.args...)
+		ok(t, err)
+		equals(t, out, test.exp)
+	}
+
+	_, err = site.i18n("missing")
+	assert(t, err != nil, "Expected error for missing translation")
+}
+
+func TestI18nMissingDir(t *testing.T) {
+	config := DefaultConfig()
+	config.I18nDir = "does-not-exist"
+	tables, err := NewSite(config).loadTranslations()
+	ok(t, err)
+	equals(t, len(tables), 0)
+}
@@ -0,0 +1,176 @@
+package sitegen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ImageFormatsConfig configures extra encodings of JPEG and PNG images,
+// written next to the originals (photo.jpg gets photo.jpg.webp).
+type ImageFormatsConfig struct {
+	// Formats to add: webp and/or avif.
+	Formats []string `yaml:"formats"`
+
+	// Encoder commands by format. {input} and {output} are replaced by the
+	// files to read and write. Defaults to cwebp and avifenc.
+	Commands map[string][]string `yaml:"commands"`
+}
+
+var defaultImageCommands = map[string][]string{
+	"webp": {"cwebp", "-quiet", "-q", "80", "{input}", "-o", "{output}"},
+	"avif": {"avifenc", "{input}", "{output}"},
+}
+
+// Formats in the order browsers should prefer them.
+var imageFormatOrder = []string{"avif", "webp"}
+
+func isTranscodableImage(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+func (s *Site) imageCommand(format string) []string {
+	if cmd, ok := s.Config.ImageFormats.Commands[format]; ok && len(cmd) > 0 {
+		return cmd
+	}
+	return defaultImageCommands[format]
+}
+
+// addImageFormats adds the configured encodings of every JPEG and PNG asset
+// to the tree.
+func (s *Site) addImageFormats(root *ContentItem) error {
+	formats := s.Config.ImageFormats.Formats
+	for _, f := range formats {
+		if s.imageCommand(f) == nil {
+			return categorize(ConfigError, fmt.Errorf("Unknown image format: %s", f))
+		}
+	}
+	if len(formats) == 0 {
+		return nil
+	}
+
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+				continue
+			}
+			if v.Type != Asset || !isTranscodableImage(v.Filename) {
+				continue
+			}
+
+			for _, f := range formats {
+				asset, format := v, f
+				item := c.addGenerated(asset.Filename+"."+format, Metadata{}, func() ([]byte, error) {
+					return s.encodeImageFormat(asset, format)
+				})
+				s.assets[asset.Path+"."+format] = item
+			}
+		}
+	}
+	walk(root)
+	return nil
+}
+
+// encodeImageFormat runs an image through the encoder for a format. Results
+// are cached on the command and the input.
+func (s *Site) encodeImageFormat(asset *ContentItem, format string) ([]byte, error) {
+	var data []byte
+	var err error
+	if s.transformsAsset(asset) {
+		data, err = s.assetData(asset)
+	} else {
+		data, err = ioutil.ReadFile(asset.FullPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	command := s.imageCommand(format)
+	h := sha256.New()
+	for _, arg := range command {
+		h.Write([]byte(arg))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	cacheFile := filepath.Join(s.Config.CacheDir, "images", hex.EncodeToString(h.Sum(nil))+"."+format)
+	if cached, err := ioutil.ReadFile(cacheFile); err == nil {
+		return cached, nil
+	}
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "input"+path.Ext(asset.Filename))
+	output := filepath.Join(dir, "output."+format)
+	err = ioutil.WriteFile(input, data, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	replacer := strings.NewReplacer("{input}", input, "{output}", output)
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = replacer.Replace(arg)
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s failed: %s\n%s", asset.FullPath, args[0], err, stderr.String())
+	}
+
+	out, err := ioutil.ReadFile(output)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(cacheFile), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(cacheFile, out, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// picture returns a <picture> element for an image asset, given its path in
+// the content directory, offering the extra encodings to browsers that
+// support them. Used as the picture template function.
+func (s *Site) picture(p, alt string) (template.HTML, error) {
+	p = strings.TrimPrefix(p, "/")
+	src, err := s.assetURL(p)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("<picture>")
+	for _, format := range imageFormatOrder {
+		if variant, ok := s.assets[p+"."+format]; ok {
+			fmt.Fprintf(buf, `<source srcset="%s" type="image/%s">`, html.EscapeString("/"+variant.OutputPath()), format)
+		}
+	}
+	fmt.Fprintf(buf, `<img src="%s" alt="%s">`, html.EscapeString(src), html.EscapeString(alt))
+	buf.WriteString("</picture>")
+	return template.HTML(buf.String()), nil
+}
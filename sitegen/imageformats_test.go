@@ -0,0 +1,50 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "img"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "img", "photo.jpg"), []byte("jpeg"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "img", "icon.svg"), []byte("<svg/>"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.ImageFormats = ImageFormatsConfig{
+		Formats:  []string{"webp"},
+		Commands: map[string][]string{"webp": {"cp", "{input}", "{output}"}},
+	}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.fingerprintAssets(root))
+	ok(t, site.addImageFormats(root))
+	root.Process()
+
+	img := root.child("img")
+	assert(t, img.child("photo.jpg.webp") != nil, "Expected webp version")
+	assert(t, img.child("icon.svg.webp") == nil, "Unexpected webp version of svg")
+
+	data, err := img.child("photo.jpg.webp").generate()
+	ok(t, err)
+	equals(t, string(data), "jpeg")
+
+	html, err := site.picture("img/photo.jpg", "A photo")
+	ok(t, err)
+	equals(t, string(html), `<picture><source srcset="/img/photo.jpg.webp" type="image/webp"><img src="/img/photo.jpg" alt="A photo"></picture>`)
+
+	site.Config.ImageFormats.Formats = []string{"jxl"}
+	err = site.addImageFormats(root)
+	equals(t, Category(err), ConfigError)
+}
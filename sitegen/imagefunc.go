@@ -0,0 +1,176 @@
+package sitegen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+var imageSpecRegex = regexp.MustCompile(`^(\d*)x(\d*)(?:\s+(crop))?$`)
+
+// imageSpec describes a resized variant of an image: 800x (width), x600
+// (height), 800x600 (fit inside) or 800x600 crop (fill and crop the center).
+type imageSpec struct {
+	Width  int
+	Height int
+	Crop   bool
+}
+
+func parseImageSpec(spec string) (imageSpec, error) {
+	m := imageSpecRegex.FindStringSubmatch(strings.TrimSpace(spec))
+	if m == nil || (m[1] == "" && m[2] == "") {
+		return imageSpec{}, fmt.Errorf("Invalid image size: %s", spec)
+	}
+	result := imageSpec{Crop: m[3] != ""}
+	result.Width, _ = strconv.Atoi(m[1])
+	result.Height, _ = strconv.Atoi(m[2])
+	if result.Crop && (result.Width == 0 || result.Height == 0) {
+		return imageSpec{}, fmt.Errorf("Cropping needs a width and a height: %s", spec)
+	}
+	return result, nil
+}
+
+// suffix is added to the filename of the variant.
+func (spec imageSpec) suffix() string {
+	s := "_"
+	if spec.Width > 0 {
+		s += strconv.Itoa(spec.Width)
+	}
+	s += "x"
+	if spec.Height > 0 {
+		s += strconv.Itoa(spec.Height)
+	}
+	if spec.Crop {
+		s += "_crop"
+	}
+	return s
+}
+
+// apply resizes an image. Images are never enlarged.
+func (spec imageSpec) apply(data []byte) ([]byte, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if spec.Crop {
+		// Scale to cover the requested size, then cut out the center.
+		tw, th := minInt(spec.Width, w), minInt(spec.Height, h)
+		sw, sh := tw, h*tw/w
+		if sh < th {
+			sw, sh = w*th/h, th
+		}
+		scaled := scaleImage(img, sw, sh)
+		x, y := (sw-tw)/2, (sh-th)/2
+		cropped := image.NewRGBA(image.Rect(0, 0, tw, th))
+		draw.Draw(cropped, cropped.Bounds(), scaled, image.Pt(x, y), draw.Src)
+		return encodeImage(cropped, format)
+	}
+
+	scale := 1.0
+	if spec.Width > 0 && spec.Width < w {
+		scale = float64(spec.Width) / float64(w)
+	}
+	if spec.Height > 0 && spec.Height < h && float64(spec.Height)/float64(h) < scale {
+		scale = float64(spec.Height) / float64(h)
+	}
+	return encodeImage(scaleImage(img, int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)), format)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+type imageVariant struct {
+	once sync.Once
+	url  string
+	err  error
+}
+
+// image returns the URL of a resized variant of an image asset, given its
+// path in the content directory. The variant is written next to the
+// original. Used as the image template function.
+func (s *Site) image(p, size string) (string, error) {
+	spec, err := parseImageSpec(size)
+	if err != nil {
+		return "", err
+	}
+
+	p = strings.TrimPrefix(p, "/")
+	asset, ok := s.assets[p]
+	if !ok || asset.Type != Asset {
+		return "", fmt.Errorf("Unknown image: %s", p)
+	}
+
+	key := p + "\x00" + size
+	s.imagesLock.Lock()
+	if s.images == nil {
+		s.images = make(map[string]*imageVariant)
+	}
+	variant, ok := s.images[key]
+	if !ok {
+		variant = &imageVariant{}
+		s.images[key] = variant
+	}
+	s.imagesLock.Unlock()
+
+	variant.once.Do(func() {
+		variant.url, variant.err = s.writeImage(asset, spec)
+	})
+	return variant.url, variant.err
+}
+
+func (s *Site) writeImage(asset *ContentItem, spec imageSpec) (string, error) {
+	data, err := ioutil.ReadFile(asset.FullPath)
+	if err != nil {
+		return "", err
+	}
+
+	ext := path.Ext(asset.Filename)
+	out := strings.TrimSuffix(asset.OutputPath(), ext) + spec.suffix() + ext
+
+	// Resizing is slow, results are cached on the source and the size.
+	sum := sha256.Sum256(append([]byte(spec.suffix()+"\x00"), data...))
+	cacheFile := filepath.Join(s.Config.CacheDir, "images", hex.EncodeToString(sum[:])+ext)
+	resized, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		resized, err = spec.apply(data)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", asset.FullPath, err)
+		}
+		err = os.MkdirAll(filepath.Dir(cacheFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(cacheFile, resized, 0644)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	target := filepath.Join(s.Config.OutputDir, filepath.FromSlash(out))
+	err = s.output().MkdirAll(filepath.Dir(target))
+	if err == nil {
+		err = s.output().WriteFile(target, resized)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "/" + out, nil
+}
@@ -0,0 +1,71 @@
+package sitegen
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageSpec(t *testing.T) {
+	tests := map[string][]int{
+		"800x":         {400, 200},
+		"x100":         {200, 100},
+		"100x100":      {100, 50},
+		"100x100 crop": {100, 100},
+		"1000x1000":    {400, 200},
+	}
+	for in, exp := range tests {
+		spec, err := parseImageSpec(in)
+		ok(t, err)
+		out, err := spec.apply(testImage(t, "png", 400, 200))
+		ok(t, err)
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+		ok(t, err)
+		equals(t, []int{cfg.Width, cfg.Height}, exp)
+	}
+
+	for _, in := range []string{"", "x", "big", "100x crop"} {
+		_, err := parseImageSpec(in)
+		assert(t, err != nil, "Expected error for %q", in)
+	}
+}
+
+func TestImageFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "img"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "img", "photo.jpg"), testImage(t, "jpeg", 400, 200), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.OutputDir = filepath.Join(dir, "output")
+	config.CacheDir = filepath.Join(dir, "cache")
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.fingerprintAssets(root))
+
+	url, err := site.image("img/photo.jpg", "100x")
+	ok(t, err)
+	equals(t, url, "/img/photo_100x.jpg")
+
+	data, err := ioutil.ReadFile(filepath.Join(config.OutputDir, "img", "photo_100x.jpg"))
+	ok(t, err)
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	ok(t, err)
+	equals(t, []int{cfg.Width, cfg.Height}, []int{100, 50})
+
+	cached, err := ioutil.ReadDir(filepath.Join(config.CacheDir, "images"))
+	ok(t, err)
+	equals(t, len(cached), 1)
+
+	_, err = site.image("img/missing.jpg", "100x")
+	assert(t, err != nil, "Expected error for unknown image")
+}
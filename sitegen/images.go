@@ -0,0 +1,198 @@
+package sitegen
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// resizeImage scales an image down so neither side exceeds max pixels.
+// Smaller images are returned unchanged.
+func resizeImage(data []byte, max int) ([]byte, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	if b.Dx() <= max && b.Dy() <= max {
+		return data, nil
+	}
+
+	w, h := max, b.Dy()*max/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = b.Dx()*max/b.Dy(), max
+	}
+	return encodeImage(scaleImage(img, w, h), format)
+}
+
+func scaleImage(img image.Image, w, h int) image.Image {
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return dst
+}
+
+func encodeImage(img image.Image, format string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 90})
+	case "png":
+		err = png.Encode(buf, img)
+	case "gif":
+		err = gif.Encode(buf, img, nil)
+	default:
+		err = fmt.Errorf("Cannot encode %s images", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stripMetadata removes EXIF, XMP, IPTC and comments from JPEG and PNG
+// images, without re-encoding them. Color profiles are kept, as are the
+// EXIF tags listed in keep (e.g. Orientation).
+func stripMetadata(data []byte, keep []string) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xff\xd8")):
+		return stripJPEG(data, keep)
+	case bytes.HasPrefix(data, pngHeader):
+		return stripPNG(data, keep)
+	}
+	return data, nil
+}
+
+// JPEG segments that are kept: JFIF (APP0), ICC profiles (APP2) and Adobe
+// color information (APP14). Other application segments and comments go.
+func keepJPEGSegment(marker byte) bool {
+	if marker == 0xfe {
+		return false
+	}
+	if marker >= 0xe0 && marker <= 0xef {
+		return marker == 0xe0 || marker == 0xe2 || marker == 0xee
+	}
+	return true
+}
+
+func stripJPEG(data []byte, keep []string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	out.Write(data[:2])
+
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xff {
+			return nil, errors.New("Invalid JPEG: expected marker")
+		}
+		if pos+2 > len(data) {
+			return nil, errors.New("Invalid JPEG: truncated marker")
+		}
+		marker := data[pos+1]
+		if marker == 0xff {
+			// Fill byte
+			pos++
+			continue
+		}
+		if marker == 0xd9 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7) {
+			out.Write(data[pos : pos+2])
+			pos += 2
+			continue
+		}
+		if pos+4 > len(data) {
+			return nil, errors.New("Invalid JPEG: truncated segment")
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 {
+			return nil, errors.New("Invalid JPEG: bad segment length")
+		}
+		end := pos + 2 + length
+		if end > len(data) {
+			return nil, errors.New("Invalid JPEG: truncated segment")
+		}
+		if marker == 0xda {
+			// Start of scan: the rest is image data.
+			out.Write(data[pos:])
+			break
+		}
+		if keepJPEGSegment(marker) {
+			out.Write(data[pos:end])
+		} else if marker == 0xe1 && bytes.HasPrefix(data[pos+4:end], exifHeader) {
+			exif := filterExif(data[pos+4+len(exifHeader):end], keep)
+			if exif != nil && len(exifHeader)+len(exif)+2 <= 0xffff {
+				segment := make([]byte, 4)
+				segment[0], segment[1] = 0xff, 0xe1
+				binary.BigEndian.PutUint16(segment[2:], uint16(len(exifHeader)+len(exif)+2))
+				out.Write(segment)
+				out.Write(exifHeader)
+				out.Write(exif)
+			}
+		}
+		pos = end
+	}
+	return out.Bytes(), nil
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+// PNG chunks holding metadata.
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+func stripPNG(data []byte, keep []string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	out.Write(pngHeader)
+
+	pos := len(pngHeader)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errors.New("Invalid PNG: truncated chunk")
+		}
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:]))
+		if end > len(data) {
+			return nil, errors.New("Invalid PNG: truncated chunk")
+		}
+		chunk := string(data[pos+4 : pos+8])
+		if !pngMetadataChunks[chunk] {
+			out.Write(data[pos:end])
+		} else if chunk == "eXIf" {
+			if exif := filterExif(data[pos+8:end-4], keep); exif != nil {
+				writePNGChunk(out, chunk, exif)
+			}
+		}
+		pos = end
+	}
+	return out.Bytes(), nil
+}
+
+func writePNGChunk(out *bytes.Buffer, name string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], name)
+	out.Write(header)
+	out.Write(data)
+
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(append([]byte(name), data...)))
+	out.Write(crc)
+}
@@ -0,0 +1,123 @@
+package sitegen
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(t *testing.T, format string, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	buf := &bytes.Buffer{}
+	if format == "png" {
+		ok(t, png.Encode(buf, img))
+	} else {
+		ok(t, jpeg.Encode(buf, img, nil))
+	}
+	return buf.Bytes()
+}
+
+func TestResizeImage(t *testing.T) {
+	out, err := resizeImage(testImage(t, "png", 400, 200), 100)
+	ok(t, err)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+	ok(t, err)
+	equals(t, format, "png")
+	equals(t, []int{cfg.Width, cfg.Height}, []int{100, 50})
+
+	out, err = resizeImage(testImage(t, "jpeg", 200, 400), 100)
+	ok(t, err)
+	cfg, format, err = image.DecodeConfig(bytes.NewReader(out))
+	ok(t, err)
+	equals(t, format, "jpeg")
+	equals(t, []int{cfg.Width, cfg.Height}, []int{50, 100})
+
+	in := testImage(t, "png", 50, 50)
+	out, err = resizeImage(in, 100)
+	ok(t, err)
+	equals(t, out, in)
+}
+
+func TestStripJPEG(t *testing.T) {
+	in := testImage(t, "jpeg", 8, 8)
+	exif := []byte("\xff\xe1\x00\x0aExif\x00\x00GP")
+	comment := []byte("\xff\xfe\x00\x05hi!")
+	withMeta := append(append(append([]byte{}, in[:2]...), append(exif, comment...)...), in[2:]...)
+
+	out, err := stripMetadata(withMeta, nil)
+	ok(t, err)
+	equals(t, out, in)
+
+	_, err = jpeg.Decode(bytes.NewReader(out))
+	ok(t, err)
+}
+
+func TestStripBrokenJPEG(t *testing.T) {
+	broken := []string{
+		"\xff\xd8\xff",                 // truncated marker
+		"\xff\xd8\xff\xe1\x00",         // truncated length
+		"\xff\xd8\xff\xe1\x00\x01ab",   // length below 2
+		"\xff\xd8\xff\xe1\x00\x10Exif", // truncated segment
+		"\xff\xd8\x00",                 // no marker
+	}
+	for _, in := range broken {
+		_, err := stripMetadata([]byte(in), []string{"Orientation"})
+		assert(t, err != nil, "Expected an error for %q", in)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	in := testImage(t, "png", 8, 8)
+	text := []byte("\x00\x00\x00\x07tEXtGPS=1,2\x00\x00\x00\x00")
+	withMeta := append(append(append([]byte{}, in[:33]...), text...), in[33:]...)
+
+	out, err := stripMetadata(withMeta, nil)
+	ok(t, err)
+	equals(t, out, in)
+}
+
+// testExif builds a little-endian TIFF structure with Make (stored outside
+// the entry), Orientation and a GPS directory pointer.
+func testExif() []byte {
+	return []byte("II\x2a\x00\x08\x00\x00\x00" +
+		"\x03\x00" +
+		"\x0f\x01\x02\x00\x06\x00\x00\x00\x32\x00\x00\x00" + // Make, at 50
+		"\x12\x01\x03\x00\x01\x00\x00\x00\x06\x00\x00\x00" + // Orientation
+		"\x25\x88\x04\x00\x01\x00\x00\x00\x00\x00\x00\x00" + // GPS
+		"\x00\x00\x00\x00" +
+		"Canon\x00")
+}
+
+func TestFilterExif(t *testing.T) {
+	equals(t, filterExif(testExif(), nil) == nil, true)
+	equals(t, filterExif(testExif(), []string{"Copyright"}) == nil, true)
+
+	out := filterExif(testExif(), []string{"Orientation"})
+	equals(t, out, []byte("II\x2a\x00\x08\x00\x00\x00"+
+		"\x01\x00"+
+		"\x12\x01\x03\x00\x01\x00\x00\x00\x06\x00\x00\x00"+
+		"\x00\x00\x00\x00"))
+
+	out = filterExif(testExif(), []string{"Make", "Orientation"})
+	equals(t, len(out), 8+2+2*12+4+6)
+	equals(t, out[18:22], []byte("\x26\x00\x00\x00")) // Make moved to 38
+	equals(t, string(out[38:]), "Canon\x00")
+}
+
+func TestStripJPEGKeepsOrientation(t *testing.T) {
+	in := testImage(t, "jpeg", 8, 8)
+	exif := append([]byte("Exif\x00\x00"), testExif()...)
+	segment := append([]byte{0xff, 0xe1, 0x00, byte(len(exif) + 2)}, exif...)
+	withMeta := append(append(append([]byte{}, in[:2]...), segment...), in[2:]...)
+
+	out, err := stripMetadata(withMeta, []string{"Orientation"})
+	ok(t, err)
+	kept := append([]byte("Exif\x00\x00"), filterExif(testExif(), []string{"Orientation"})...)
+	expected := append(append(append([]byte{}, in[:2]...), append([]byte{0xff, 0xe1, 0x00, byte(len(kept) + 2)}, kept...)...), in[2:]...)
+	equals(t, out, expected)
+
+	_, err = jpeg.Decode(bytes.NewReader(out))
+	ok(t, err)
+}
@@ -0,0 +1,127 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var includeShortcodeRegex = regexp.MustCompile(`\{\{<\s*include\s+"([^"]+)"((?:\s+\w+="[^"]*")*)\s*>\}\}`)
+
+// includeFiles replaces the include shortcodes in Markdown
+// ({{< include "cmd/main.go" lines="10-20" >}}) with a code block holding
+// the file, or the given lines of it. Paths are relative to the content
+// folder. The language is taken from the extension unless set with lang="",
+// other options (hl_lines="2") are passed on to the code block.
+func (s *Site) includeFiles(sourcePath string, body []byte) ([]byte, error) {
+	var includeErr error
+	out := includeShortcodeRegex.ReplaceAllFunc(body, func(m []byte) []byte {
+		parts := includeShortcodeRegex.FindSubmatch(m)
+		file := string(parts[1])
+		attrs := parseAttributes(string(parts[2]))
+
+		code, err := s.readInclude(file, attrs["lines"])
+		if err != nil {
+			includeErr = fmt.Errorf("%s: cannot include %s: %w", sourcePath, file, err)
+			return m
+		}
+
+		lang, ok := attrs["lang"]
+		if !ok {
+			lang = strings.TrimPrefix(path.Ext(file), ".")
+		}
+		options := make([]string, 0)
+		for key, value := range attrs {
+			if key != "lang" && key != "lines" {
+				options = append(options, fmt.Sprintf("%s=%q", key, value))
+			}
+		}
+		sort.Strings(options)
+		if len(options) > 0 {
+			lang += " {" + strings.Join(options, ", ") + "}"
+		}
+
+		fence := "```"
+		for bytes.Contains(code, []byte(fence)) {
+			fence += "`"
+		}
+
+		result := &bytes.Buffer{}
+		fmt.Fprintf(result, "%s%s\n", fence, lang)
+		result.Write(bytes.TrimRight(code, "\n"))
+		fmt.Fprintf(result, "\n%s", fence)
+		return result.Bytes()
+	})
+	if includeErr != nil {
+		return nil, includeErr
+	}
+	return out, nil
+}
+
+// readInclude reads a file, or some of its lines (10-20, or 10 for a single
+// line).
+func (s *Site) readInclude(file, lines string) ([]byte, error) {
+	filename, err := s.includePath(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if lines == "" {
+		return data, nil
+	}
+
+	wanted, err := parseLineRanges(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &bytes.Buffer{}
+	all := bytes.SplitAfter(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	for i, line := range all {
+		if wanted[i+1] {
+			result.Write(line)
+		}
+	}
+	if result.Len() == 0 {
+		return nil, fmt.Errorf("Lines %s not found, the file has %d lines", lines, len(all))
+	}
+	return result.Bytes(), nil
+}
+
+// includePath finds an included file in the content folders. Files outside
+// of them can't be included, also not through a symlink.
+func (s *Site) includePath(file string) (string, error) {
+	clean := path.Clean(file)
+	if path.IsAbs(clean) || filepath.IsAbs(filepath.FromSlash(file)) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("Path outside of the content folder")
+	}
+
+	for _, dir := range s.Config.ContentDirs {
+		filename := filepath.Join(dir, filepath.FromSlash(clean))
+		resolved, err := filepath.EvalSymlinks(filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		root, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return "", err
+		}
+		if !isWithin(resolved, root) {
+			return "", fmt.Errorf("Path outside of the content folder")
+		}
+		return resolved, nil
+	}
+	return "", fmt.Errorf("File not found in the content folder")
+}
@@ -0,0 +1,57 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIncludeFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "cmd"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "cmd", "main.go"), []byte("package main\n\nfunc main() {\n\tprintln(\"```\")\n}\n"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	site := NewSite(config)
+
+	out, err := site.includeFiles("index.md", []byte(`Code: {{< include "cmd/main.go" >}}`))
+	ok(t, err)
+	equals(t, string(out), "Code: ````go\npackage main\n\nfunc main() {\n\tprintln(\"```\")\n}\n````")
+
+	out, err = site.includeFiles("index.md", []byte(`{{< include "cmd/main.go" lines="3-4" lang="" hl_lines="2" >}}`))
+	ok(t, err)
+	equals(t, string(out), "```` {hl_lines=\"2\"}\nfunc main() {\n\tprintln(\"```\")\n````")
+
+	_, err = site.includeFiles("index.md", []byte(`{{< include "cmd/main.go" lines="8-9" >}}`))
+	equals(t, err.Error(), "index.md: cannot include cmd/main.go: Lines 8-9 not found, the file has 5 lines")
+
+	_, err = site.includeFiles("index.md", []byte(`{{< include "missing.go" >}}`))
+	assert(t, err != nil, "Expected an error for a missing file")
+}
+
+func TestIncludeOutsideContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(content, 0755))
+	secret := filepath.Join(dir, "secret.txt")
+	ok(t, ioutil.WriteFile(secret, []byte("secret"), 0644))
+	ok(t, os.Symlink(secret, filepath.Join(content, "link.txt")))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	site := NewSite(config)
+
+	for _, file := range []string{"../secret.txt", "a/../../secret.txt", filepath.ToSlash(secret), "link.txt"} {
+		_, err := site.includeFiles("index.md", []byte(`{{< include "`+file+`" >}}`))
+		assert(t, err != nil, "Expected an error for %s", file)
+	}
+}
@@ -0,0 +1,133 @@
+package sitegen
+
+import (
+	"bytes"
+	"html/template"
+	"path"
+	"strings"
+)
+
+// jsonLD returns schema.org structured data for a page, as JSON-LD script
+// elements: the page itself and, below the root, its breadcrumbs. The type
+// is set with schemaType in the front matter (none leaves it out), pages
+// with a date default to BlogPosting, others to WebPage. Used as the jsonLD
+// template function.
+func (s *Site) jsonLD(c *ContentItem) (template.HTML, error) {
+	blocks := make([]map[string]interface{}, 0, 2)
+	if page := s.schemaPage(c); page != nil {
+		blocks = append(blocks, page)
+	}
+	if crumbs := s.breadcrumbs(c); len(crumbs) > 1 {
+		items := make([]map[string]interface{}, len(crumbs))
+		for i, crumb := range crumbs {
+			items[i] = map[string]interface{}{
+				"@type":    "ListItem",
+				"position": i + 1,
+				"name":     crumb.Metadata.Title,
+				"item":     s.absoluteURL(crumb.Url),
+			}
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"@context":        "https://schema.org",
+			"@type":           "BreadcrumbList",
+			"itemListElement": items,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	for _, block := range blocks {
+		data, err := jsonify(block)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(`<script type="application/ld+json">`)
+		buf.WriteString(string(data))
+		buf.WriteString(`</script>`)
+	}
+	return template.HTML(buf.String()), nil
+}
+
+func (s *Site) schemaPage(c *ContentItem) map[string]interface{} {
+	kind := c.Metadata.String("schemaType")
+	if kind == "" {
+		kind = "WebPage"
+		if !c.Metadata.Date.IsZero() {
+			kind = "BlogPosting"
+		}
+	}
+	if kind == "none" {
+		return nil
+	}
+
+	page := map[string]interface{}{
+		"@context": "https://schema.org",
+		"@type":    kind,
+		"url":      s.absoluteURL(c.Url),
+	}
+	if description := c.Description(); description != "" {
+		page["description"] = description
+	}
+	if image := c.OGImage(); image != "" {
+		page["image"] = s.absoluteURL(image)
+	}
+
+	if kind == "WebPage" {
+		page["name"] = c.Metadata.Title
+		return page
+	}
+
+	page["headline"] = c.Metadata.Title
+	if !c.Metadata.Date.IsZero() {
+		page["datePublished"] = c.Metadata.Date.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if author := c.Metadata.String("author"); author != "" {
+		page["author"] = map[string]interface{}{"@type": "Person", "name": author}
+	}
+	if s.Config.Title != "" {
+		publisher := map[string]interface{}{"@type": "Organization", "name": s.Config.Title}
+		if s.Config.BaseURL != "" {
+			publisher["url"] = s.Config.BaseURL
+		}
+		page["publisher"] = publisher
+	}
+	return page
+}
+
+// breadcrumbs returns the pages leading up to c: the index pages of the
+// directories it's in, followed by c itself. Directories without an index
+// page are skipped.
+func (s *Site) breadcrumbs(c *ContentItem) []*ContentItem {
+	if s.root == nil {
+		return []*ContentItem{c}
+	}
+
+	crumbs := make([]*ContentItem, 0)
+	dir := s.root
+	parts := strings.Split(path.Dir(c.Path), "/")
+	if parts[0] == "." {
+		parts = nil
+	}
+	for i := 0; ; i++ {
+		if index := dir.indexPage(); index != nil && index != c {
+			crumbs = append(crumbs, index)
+		}
+		if i == len(parts) {
+			break
+		}
+		dir = dir.child(parts[i])
+		if dir == nil || dir.Type != Directory {
+			break
+		}
+	}
+	return append(crumbs, c)
+}
+
+// indexPage returns the page that is shown for a directory, if any.
+func (c *ContentItem) indexPage() *ContentItem {
+	for _, v := range c.Children {
+		if v.Type == Content && path.Base(v.OutputPath()) == "index.html" {
+			return v
+		}
+	}
+	return nil
+}
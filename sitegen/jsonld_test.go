@@ -0,0 +1,49 @@
+package sitegen
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJSONLD(t *testing.T) {
+	config := DefaultConfig()
+	config.Title = "Example"
+	config.BaseURL = "https://example.com"
+	site := NewSite(config)
+
+	page := func(p, title string) *ContentItem {
+		return &ContentItem{Site: site, Filename: "index.html", Path: p, Type: Content, Metadata: Metadata{Title: title}}
+	}
+	root := &ContentItem{Site: site, Type: Directory}
+	home := page("index.html", "Home")
+	root.Children = append(root.Children, home)
+	blog := root.ensureDir("blog")
+	blogIndex := page("blog/index.html", "Blog")
+	post := &ContentItem{Site: site, Filename: "post.html", Path: "blog/post.md", Type: Content, Metadata: Metadata{
+		Title:  "Post",
+		Date:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Params: map[string]interface{}{"author": "Jo"},
+	}}
+	blog.Children = append(blog.Children, blogIndex, post)
+	root.Process()
+	site.root = root
+
+	equals(t, site.breadcrumbs(post), []*ContentItem{home, blogIndex, post})
+	equals(t, site.breadcrumbs(blogIndex), []*ContentItem{home, blogIndex})
+	equals(t, site.breadcrumbs(home), []*ContentItem{home})
+
+	html, err := site.jsonLD(post)
+	ok(t, err)
+	equals(t, string(html), `<script type="application/ld+json">{"@context":"https://schema.org","@type":"BlogPosting",`+
+		`"author":{"@type":"Person","name":"Jo"},"datePublished":"2024-05-01T00:00:00Z","headline":"Post",`+
+		`"publisher":{"@type":"Organization","name":"Example","url":"https://example.com"},"url":"https://example.com/blog/post.html"}</script>`+
+		`<script type="application/ld+json">{"@context":"https://schema.org","@type":"BreadcrumbList","itemListElement":[`+
+		`{"@type":"ListItem","item":"https://example.com/","name":"Home","position":1},`+
+		`{"@type":"ListItem","item":"https://example.com/blog/","name":"Blog","position":2},`+
+		`{"@type":"ListItem","item":"https://example.com/blog/post.html","name":"Post","position":3}]}</script>`)
+
+	home.Metadata.Params = map[string]interface{}{"schemaType": "none"}
+	html, err = site.jsonLD(home)
+	ok(t, err)
+	equals(t, string(html), "")
+}
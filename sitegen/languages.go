@@ -0,0 +1,117 @@
+package sitegen
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// LanguageConfig holds the settings of one language of a multilingual site.
+type LanguageConfig struct {
+	// Name of the language, as shown to visitors (e.g. Nederlands).
+	Name string `yaml:"name"`
+
+	// Address the pages in this language are published at, instead of the
+	// site-wide baseURL.
+	BaseURL string `yaml:"baseURL"`
+}
+
+func (c *Config) Language(code string) *LanguageConfig {
+	if lang, ok := c.Languages[code]; ok && lang != nil {
+		return lang
+	}
+	return &LanguageConfig{}
+}
+
+// languageOf returns the language of the page at the given content path:
+// that of its top-level directory (nl/about.md) or filename (about.nl.md),
+// the default language otherwise.
+func (s *Site) languageOf(p string) string {
+	if top := sectionName(p); s.Config.Languages[top] != nil {
+		return top
+	}
+	key := strings.TrimSuffix(p, path.Ext(p))
+	if ext := path.Ext(key); ext != "" && s.Config.Languages[ext[1:]] != nil {
+		return ext[1:]
+	}
+	return s.Config.DefaultLanguage
+}
+
+// assignLanguages sets the language of every page. Pages in a top-level
+// directory named after a language (content/nl/) or with the language in
+// their filename (about.nl.md) are in that language, others in the default
+// one. Pages with the language in their filename are moved to the directory
+// of the language, unless it's the default language.
+func (s *Site) assignLanguages(root *ContentItem) {
+	s.translations = make(map[string][]*ContentItem)
+
+	moved := make([]*ContentItem, 0)
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		children := c.Children[:0]
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+			}
+			if v.Type != Content {
+				children = append(children, v)
+				continue
+			}
+
+			key := strings.TrimSuffix(v.Path, path.Ext(v.Path))
+			v.Lang = s.languageOf(v.Path)
+			if s.Config.Languages[v.Lang] == nil {
+				// Default language, not configured.
+			} else if sectionName(v.Path) == v.Lang {
+				key = strings.TrimPrefix(key, v.Lang+"/")
+			} else if ext := path.Ext(key); ext == "."+v.Lang {
+				key = strings.TrimSuffix(key, ext)
+				v.Filename = strings.TrimSuffix(v.Filename, ext+".html") + ".html"
+			}
+
+			v.translationKey = key
+			s.translations[key] = append(s.translations[key], v)
+			if v.Lang != s.Config.DefaultLanguage && sectionName(v.Path) != v.Lang {
+				v.Path = path.Join(v.Lang, v.Path)
+				moved = append(moved, v)
+				continue
+			}
+			children = append(children, v)
+		}
+		c.Children = children
+	}
+	walk(root)
+
+	for _, v := range moved {
+		dir := root
+		for _, part := range strings.Split(path.Dir(v.Path), "/") {
+			dir = dir.ensureDir(part)
+		}
+		dir.Children = append(dir.Children, v)
+	}
+}
+
+// Translations returns the other language versions of a page, sorted by
+// language.
+func (c *ContentItem) Translations() []*ContentItem {
+	result := make([]*ContentItem, 0)
+	if c.translationKey == "" {
+		return result
+	}
+	for _, v := range c.Site.translations[c.translationKey] {
+		if v != c {
+			result = append(result, v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Lang < result[j].Lang })
+	return result
+}
+
+// LanguageName returns the name of the language of a page, or its code
+// when no name is configured.
+func (c *ContentItem) LanguageName() string {
+	if name := c.Site.Config.Language(c.Lang).Name; name != "" {
+		return name
+	}
+	return c.Lang
+}
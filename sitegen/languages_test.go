@@ -0,0 +1,50 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLanguages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "fr"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "about.md"), []byte("# About"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "about.nl.md"), []byte("# Over"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "fr", "about.md"), []byte("# À propos"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "contact.md"), []byte("# Contact"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.BaseURL = "https://example.com/"
+	config.Languages = map[string]*LanguageConfig{
+		"en": {},
+		"nl": {Name: "Nederlands", BaseURL: "https://example.nl/"},
+		"fr": {},
+	}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.assignLanguages(root)
+	root.Process()
+
+	en := root.child("about.html")
+	nl := root.child("nl").child("about.html")
+	fr := root.child("fr").child("about.html")
+	assert(t, root.child("about.nl.html") == nil, "Translation should be moved")
+	equals(t, []string{en.Lang, nl.Lang, fr.Lang}, []string{"en", "nl", "fr"})
+	equals(t, nl.Url, "/nl/about.html")
+	equals(t, nl.Permalink, "https://example.nl/nl/about.html")
+	equals(t, en.Permalink, "https://example.com/about.html")
+	equals(t, nl.LanguageName(), "Nederlands")
+	equals(t, fr.LanguageName(), "fr")
+
+	equals(t, en.Translations(), []*ContentItem{fr, nl})
+	equals(t, nl.Translations(), []*ContentItem{en, fr})
+	equals(t, len(root.child("contact.html").Translations()), 0)
+}
@@ -0,0 +1,146 @@
+package sitegen
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var linkRegex = regexp.MustCompile(`(?i)\s(?:href|src)\s*=\s*["']([^"']*)["']`)
+
+// checkLinks verifies that the internal links (href and src attributes) in
+// the generated pages point to files that exist. Depending on the checkLinks
+// setting, broken links are logged (warn) or fail the build (error).
+func (s *Site) checkLinks(root *ContentItem) error {
+	mode := s.Config.CheckLinks
+	if mode == "" {
+		return nil
+	}
+
+	log.Println("==> Checking links")
+	urls := root.urlSet()
+	broken := make([]string, 0)
+	for _, page := range root.htmlOutputs() {
+		data, err := s.output().ReadFile(filepath.Join(s.Config.OutputDir, filepath.FromSlash(page.OutputPath())))
+		if err != nil {
+			return err
+		}
+		links := parseLinks(data)
+
+		for _, link := range links {
+			target, ok := s.localTarget(page.OutputPath(), link)
+			if ok && !s.linkExists(urls, target) {
+				broken = append(broken, fmt.Sprintf("/%s: %s", page.OutputPath(), link))
+			}
+		}
+	}
+	if len(broken) == 0 {
+		return nil
+	}
+
+	sort.Strings(broken)
+	if mode == "warn" {
+		for _, v := range broken {
+			log.Printf("Broken link on %s\n", v)
+		}
+		return nil
+	}
+	return categorize(LinkError, fmt.Errorf("Broken links:\n%s", strings.Join(broken, "\n")))
+}
+
+// htmlOutputs returns the items below c that are written as HTML.
+func (c *ContentItem) htmlOutputs() []*ContentItem {
+	result := make([]*ContentItem, 0)
+	for _, v := range c.Children {
+		switch {
+		case v.Type == Directory:
+			result = append(result, v.htmlOutputs()...)
+		case v.Type == Content && v.hasOutputFormat("html"):
+			result = append(result, v)
+		case v.Type == Generated && path.Ext(v.Filename) == ".html":
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// readLinks returns the targets of the links (href and src attributes) in an
+// HTML file.
+func readLinks(filename string) ([]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseLinks(data), nil
+}
+
+// parseLinks returns the targets of the links in HTML.
+func parseLinks(data []byte) []string {
+	links := make([]string, 0)
+	for _, m := range linkRegex.FindAllStringSubmatch(string(data), -1) {
+		links = append(links, html.UnescapeString(m[1]))
+	}
+	return links
+}
+
+// localTarget resolves a link on a page (given by its output path) to a path
+// on the site, without the path of the baseURL. It returns false for links
+// to other sites.
+func (s *Site) localTarget(page string, link string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Opaque != "" {
+		return "", false
+	}
+	if u.Scheme != "" || u.Host != "" {
+		base, err := url.Parse(s.Config.BaseURL)
+		if err != nil || s.Config.BaseURL == "" || u.Host != base.Host {
+			return "", false
+		}
+	}
+	if u.Path == "" {
+		// Only a fragment or query on the same page.
+		return "", false
+	}
+
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(path.Dir("/"+page), p)
+		if strings.HasSuffix(u.Path, "/") && p != "/" {
+			p += "/"
+		}
+		return p, true
+	}
+
+	if base := strings.TrimSuffix(s.basePath(), "/"); base != "" {
+		if p != base && !strings.HasPrefix(p, base+"/") {
+			return p, true
+		}
+		p = strings.TrimPrefix(p, base)
+		if p == "" {
+			p = "/"
+		}
+	}
+	return p, true
+}
+
+// linkExists checks a path against the generated URLs, falling back to
+// files in the output folder that sitegen didn't write (see keep).
+func (s *Site) linkExists(urls map[string]bool, p string) bool {
+	if urls[p] || urls[strings.TrimSuffix(p, "/")+"/"] {
+		return true
+	}
+
+	file := filepath.Join(s.Config.OutputDir, filepath.FromSlash(p))
+	info, err := s.output().Stat(file)
+	if err == nil && info.IsDir() {
+		_, err = s.output().Stat(filepath.Join(file, "index.html"))
+	}
+	return err == nil
+}
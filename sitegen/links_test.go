@@ -0,0 +1,81 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	output := filepath.Join(dir, "output")
+	ok(t, os.MkdirAll(filepath.Join(content, "blog"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(output, "blog"), 0755))
+	for _, name := range []string{"index.md", "blog/index.md", "blog/post.md", "style.css"} {
+		ok(t, ioutil.WriteFile(filepath.Join(content, filepath.FromSlash(name)), []byte(""), 0644))
+	}
+
+	pages := map[string]string{
+		"index.html": `<a href="/blog/">Blog</a> <a href="/blog">Blog</a> <a href="blog/post.html#top">Post</a>
+<link href="/style.css"> <img src="/missing.png"> <a href="https://example.com/blog/post.html">Abs</a>
+<a href="https://other.com/missing">Ext</a> <a href="mailto:me@example.com">Mail</a> <a href="#top">Top</a>
+<a href="/CNAME">Kept</a>`,
+		"blog/index.html": `<a href="post.html">Post</a> <a href="../">Home</a> <a href="other.html">Other</a>
+<a href="https://example.com/gone/">Gone</a>`,
+		"blog/post.html": `<a href='/blog/'>Blog</a>`,
+		"CNAME":          "example.com",
+	}
+	for name, data := range pages {
+		ok(t, ioutil.WriteFile(filepath.Join(output, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.OutputDir = output
+	config.BaseURL = "https://example.com/"
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	root.Process()
+
+	ok(t, site.checkLinks(root))
+
+	config.CheckLinks = "warn"
+	ok(t, site.checkLinks(root))
+
+	config.CheckLinks = "error"
+	err = site.checkLinks(root)
+	assert(t, err != nil, "Expected broken links")
+	equals(t, Category(err), LinkError)
+	equals(t, strings.Split(err.Error(), "\n")[1:], []string{
+		"/blog/index.html: https://example.com/gone/",
+		"/blog/index.html: other.html",
+		"/index.html: /missing.png",
+	})
+}
+
+func TestLocalTargetBasePath(t *testing.T) {
+	config := DefaultConfig()
+	config.BaseURL = "https://example.com/docs/"
+	site := NewSite(config)
+	check := func(link, expected string, local bool) {
+		target, ok := site.localTarget("guide/index.html", link)
+		equals(t, ok, local)
+		equals(t, target, expected)
+	}
+	check("/docs/css/site.css", "/css/site.css", true)
+	check("/docs/", "/", true)
+	check("/elsewhere", "/elsewhere", true)
+	check("https://example.com/docs/guide/", "/guide/", true)
+	check("intro.html", "/guide/intro.html", true)
+	check("../", "/", true)
+	check("https://other.com/docs/", "", false)
+	check("?page=2", "", false)
+}
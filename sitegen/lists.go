@@ -0,0 +1,35 @@
+package sitegen
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/russross/blackfriday"
+)
+
+var taskItemRegex = regexp.MustCompile(`^(<p>)?\[([ xX])\]\s+`)
+
+// ListItem turns list items that start with [ ] or [x] into GitHub-style
+// task list items, with a checkbox.
+func (r *renderer) ListItem(out *bytes.Buffer, text []byte, flags int) {
+	m := taskItemRegex.FindSubmatch(text)
+	if !r.taskLists || m == nil || flags&(blackfriday.LIST_TYPE_DEFINITION|blackfriday.LIST_TYPE_TERM) != 0 {
+		r.Html.ListItem(out, text, flags)
+		return
+	}
+
+	item := &bytes.Buffer{}
+	item.Write(m[1])
+	item.WriteString(`<input type="checkbox" class="task-list-item-checkbox" disabled="disabled"`)
+	if !bytes.Equal(m[2], []byte(" ")) {
+		item.WriteString(` checked="checked"`)
+	}
+	item.WriteString(" /> ")
+	item.Write(text[len(m[0]):])
+
+	start := out.Len()
+	r.Html.ListItem(out, item.Bytes(), flags)
+	written := append([]byte{}, out.Bytes()[start:]...)
+	out.Truncate(start)
+	out.Write(bytes.Replace(written, []byte("<li>"), []byte(`<li class="task-list-item">`), 1))
+}
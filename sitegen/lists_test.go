@@ -0,0 +1,26 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestTaskLists(t *testing.T) {
+	input := []byte("- [ ] Write docs\n- [x] Fix bug\n- Plain [ ] item\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<ul>\n"+
+			"<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled=\"disabled\" /> Write docs</li>\n"+
+			"<li class=\"task-list-item\"><input type=\"checkbox\" class=\"task-list-item-checkbox\" disabled=\"disabled\" checked=\"checked\" /> Fix bug</li>\n"+
+			"<li>Plain [ ] item</li>\n"+
+			"</ul>\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{Extensions: map[string]bool{"taskLists": false}})),
+		"<ul>\n<li>[ ] Write docs</li>\n<li>[x] Fix bug</li>\n<li>Plain [ ] item</li>\n</ul>\n")
+}
+
+func TestDefinitionLists(t *testing.T) {
+	input := []byte("Sitegen\n: A static site generator\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<dl>\n<dt>Sitegen</dt>\n<dd>A static site generator</dd>\n</dl>\n")
+}
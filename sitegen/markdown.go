@@ -0,0 +1,491 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/russross/blackfriday"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	headerIDRegex = regexp.MustCompile(`^\s*<h\d id="([^"]+)">`)
+	htmlTagRegex  = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)[^>]*>`)
+)
+
+// Markdown extensions that can be turned on or off, by name.
+var markdownExtensions = map[string]int{
+	"noIntraEmphasis":        blackfriday.EXTENSION_NO_INTRA_EMPHASIS,
+	"tables":                 blackfriday.EXTENSION_TABLES,
+	"fencedCode":             blackfriday.EXTENSION_FENCED_CODE,
+	"autolink":               blackfriday.EXTENSION_AUTOLINK,
+	"strikethrough":          blackfriday.EXTENSION_STRIKETHROUGH,
+	"laxHTMLBlocks":          blackfriday.EXTENSION_LAX_HTML_BLOCKS,
+	"spaceHeadings":          blackfriday.EXTENSION_SPACE_HEADERS,
+	"hardLineBreak":          blackfriday.EXTENSION_HARD_LINE_BREAK,
+	"footnotes":              blackfriday.EXTENSION_FOOTNOTES,
+	"noEmptyLineBeforeBlock": blackfriday.EXTENSION_NO_EMPTY_LINE_BEFORE_BLOCK,
+	"headingIDs":             blackfriday.EXTENSION_HEADER_IDS,
+	"titleblock":             blackfriday.EXTENSION_TITLEBLOCK,
+	"backslashLineBreak":     blackfriday.EXTENSION_BACKSLASH_LINE_BREAK,
+	"definitionLists":        blackfriday.EXTENSION_DEFINITION_LISTS,
+
+	// Handled by the renderer.
+	"taskLists":  0,
+	"attributes": 0,
+}
+
+// Options of the HTML renderer that can be turned on or off like
+// extensions.
+var markdownHTMLOptions = map[string]int{
+	"smartypants":             blackfriday.HTML_USE_SMARTYPANTS,
+	"smartypantsFractions":    blackfriday.HTML_SMARTYPANTS_FRACTIONS,
+	"smartypantsDashes":       blackfriday.HTML_SMARTYPANTS_DASHES,
+	"smartypantsLatexDashes":  blackfriday.HTML_SMARTYPANTS_LATEX_DASHES,
+	"smartypantsAngledQuotes": blackfriday.HTML_SMARTYPANTS_ANGLED_QUOTES,
+}
+
+// Extensions that are on unless turned off.
+var defaultMarkdownExtensions = []string{
+	"noIntraEmphasis", "tables", "fencedCode", "autolink", "strikethrough",
+	"spaceHeadings", "headingIDs", "footnotes", "definitionLists",
+	"taskLists", "attributes", "smartypants", "smartypantsFractions",
+	"smartypantsLatexDashes",
+}
+
+// MarkdownConfig configures how Markdown content is rendered.
+type MarkdownConfig struct {
+	// Extensions to turn on or off, e.g. {tables: false, hardLineBreak:
+	// true}. See markdownExtensions and markdownHTMLOptions for the names.
+	Extensions map[string]bool `yaml:"extensions"`
+
+	// Give every heading an id generated from its text (Getting started
+	// becomes getting-started), so it can be linked to. Repeated ids get a
+	// number (intro-1). Set an id by hand with ## Heading {#my-id}, those
+	// are never renamed.
+	HeadingIDs bool `yaml:"headingIDs"`
+
+	// Add a link to itself to each h2-h4 heading, for copying. Implies
+	// HeadingIDs.
+	HeadingAnchors bool `yaml:"headingAnchors"`
+
+	// Text of the heading anchors, defaults to "#".
+	AnchorSymbol string `yaml:"anchorSymbol"`
+
+	Footnotes FootnoteConfig `yaml:"footnotes"`
+
+	Math MathConfig `yaml:"math"`
+
+	// Code blocks that are diagrams, keyed by language (mermaid, plantuml).
+	Diagrams map[string]DiagramConfig `yaml:"diagrams"`
+
+	// Pass on HTML written in Markdown, on by default. Turned off, it's
+	// left out.
+	UnsafeHTML *bool `yaml:"unsafeHTML"`
+
+	// Smart punctuation (curly quotes, dashes), the same as the
+	// smartypants extension.
+	Smartypants *bool `yaml:"smartypants"`
+
+	// Quotes of smart punctuation: a style (english, german, dutch,
+	// french, straight, ...) or the four quotes to use (double opening and
+	// closing, then single, e.g. „“‚‘). Defaults to the style of the
+	// language of the page, English quotes otherwise.
+	Quotes string `yaml:"quotes"`
+
+	// Turn emoji shortcodes (:tada:) into emoji.
+	Emoji bool `yaml:"emoji"`
+
+	// Show emoji as images from this URL, rather than as text. {name} is
+	// replaced by the shortcode, {code} by the code points in hex (1f389).
+	EmojiImages string `yaml:"emojiImages"`
+
+	// Open links to other sites in a new tab, with target="_blank" and
+	// rel="noopener noreferrer".
+	ExternalLinksNewTab bool `yaml:"externalLinksNewTab"`
+
+	// Point relative links to Markdown files ([x](../other.md)) to the
+	// page made from them, so the content reads well elsewhere (e.g. on
+	// GitHub) and works on the site.
+	RewriteMarkdownLinks bool `yaml:"rewriteMarkdownLinks"`
+
+	// Address of the site, links elsewhere are external.
+	baseURL string
+
+	// Prefix of the footnote ids of the page being rendered.
+	footnotePrefix string
+
+	// Templates for the links and images of the page being rendered.
+	hooks *renderHooks
+
+	// Language of the page being rendered.
+	lang string
+}
+
+// FootnoteConfig configures the rendering of footnotes.
+type FootnoteConfig struct {
+	// Text of the links from a footnote back to where it's referenced,
+	// defaults to "↩".
+	ReturnLink string `yaml:"returnLink"`
+
+	// Prefix the footnote ids with the path of the page (fn:blog-post-1
+	// instead of fn:1), so they don't collide when several pages are shown
+	// on one, e.g. on a listing.
+	PagePrefix bool `yaml:"pagePrefix"`
+
+	// Heading above the footnotes, none by default.
+	Heading string `yaml:"heading"`
+}
+
+// markdownConfig returns the Markdown settings for rendering a page, with
+// the overrides from its front matter: markup holds any of the Markdown
+// settings (markup: {unsafeHTML: true, extensions: {tables: false}}).
+func (s *Site) markdownConfig(sourcePath string, metadata Metadata) (MarkdownConfig, error) {
+	config := s.Config.Markdown
+	if markup, ok := metadata.Params["markup"]; ok {
+		data, err := yaml.Marshal(markup)
+		if err != nil {
+			return config, err
+		}
+
+		page := MarkdownConfig{}
+		err = yaml.UnmarshalStrict(data, &page)
+		if err != nil {
+			return config, fmt.Errorf("%s: invalid markup: %w", sourcePath, err)
+		}
+		if page.hasCommands() {
+			return config, categorize(ParseError, fmt.Errorf("%s: invalid markup: commands can only be set in sitegen.yaml", sourcePath))
+		}
+
+		// Copied, so the page adds to the site settings rather than
+		// changing them.
+		config.Extensions = make(map[string]bool)
+		for name, enabled := range s.Config.Markdown.Extensions {
+			config.Extensions[name] = enabled
+		}
+		config.Diagrams = make(map[string]DiagramConfig)
+		for lang, diagram := range s.Config.Markdown.Diagrams {
+			config.Diagrams[lang] = diagram
+		}
+		config.UnsafeHTML = copyBool(config.UnsafeHTML)
+		config.Smartypants = copyBool(config.Smartypants)
+
+		err = yaml.Unmarshal(data, &config)
+		if err != nil {
+			return config, err
+		}
+
+		// Diagrams listed by the page still render with the site's
+		// commands.
+		for lang, diagram := range config.Diagrams {
+			diagram.Command = s.Config.Markdown.Diagrams[lang].Command
+			config.Diagrams[lang] = diagram
+		}
+	}
+	err := config.validate()
+	if err != nil {
+		return config, fmt.Errorf("%s: %w", sourcePath, err)
+	}
+
+	if config.Footnotes.PagePrefix {
+		config.footnotePrefix = slugify(strings.TrimSuffix(sourcePath, path.Ext(sourcePath))) + "-"
+	}
+	config.baseURL = s.Config.BaseURL
+	config.hooks = s.renderHooks(sourcePath)
+	config.lang = s.languageOf(sourcePath)
+	return config, nil
+}
+
+// hasCommands tells whether the settings run any commands, which pages
+// can't do: their front matter is often less trusted than sitegen.yaml.
+func (c MarkdownConfig) hasCommands() bool {
+	if len(c.Math.Command) > 0 || len(c.Math.DisplayCommand) > 0 {
+		return true
+	}
+	for _, diagram := range c.Diagrams {
+		if len(diagram.Command) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	v := *b
+	return &v
+}
+
+// validate checks that all extensions and the quote style exist.
+func (c MarkdownConfig) validate() error {
+	if _, err := c.quoteStyle(); err != nil {
+		return err
+	}
+
+	unknown := make([]string, 0)
+	for name := range c.Extensions {
+		if _, ok := markdownExtensions[name]; ok {
+			continue
+		}
+		if _, ok := markdownHTMLOptions[name]; ok {
+			continue
+		}
+		unknown = append(unknown, name)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Unknown Markdown extension: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// enabled returns whether each extension is on.
+func (c MarkdownConfig) enabled() map[string]bool {
+	enabled := make(map[string]bool)
+	for _, name := range defaultMarkdownExtensions {
+		enabled[name] = true
+	}
+	for name, on := range c.Extensions {
+		enabled[name] = on
+	}
+	if c.Smartypants != nil {
+		enabled["smartypants"] = *c.Smartypants
+	}
+	return enabled
+}
+
+// flags returns the blackfriday extensions and HTML flags to render with.
+// unsafeHTML reports whether raw HTML is allowed, which it is unless
+// unsafeHTML is set to false.
+func (c MarkdownConfig) unsafeHTML() bool {
+	return c.UnsafeHTML == nil || *c.UnsafeHTML
+}
+
+func (c MarkdownConfig) flags() (int, int) {
+	extensions, htmlFlags := 0, 0
+	for name, on := range c.enabled() {
+		if !on {
+			continue
+		}
+		extensions |= markdownExtensions[name]
+		htmlFlags |= markdownHTMLOptions[name]
+	}
+	if c.UnsafeHTML != nil && !*c.UnsafeHTML {
+		htmlFlags |= blackfriday.HTML_SKIP_HTML | blackfriday.HTML_SKIP_STYLE
+	}
+	return extensions, htmlFlags
+}
+
+// markdown renders the Markdown of a page, with the steps that need the
+// site (e.g. including files, rendering math and diagrams).
+func (s *Site) markdown(body []byte, sourcePath string, metadata Metadata) ([]byte, error) {
+	body, err := s.includeFiles(sourcePath, body)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := s.markdownConfig(sourcePath, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return s.cachedMarkdown(body, config, func() ([]byte, error) {
+		content := renderMarkdown(body, config)
+		if config.hooks != nil && config.hooks.err != nil {
+			return nil, config.hooks.err
+		}
+		if len(config.Diagrams) > 0 {
+			content, err = s.renderDiagrams(sourcePath, content, config.Diagrams)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if config.Math.Enabled && len(config.Math.Command) > 0 {
+			return s.renderMath(sourcePath, content, config.Math)
+		}
+		return content, nil
+	})
+}
+
+// headingRecorder collects the ids set by hand on headings.
+type headingRecorder struct {
+	*blackfriday.Html
+	ids []string
+}
+
+func (r *headingRecorder) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	r.ids = append(r.ids, id)
+}
+
+// explicitHeadingIDs returns the id set by hand on each heading
+// (## Setup {#install}), in order, "" for headings without one. Without
+// generated ids, the parser only passes those.
+func explicitHeadingIDs(input []byte, extensions int) []string {
+	recorder := &headingRecorder{Html: blackfriday.HtmlRenderer(0, "", "").(*blackfriday.Html)}
+	blackfriday.Markdown(input, recorder, extensions&^blackfriday.EXTENSION_AUTO_HEADER_IDS)
+	return recorder.ids
+}
+
+// uniqueHeadingID returns the id for the next heading. Ids set by hand are
+// kept, generated ones that are already taken get a number (intro-1),
+// skipping the ids set by hand anywhere on the page.
+func (r *renderer) uniqueHeadingID(id string) string {
+	if r.headingIDs == nil {
+		r.headingIDs = make(map[string]bool)
+	}
+	explicit := r.headings < len(r.explicitIDs) && r.explicitIDs[r.headings] == id
+	r.headings++
+	if explicit && !r.headingIDs[id] {
+		r.headingIDs[id] = true
+		return id
+	}
+
+	unique := id
+	for n := 1; r.headingIDs[unique] || r.isExplicitID(unique); n++ {
+		unique = fmt.Sprintf("%s-%d", id, n)
+	}
+	r.headingIDs[unique] = true
+	return unique
+}
+
+func (r *renderer) isExplicitID(id string) bool {
+	for _, v := range r.explicitIDs {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *renderer) Header(out *bytes.Buffer, text func() bool, level int, id string) {
+	if id != "" {
+		id = r.uniqueHeadingID(id)
+	}
+	start := out.Len()
+	r.Html.Header(out, text, level, id)
+	r.markBlock(out, start)
+	if !r.config.HeadingAnchors || level < 2 || level > 4 {
+		return
+	}
+
+	header := out.Bytes()[start:]
+	m := headerIDRegex.FindSubmatch(header)
+	end := bytes.LastIndex(header, []byte(fmt.Sprintf("</h%d>", level)))
+	if m == nil || end == -1 {
+		return
+	}
+
+	symbol := r.config.AnchorSymbol
+	if symbol == "" {
+		symbol = "#"
+	}
+	rest := append([]byte(nil), header[end:]...)
+	out.Truncate(start + end)
+	fmt.Fprintf(out, ` <a class="anchor" href="#%s" aria-hidden="true">%s</a>`, m[1], html.EscapeString(symbol))
+	out.Write(rest)
+}
+
+func (r *renderer) Footnotes(out *bytes.Buffer, text func() bool) {
+	out.WriteString("<div class=\"footnotes\">\n")
+	r.HRule(out)
+	if heading := r.config.Footnotes.Heading; heading != "" {
+		fmt.Fprintf(out, "<h2>%s</h2>\n", html.EscapeString(heading))
+	}
+	r.List(out, text, blackfriday.LIST_TYPE_ORDERED)
+	out.WriteString("</div>\n")
+}
+
+// AutoLink marks bare links to other sites, like Link.
+func (r *renderer) AutoLink(out *bytes.Buffer, link []byte, kind int) {
+	start := out.Len()
+	r.Html.AutoLink(out, link, kind)
+	if kind != blackfriday.LINK_TYPE_EMAIL {
+		r.markExternal(out, start, link)
+	}
+}
+
+// markExternal adds the attributes of external links to the link written
+// from start, when it's one.
+func (r *renderer) markExternal(out *bytes.Buffer, start int, link []byte) {
+	if !r.config.ExternalLinksNewTab || !leavesSite(string(link), r.config.baseURL) {
+		return
+	}
+	written := out.Bytes()[start:]
+	end := bytes.IndexByte(written, '>')
+	if !bytes.HasPrefix(written, []byte("<a ")) || end == -1 {
+		return
+	}
+
+	tag := append([]byte{}, written[:end]...)
+	rest := append([]byte{}, written[end:]...)
+	out.Truncate(start)
+	out.Write(tag)
+	out.WriteString(` target="_blank" rel="noopener noreferrer"`)
+	out.Write(rest)
+}
+
+// leavesSite returns whether a link goes to another site: an http(s) or
+// protocol-relative URL outside of the baseURL.
+func leavesSite(link, baseURL string) bool {
+	lower := strings.ToLower(link)
+	if !isExternalLink(lower) {
+		return false
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Host == "" {
+		return true
+	}
+	target, err := url.Parse(link)
+	if err != nil || !strings.EqualFold(target.Host, base.Host) {
+		return true
+	}
+	prefix := strings.TrimSuffix(base.Path, "/")
+	return target.Path != prefix && !strings.HasPrefix(target.Path, prefix+"/")
+}
+
+// replaceInText calls fn for the text between the tags of rendered HTML and
+// replaces it with the result. Within code, fn gets the innermost code tag:
+// code and pre hold escaped text, highlight (code blocks, before they're
+// highlighted) holds it as written.
+func replaceInText(in []byte, fn func(text, code string) string) []byte {
+	out := &strings.Builder{}
+	code := make([]string, 0)
+	text := string(in)
+	for len(text) > 0 {
+		loc := htmlTagRegex.FindStringSubmatchIndex(text)
+		end := len(text)
+		if loc != nil {
+			end = loc[0]
+		}
+
+		inner := ""
+		if len(code) > 0 {
+			inner = code[len(code)-1]
+		}
+		out.WriteString(fn(text[:end], inner))
+		if loc == nil {
+			break
+		}
+
+		tag := strings.ToLower(text[loc[4]:loc[5]])
+		if tag == "code" || tag == "pre" || tag == "highlight" {
+			if loc[3] > loc[2] {
+				if len(code) > 0 {
+					code = code[:len(code)-1]
+				}
+			} else {
+				code = append(code, tag)
+			}
+		}
+		out.WriteString(text[loc[0]:loc[1]])
+		text = text[loc[1]:]
+	}
+	return []byte(out.String())
+}
@@ -0,0 +1,137 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestHeadingAnchors(t *testing.T) {
+	input := []byte("# Title\n\n## Getting started\n\n### Install {#setup}\n\n##### Deep\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<h1>Title</h1>\n\n<h2>Getting started</h2>\n\n<h3 id=\"setup\">Install</h3>\n\n<h5>Deep</h5>\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{HeadingAnchors: true})),
+		"<h1 id=\"title\">Title</h1>\n\n"+
+			"<h2 id=\"getting-started\">Getting started <a class=\"anchor\" href=\"#getting-started\" aria-hidden=\"true\">#</a></h2>\n\n"+
+			"<h3 id=\"setup\">Install <a class=\"anchor\" href=\"#setup\" aria-hidden=\"true\">#</a></h3>\n\n"+
+			"<h5 id=\"deep\">Deep</h5>\n")
+
+	equals(t, string(renderMarkdown([]byte("## Hello\n"), MarkdownConfig{HeadingAnchors: true, AnchorSymbol: "¶"})),
+		"<h2 id=\"hello\">Hello <a class=\"anchor\" href=\"#hello\" aria-hidden=\"true\">¶</a></h2>\n")
+}
+
+func TestHeadingIDs(t *testing.T) {
+	input := []byte("## Intro\n\n## Intro\n\n## Setup {#intro-1}\n\n## Intro\n\n## Other {#custom}\n")
+	equals(t, string(renderMarkdown(input, MarkdownConfig{HeadingIDs: true})),
+		"<h2 id=\"intro\">Intro</h2>\n\n"+
+			"<h2 id=\"intro-2\">Intro</h2>\n\n"+
+			"<h2 id=\"intro-1\">Setup</h2>\n\n"+
+			"<h2 id=\"intro-3\">Intro</h2>\n\n"+
+			"<h2 id=\"custom\">Other</h2>\n")
+
+	// Ids set by hand win, even over earlier generated ones.
+	input = []byte("## Custom\n\n## Other {#custom}\n")
+	equals(t, string(renderMarkdown(input, MarkdownConfig{HeadingIDs: true})),
+		"<h2 id=\"custom-1\">Custom</h2>\n\n<h2 id=\"custom\">Other</h2>\n")
+}
+
+func TestFootnotes(t *testing.T) {
+	input := []byte("Text[^1].\n\n[^1]: Note.\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<p>Text<sup class=\"footnote-ref\" id=\"fnref:1\"><a href=\"#fn:1\">1</a></sup>.</p>\n"+
+			"<div class=\"footnotes\">\n\n<hr />\n\n<ol>\n"+
+			"<li id=\"fn:1\">Note.\n <a class=\"footnote-return\" href=\"#fnref:1\">↩</a></li>\n"+
+			"</ol>\n</div>\n")
+
+	config := DefaultConfig()
+	config.Markdown.Footnotes = FootnoteConfig{ReturnLink: "back", PagePrefix: true, Heading: "Notes"}
+	site := NewSite(config)
+	out, err := site.markdown(input, "blog/my-post.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out),
+		"<p>Text<sup class=\"footnote-ref\" id=\"fnref:blog-my-post-1\"><a href=\"#fn:blog-my-post-1\">1</a></sup>.</p>\n"+
+			"<div class=\"footnotes\">\n\n<hr />\n<h2>Notes</h2>\n\n<ol>\n"+
+			"<li id=\"fn:blog-my-post-1\">Note.\n <a class=\"footnote-return\" href=\"#fnref:blog-my-post-1\">back</a></li>\n"+
+			"</ol>\n</div>\n")
+}
+
+func TestExternalLinksNewTab(t *testing.T) {
+	input := []byte("[Go](https://golang.org/) [Home](https://example.com/docs/) [Rel](/about/) https://github.com/ <a@b.com>\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<p><a href=\"https://golang.org/\">Go</a> <a href=\"https://example.com/docs/\">Home</a> <a href=\"/about/\">Rel</a> <a href=\"https://github.com/\">https://github.com/</a> <a href=\"mailto:a@b.com\">a@b.com</a></p>\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{ExternalLinksNewTab: true, baseURL: "https://example.com/"})),
+		"<p><a href=\"https://golang.org/\" target=\"_blank\" rel=\"noopener noreferrer\">Go</a> "+
+			"<a href=\"https://example.com/docs/\">Home</a> <a href=\"/about/\">Rel</a> "+
+			"<a href=\"https://github.com/\" target=\"_blank\" rel=\"noopener noreferrer\">https://github.com/</a> "+
+			"<a href=\"mailto:a@b.com\">a@b.com</a></p>\n")
+}
+
+func TestLeavesSite(t *testing.T) {
+	equals(t, leavesSite("https://golang.org/", ""), true)
+	equals(t, leavesSite("//cdn.example.com/x.js", ""), true)
+	equals(t, leavesSite("/about/", ""), false)
+	equals(t, leavesSite("mailto:a@b.com", ""), false)
+	equals(t, leavesSite("https://example.com/docs/a/", "https://example.com/docs/"), false)
+	equals(t, leavesSite("http://EXAMPLE.com/docs", "https://example.com/docs/"), false)
+	equals(t, leavesSite("https://example.com/other/", "https://example.com/docs/"), true)
+	equals(t, leavesSite("https://example.community/", "https://example.com"), true)
+	equals(t, leavesSite("https://example.com/docs?x=1", "https://example.com/docs/"), false)
+	equals(t, leavesSite("https://example.com/", "example.com"), true)
+	equals(t, leavesSite("https://example.com/", "/docs/"), true)
+}
+
+func TestMarkdownExtensions(t *testing.T) {
+	input := []byte("a ~~b~~ \"c\" 1/2\nd\n")
+
+	equals(t, string(renderMarkdown(input, MarkdownConfig{})),
+		"<p>a <del>b</del> &ldquo;c&rdquo; <sup>1</sup>&frasl;<sub>2</sub>\nd</p>\n")
+
+	config := MarkdownConfig{Extensions: map[string]bool{"strikethrough": false, "smartypants": false, "hardLineBreak": true}}
+	equals(t, string(renderMarkdown(input, config)),
+		"<p>a ~~b~~ &quot;c&quot; 1/2<br />\nd</p>\n")
+
+	site := NewSite(DefaultConfig())
+	site.Config.Markdown.Extensions = map[string]bool{"hardLineBreak": true}
+	out, err := site.markdown(input, "index.md", Metadata{Params: map[string]interface{}{
+		"markup": map[interface{}]interface{}{"extensions": map[interface{}]interface{}{"strikethrough": false}},
+	}})
+	ok(t, err)
+	equals(t, string(out), "<p>a ~~b~~ &ldquo;c&rdquo; <sup>1</sup>&frasl;<sub>2</sub><br />\nd</p>\n")
+
+	_, err = site.markdown(input, "index.md", Metadata{Params: map[string]interface{}{
+		"markup": map[interface{}]interface{}{"extensions": map[interface{}]interface{}{"tabels": false}},
+	}})
+	equals(t, err.Error(), "index.md: Unknown Markdown extension: tabels")
+}
+
+func TestPageMarkup(t *testing.T) {
+	input := []byte("\"Hi\" <b>there</b>\n\n<div>Raw</div>\n")
+	unsafe := false
+
+	site := NewSite(DefaultConfig())
+	site.Config.Markdown.UnsafeHTML = &unsafe
+	site.Config.Markdown.Extensions = map[string]bool{"tables": true}
+
+	out, err := site.markdown(input, "index.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<p>&ldquo;Hi&rdquo; there</p>\n")
+
+	markup := func(v map[interface{}]interface{}) Metadata {
+		return Metadata{Params: map[string]interface{}{"markup": v}}
+	}
+	out, err = site.markdown(input, "index.md", markup(map[interface{}]interface{}{
+		"unsafeHTML":  true,
+		"smartypants": false,
+		"extensions":  map[interface{}]interface{}{"tables": false},
+	}))
+	ok(t, err)
+	equals(t, string(out), "<p>&quot;Hi&quot; <b>there</b></p>\n\n<div>Raw</div>\n")
+	equals(t, site.Config.Markdown.Extensions, map[string]bool{"tables": true})
+	equals(t, *site.Config.Markdown.UnsafeHTML, false)
+
+	_, err = site.markdown(input, "index.md", markup(map[interface{}]interface{}{"unsafeHtml": true}))
+	assert(t, err != nil, "Expected an error for an unknown setting")
+}
@@ -0,0 +1,158 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+)
+
+var (
+	displayMathRegex     = regexp.MustCompile(`(?s)\$\$(.+?)\$\$`)
+	inlineMathRegex      = regexp.MustCompile(`\$([^\s$](?:[^$\n]*?[^\s$\\])?)\$`)
+	mathPlaceholderRegex = regexp.MustCompile("\x00math(\\d+)\x00")
+	displayMathParaRegex = regexp.MustCompile("<p>\x00math(\\d+)\x00</p>")
+	renderedMathRegex    = regexp.MustCompile(`(?s)<(span|div) class="math (inline|display)">\\[(\[](.*?)\\[)\]]</(?:span|div)>`)
+)
+
+// MathConfig configures the rendering of TeX math in Markdown.
+type MathConfig struct {
+	// Recognize $inline$ and $$display$$ math. It's kept as written
+	// (\(...\) and \[...\] in a math span or div) for rendering in the
+	// browser, e.g. with KaTeX.
+	Enabled bool `yaml:"enabled"`
+
+	// Render math when building instead, with a command that reads TeX on
+	// stdin and writes HTML or MathML to stdout (e.g. [katex]).
+	Command []string `yaml:"command"`
+
+	// Command for display math, defaults to Command.
+	DisplayCommand []string `yaml:"displayCommand"`
+}
+
+type mathSpan struct {
+	source  []byte
+	tex     []byte
+	display bool
+
+	// An escaped dollar (\$), rather than math.
+	escaped bool
+}
+
+// protectMath swaps the math in Markdown for placeholders, so it isn't
+// mangled by the Markdown renderer. Dollars escaped with a backslash, and
+// amounts like $5 and $10, aren't math.
+func protectMath(input []byte) ([]byte, []mathSpan) {
+	spans := make([]mathSpan, 0)
+	add := func(span mathSpan) []byte {
+		spans = append(spans, span)
+		return []byte("\x00math" + strconv.Itoa(len(spans)-1) + "\x00")
+	}
+	dollar := add(mathSpan{source: []byte(`\$`), escaped: true})
+	placeholder := func(m []byte, tex []byte, display bool) []byte {
+		return add(mathSpan{
+			source:  bytes.ReplaceAll(m, dollar, []byte(`\$`)),
+			tex:     bytes.ReplaceAll(tex, dollar, []byte(`\$`)),
+			display: display,
+		})
+	}
+
+	// Placeholders are delimited by NUL bytes, which don't belong in text,
+	// so the page can't contain one.
+	input = bytes.ReplaceAll(input, []byte{0}, nil)
+	input = bytes.ReplaceAll(input, []byte(`\$`), dollar)
+	input = replaceMath(input, displayMathRegex, func(m []byte) []byte {
+		return placeholder(m, bytes.TrimSpace(m[2:len(m)-2]), true)
+	})
+	input = replaceMath(input, inlineMathRegex, func(m []byte) []byte {
+		return placeholder(m, m[1:len(m)-1], false)
+	})
+	return input, spans
+}
+
+func replaceMath(input []byte, re *regexp.Regexp, fn func(m []byte) []byte) []byte {
+	out := &bytes.Buffer{}
+	last := 0
+	for _, loc := range re.FindAllIndex(input, -1) {
+		if loc[0] < last {
+			continue
+		}
+		if loc[1] < len(input) && input[loc[1]] >= '0' && input[loc[1]] <= '9' {
+			continue
+		}
+		out.Write(input[last:loc[0]])
+		out.Write(fn(input[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	out.Write(input[last:])
+	return out.Bytes()
+}
+
+// restoreMath puts the math back into the rendered HTML. Placeholders that
+// ended up in code get the original text back.
+func restoreMath(in []byte, spans []mathSpan) []byte {
+	span := func(m []byte, re *regexp.Regexp) *mathSpan {
+		i, _ := strconv.Atoi(string(re.FindSubmatch(m)[1]))
+		if i >= len(spans) {
+			return nil
+		}
+		return &spans[i]
+	}
+
+	in = displayMathParaRegex.ReplaceAllFunc(in, func(m []byte) []byte {
+		if span := span(m, displayMathParaRegex); span != nil && span.display {
+			return []byte(mathHTML(span))
+		}
+		return m
+	})
+	return replaceInText(in, func(text, code string) string {
+		return mathPlaceholderRegex.ReplaceAllStringFunc(text, func(m string) string {
+			span := span([]byte(m), mathPlaceholderRegex)
+			switch {
+			case span == nil:
+				return m
+			case code == "highlight":
+				return string(span.source)
+			case code != "":
+				return html.EscapeString(string(span.source))
+			case span.escaped:
+				return "$"
+			default:
+				return mathHTML(span)
+			}
+		})
+	})
+}
+
+func mathHTML(span *mathSpan) string {
+	tex := html.EscapeString(string(span.tex))
+	if span.display {
+		return `<div class="math display">\[` + tex + `\]</div>`
+	}
+	return `<span class="math inline">\(` + tex + `\)</span>`
+}
+
+// renderMath renders the math in a page with the configured command.
+func (s *Site) renderMath(path string, in []byte, config MathConfig) ([]byte, error) {
+	var renderErr error
+	out := renderedMathRegex.ReplaceAllFunc(in, func(m []byte) []byte {
+		parts := renderedMathRegex.FindSubmatch(m)
+		command := config.Command
+		if string(parts[2]) == "display" && len(config.DisplayCommand) > 0 {
+			command = config.DisplayCommand
+		}
+
+		tex := html.UnescapeString(string(parts[3]))
+		rendered, err := s.transform(Transformer{Command: command}, []byte(tex))
+		if err != nil {
+			renderErr = fmt.Errorf("Rendering math in %s failed: %w", path, err)
+			return m
+		}
+		return bytes.TrimSpace(rendered)
+	})
+	if renderErr != nil {
+		return nil, renderErr
+	}
+	return out, nil
+}
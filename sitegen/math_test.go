@@ -0,0 +1,90 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMath(t *testing.T) {
+	config := MarkdownConfig{Math: MathConfig{Enabled: true}}
+
+	equals(t, string(renderMarkdown([]byte("Euler: $e^{i\\pi} + 1 = 0$, *a_b* $x<y$.\n"), config)),
+		"<p>Euler: <span class=\"math inline\">\\(e^{i\\pi} + 1 = 0\\)</span>, <em>a_b</em> <span class=\"math inline\">\\(x&lt;y\\)</span>.</p>\n")
+
+	equals(t, string(renderMarkdown([]byte("It costs $5 or $10.\n\nWrite \\$x$, $\\$y$.\n"), config)),
+		"<p>It costs $5 or $10.</p>\n\n<p>Write $x$, <span class=\"math inline\">\\(\\$y\\)</span>.</p>\n")
+
+	equals(t, string(renderMarkdown([]byte("Sum:\n\n$$\n\\sum_{i=1}^n i_*\n$$\n\nDone.\n"), config)),
+		"<p>Sum:</p>\n\n<div class=\"math display\">\\[\\sum_{i=1}^n i_*\\]</div>\n\n<p>Done.</p>\n")
+
+	equals(t, string(renderMarkdown([]byte("Run `echo $a$`:\n\n```sh\necho $HOME$ <x>\n```\n"), config)),
+		"<p>Run <code>echo $a$</code>:</p>\n<highlight language=\"sh\">echo $HOME$ <x></highlight>")
+
+	equals(t, string(renderMarkdown([]byte("Just $x$.\n"), MarkdownConfig{})),
+		"<p>Just $x$.</p>\n")
+
+	// Text that looks like a placeholder stays as is.
+	equals(t, string(renderMarkdown([]byte("$x$ sitegenmath0x math0 \x00math0\x00\n"), config)),
+		"<p><span class=\"math inline\">\\(x\\)</span> sitegenmath0x math0 math0</p>\n")
+}
+
+func TestRenderMath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	command := filepath.Join(dir, "math")
+	ok(t, ioutil.WriteFile(command, []byte("#!/bin/sh\necho \"<math>$(cat)</math>\"\n"), 0755))
+
+	config := DefaultConfig()
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.Markdown.Math = MathConfig{
+		Enabled:        true,
+		Command:        []string{command},
+		DisplayCommand: []string{command, "display"},
+	}
+	site := NewSite(config)
+
+	out, err := site.markdown([]byte("Inline $a<b$ and\n\n$$x^2$$\n"), "index.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<p>Inline <math>a<b</math> and</p>\n\n<math>x^2</math>\n")
+
+	config.Markdown.Math.Command = []string{filepath.Join(dir, "missing")}
+	_, err = site.markdown([]byte("Inline $a$\n"), "index.md", Metadata{})
+	assert(t, err != nil, "Expected an error for a failing command")
+}
+
+func TestPageMath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	command := filepath.Join(dir, "math")
+	ok(t, ioutil.WriteFile(command, []byte("#!/bin/sh\necho \"<math>$(cat)</math>\"\n"), 0755))
+
+	config := DefaultConfig()
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.Markdown.Math = MathConfig{Command: []string{command}}
+	site := NewSite(config)
+
+	markup := func(v map[interface{}]interface{}) Metadata {
+		return Metadata{Params: map[string]interface{}{"markup": v}}
+	}
+	out, err := site.markdown([]byte("Inline $a$\n"), "index.md", markup(map[interface{}]interface{}{
+		"math": map[interface{}]interface{}{"enabled": true},
+	}))
+	ok(t, err)
+	equals(t, string(out), "<p>Inline <math>a</math></p>\n")
+
+	for _, v := range []map[interface{}]interface{}{
+		{"math": map[interface{}]interface{}{"enabled": true, "command": []interface{}{"sh", "-c", "id"}}},
+		{"math": map[interface{}]interface{}{"displayCommand": []interface{}{"id"}}},
+		{"diagrams": map[interface{}]interface{}{"plantuml": map[interface{}]interface{}{"command": []interface{}{"id"}}}},
+	} {
+		_, err = site.markdown([]byte("Inline $a$\n"), "index.md", markup(v))
+		assert(t, err != nil, "Expected an error for a command in %v", v)
+		equals(t, Category(err), ParseError)
+	}
+}
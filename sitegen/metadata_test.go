@@ -0,0 +1,63 @@
+package sitegen
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMetadataProcessors(t *testing.T) {
+	defer func(old map[MetadataPhase][]MetadataProcessor) { processors = old }(processors)
+	processors = make(map[MetadataPhase][]MetadataProcessor)
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "post.md"), []byte("---\ntitle: Post\n---\nHi\n"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	AddMetadataProcessor(AfterParse, func(c *ContentItem) (interface{}, error) {
+		return []string{"parsed " + c.Metadata.Title}, nil
+	})
+	AddMetadataProcessor(AfterProcess, func(c *ContentItem) (interface{}, error) {
+		if c.Type != Content {
+			return c.Extra, nil
+		}
+		return append(c.Extra.([]string), "url "+c.Url), nil
+	})
+	AddMetadataProcessor(AfterProcess, func(c *ContentItem) (interface{}, error) {
+		if c.Type != Content {
+			return c.Extra, nil
+		}
+		return append(c.Extra.([]string), "last"), nil
+	})
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	page := root.child("post.html")
+	equals(t, []string{"parsed Post"}, page.Extra)
+
+	processError = nil
+	root.Process()
+	ok(t, processError)
+	equals(t, []string{"parsed Post", "url /post.html", "last"}, page.Extra)
+
+	// Replaces the processors that run after processing.
+	SetMetadataProcessor(func(c *ContentItem) (interface{}, error) {
+		return "only", nil
+	})
+	root.Process()
+	equals(t, "only", page.Extra)
+	equals(t, 1, len(processors[AfterParse]))
+
+	AddMetadataProcessor(AfterParse, func(c *ContentItem) (interface{}, error) {
+		return nil, errors.New("Broken")
+	})
+	_, err = site.crawlContent()
+	equals(t, "post.md: Broken", err.Error())
+}
@@ -0,0 +1,70 @@
+package sitegen
+
+import (
+	"path"
+	"strings"
+
+	"github.com/tdewolff/minify/v2"
+	"github.com/tdewolff/minify/v2/css"
+	"github.com/tdewolff/minify/v2/html"
+	"github.com/tdewolff/minify/v2/js"
+	"github.com/tdewolff/minify/v2/json"
+	"github.com/tdewolff/minify/v2/svg"
+	"github.com/tdewolff/minify/v2/xml"
+)
+
+// Formats that can be minified, with their media type.
+var minifyTypes = map[string]string{
+	"html": "text/html",
+	"css":  "text/css",
+	"js":   "application/javascript",
+	"json": "application/json",
+	"svg":  "image/svg+xml",
+	"xml":  "text/xml",
+}
+
+var minifyExtensions = map[string]string{
+	".html": "html",
+	".htm":  "html",
+	".css":  "css",
+	".js":   "js",
+	".mjs":  "js",
+	".json": "json",
+	".svg":  "svg",
+	".xml":  "xml",
+}
+
+func newMinifier() *minify.M {
+	m := minify.New()
+	m.AddFunc("text/html", html.Minify)
+	m.AddFunc("text/css", css.Minify)
+	m.AddFunc("application/javascript", js.Minify)
+	m.AddFunc("application/json", json.Minify)
+	m.AddFunc("image/svg+xml", svg.Minify)
+	m.AddFunc("text/xml", xml.Minify)
+	return m
+}
+
+// minifyFormat returns the format to minify the given file as, or "" if
+// minification isn't enabled for it.
+func (s *Site) minifyFormat(filename string) string {
+	format := minifyExtensions[strings.ToLower(path.Ext(filename))]
+	if !s.minifies(format) {
+		return ""
+	}
+	return format
+}
+
+// minify minifies data in the given format, if enabled.
+func (s *Site) minify(format string, data []byte) ([]byte, error) {
+	if !s.minifies(format) {
+		return data, nil
+	}
+	return s.minifier.Bytes(minifyTypes[format], data)
+}
+
+// minifies reports whether a format gets minified. Development builds are
+// left readable.
+func (s *Site) minifies(format string) bool {
+	return s.Config.Minify[format] && !s.isDevelopment()
+}
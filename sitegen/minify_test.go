@@ -0,0 +1,22 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	site := NewSite(DefaultConfig())
+
+	in := []byte("body {\n    color: red;\n}\n")
+	out, err := site.minify("css", in)
+	ok(t, err)
+	equals(t, string(out), string(in))
+	equals(t, site.minifyFormat("style.css"), "")
+
+	site.Config.Minify = map[string]bool{"css": true}
+	out, err = site.minify("css", in)
+	ok(t, err)
+	equals(t, string(out), "body{color:red}")
+	equals(t, site.minifyFormat("STYLE.CSS"), "css")
+	equals(t, site.minifyFormat("app.js"), "")
+}
@@ -0,0 +1,97 @@
+package sitegen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Used when there's no archetype for a new page.
+const defaultArchetype = `---
+title: "{{.Title}}"
+date: {{.Date}}
+---
+`
+
+// Archetype is the data available in archetype templates.
+type Archetype struct {
+	Title   string
+	Date    string // Current time, in the format used in front matter
+	Section string
+	Path    string // Path of the new file, relative to the content folder
+}
+
+// NewContent creates a content file from an archetype: archetypes/<section>.md
+// or archetypes/default.md, with a built-in default if neither exists. The
+// path is relative to the (first) content folder.
+func (s *Site) NewContent(p string) error {
+	if p == "" {
+		return categorize(ConfigError, errors.New("Usage: sitegen new <path>"))
+	}
+	p = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(p)), "/")
+	if !isContentFile(p) {
+		p += ".md"
+	}
+
+	filename := filepath.Join(s.Config.ContentDirs[0], filepath.FromSlash(p))
+	if _, err := os.Stat(filename); err == nil {
+		return fmt.Errorf("%s already exists", filename)
+	}
+
+	archetype, err := s.archetype(sectionName(p))
+	if err != nil {
+		return err
+	}
+	t, err := template.New(p).Parse(archetype)
+	if err != nil {
+		return categorize(TemplateError, err)
+	}
+
+	buf := &bytes.Buffer{}
+	err = t.Execute(buf, Archetype{
+		Title:   humanizeFilename(p),
+		Date:    time.Now().In(location()).Format("2006-01-02 15:04:05"),
+		Section: sectionName(p),
+		Path:    p,
+	})
+	if err != nil {
+		return categorize(TemplateError, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filename, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	log.Printf("==> Created %s\n", filename)
+	return nil
+}
+
+// archetype returns the template for new pages in a section.
+func (s *Site) archetype(section string) (string, error) {
+	names := []string{"default.md"}
+	if section != "" {
+		names = append([]string{section + ".md"}, names...)
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(s.Config.ArchetypeDir, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return defaultArchetype, nil
+}
@@ -0,0 +1,41 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.ArchetypeDir = filepath.Join(dir, "archetypes")
+	site := NewSite(config)
+
+	ok(t, site.NewContent("about"))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "content", "about.md"))
+	ok(t, err)
+	assert(t, strings.HasPrefix(string(data), "---\ntitle: \"About\"\ndate: "), "Unexpected page: %s", data)
+
+	// The new page can be read back.
+	c := &ContentItem{Site: site}
+	ok(t, c.parseContent(filepath.Join(dir, "content", "about.md")))
+	equals(t, c.Metadata.Title, "About")
+	assert(t, !c.Metadata.Date.IsZero(), "Expected date")
+
+	ok(t, os.MkdirAll(config.ArchetypeDir, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(config.ArchetypeDir, "blog.md"), []byte("---\ntitle: {{.Title}}\ntags: []\n---\nIn {{.Section}}\n"), 0644))
+	ok(t, site.NewContent("blog/2024-05-01-my_first-post.md"))
+	data, err = ioutil.ReadFile(filepath.Join(dir, "content", "blog", "2024-05-01-my_first-post.md"))
+	ok(t, err)
+	equals(t, string(data), "---\ntitle: My first post\ntags: []\n---\nIn blog\n")
+
+	assert(t, site.NewContent("about.md") != nil, "Expected error for existing file")
+	equals(t, Category(site.NewContent("")), ConfigError)
+}
@@ -0,0 +1,47 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// notFoundPage is the output of content/404.md (or 404.html), shown for
+// unknown URLs. It's left out of listings and feeds.
+const notFoundPage = "404.html"
+
+func isNotFoundPage(c *ContentItem) bool {
+	return c.Type == Content && c.OutputPath() == notFoundPage
+}
+
+// listedPages returns the pages that can show up in listings and feeds.
+func (c *ContentItem) listedPages() []*ContentItem {
+	pages := make([]*ContentItem, 0)
+	for _, page := range c.allPages() {
+		if !isNotFoundPage(page) {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// handler serves the output directory, using the 404 page for files that
+// don't exist.
+func (s *Site) handler() http.Handler {
+	files := http.FileServer(http.Dir(s.Config.OutputDir))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := filepath.Join(s.Config.OutputDir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+		if _, err := os.Stat(p); os.IsNotExist(err) {
+			page, err := ioutil.ReadFile(filepath.Join(s.Config.OutputDir, notFoundPage))
+			if err == nil {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write(page)
+				return
+			}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNotFoundPage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "404.md"), []byte("---\ntags: [a]\n---\nNot found"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "post.md"), []byte("---\ntags: [a]\n---\nPost"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Taxonomies["tags"] = &TaxonomyConfig{}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	assert(t, isNotFoundPage(root.child("404.html")), "Expected 404 page")
+	equals(t, len(root.listedPages()), 1)
+
+	site.buildTaxonomies(root)
+	equals(t, site.Taxonomies["tags"]["a"], []*ContentItem{root.child("post.html")})
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("Home"), 0644))
+
+	config := DefaultConfig()
+	config.OutputDir = dir
+	site := NewSite(config)
+	handler := site.handler()
+
+	get := func(p string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", p, nil))
+		return w
+	}
+
+	// Without a 404 page, the default response is used.
+	equals(t, get("/missing").Code, http.StatusNotFound)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, notFoundPage), []byte("Oops"), 0644))
+	w := get("/missing/page/")
+	equals(t, w.Code, http.StatusNotFound)
+	equals(t, w.Body.String(), "Oops")
+
+	w = get("/")
+	equals(t, w.Code, http.StatusOK)
+	equals(t, w.Body.String(), "Home")
+}
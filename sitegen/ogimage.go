@@ -0,0 +1,195 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// OGImageConfig configures the generated social card images.
+type OGImageConfig struct {
+	// Generate an image for every page that doesn't set an image in its
+	// front matter.
+	Enabled bool `yaml:"enabled"`
+
+	// Image the cards are drawn on (PNG or JPEG), scaled to fill them.
+	Background string `yaml:"background"`
+
+	// Colors, as #rrggbb. Background is used when there's no background
+	// image.
+	BackgroundColor string `yaml:"backgroundColor"`
+	TextColor       string `yaml:"textColor"`
+
+	// Text shown under the title, e.g. the name of the site.
+	Brand string `yaml:"brand"`
+}
+
+const (
+	ogImageWidth   = 1200
+	ogImageHeight  = 630
+	ogImagePadding = 80
+)
+
+// addOGImages adds a social card (name.og.png) next to every page.
+func (s *Site) addOGImages(root *ContentItem) error {
+	config := s.Config.OGImage
+	if !config.Enabled {
+		return nil
+	}
+
+	background, err := parseColor(config.BackgroundColor)
+	if err != nil {
+		return categorize(ConfigError, err)
+	}
+	text, err := parseColor(config.TextColor)
+	if err != nil {
+		return categorize(ConfigError, err)
+	}
+
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+				continue
+			}
+			if v.Type != Content || v.Metadata.String("image") != "" {
+				continue
+			}
+
+			page := v
+			name := strings.TrimSuffix(page.OutputPath(), path.Ext(page.OutputPath()))
+			page.ogImage = c.addGenerated(path.Base(name)+".og.png", Metadata{}, func() ([]byte, error) {
+				return s.renderOGImage(page.Metadata.Title, background, text)
+			})
+		}
+	}
+	walk(root)
+	return nil
+}
+
+// OGImage returns the URL of the image to show when the page is shared: the
+// image from the front matter, or the generated card.
+func (c *ContentItem) OGImage() string {
+	if img := c.Metadata.String("image"); img != "" {
+		return img
+	}
+	if c.ogImage != nil {
+		return c.ogImage.Url
+	}
+	return ""
+}
+
+func (s *Site) renderOGImage(title string, background, text color.Color) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, ogImageWidth, ogImageHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	if s.Config.OGImage.Background != "" {
+		f, err := os.Open(s.Config.OGImage.Background)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		bg, _, err := image.Decode(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Config.OGImage.Background, err)
+		}
+		draw.CatmullRom.Scale(img, img.Bounds(), bg, bg.Bounds(), draw.Over, nil)
+	}
+
+	titleFace, err := loadFace(gobold.TTF, 64)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	brandFace, err := loadFace(goregular.TTF, 32)
+	if err != nil {
+		return nil, err
+	}
+	defer brandFace.Close()
+
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(text), Face: titleFace}
+	lineHeight := titleFace.Metrics().Height.Ceil() + 8
+	y := ogImagePadding + titleFace.Metrics().Ascent.Ceil()
+	for _, line := range wrapText(d, title, ogImageWidth-2*ogImagePadding, 4) {
+		d.Dot = fixed.P(ogImagePadding, y)
+		d.DrawString(line)
+		y += lineHeight
+	}
+
+	if brand := s.Config.OGImage.Brand; brand != "" {
+		d.Face = brandFace
+		d.Dot = fixed.P(ogImagePadding, ogImageHeight-ogImagePadding)
+		d.DrawString(brand)
+	}
+
+	buf := &bytes.Buffer{}
+	err = png.Encode(buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func loadFace(ttf []byte, size float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// wrapText splits text into lines that fit in width pixels. Text that
+// doesn't fit in maxLines is cut off with an ellipsis.
+func wrapText(d *font.Drawer, text string, width, maxLines int) []string {
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && d.MeasureString(candidate).Ceil() > width {
+			lines = append(lines, line)
+			line = word
+		} else {
+			line = candidate
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1]
+		for d.MeasureString(last+"…").Ceil() > width {
+			i := strings.LastIndex(last, " ")
+			if i < 0 {
+				break
+			}
+			last = last[:i]
+		}
+		lines[maxLines-1] = last + "…"
+	}
+	return lines
+}
+
+// parseColor parses a #rrggbb color.
+func parseColor(s string) (color.Color, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 || s[0] != '#' {
+		return nil, fmt.Errorf("Invalid color: %s", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
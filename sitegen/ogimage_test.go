@@ -0,0 +1,61 @@
+package sitegen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestOGImages(t *testing.T) {
+	config := DefaultConfig()
+	config.OGImage.Enabled = true
+	config.OGImage.Brand = "example.com"
+	site := NewSite(config)
+
+	root := &ContentItem{Site: site, Type: Directory}
+	blog := root.ensureDir("blog")
+	post := &ContentItem{Site: site, Filename: "post.md", Path: "blog/post.md", Type: Content, Metadata: Metadata{Title: "A post"}}
+	own := &ContentItem{Site: site, Filename: "own.md", Path: "blog/own.md", Type: Content, Metadata: Metadata{
+		Title:  "Own image",
+		Params: map[string]interface{}{"image": "/img/own.png"},
+	}}
+	blog.Children = append(blog.Children, post, own)
+
+	ok(t, site.addOGImages(root))
+	root.Process()
+
+	equals(t, post.OGImage(), "/blog/post.og.png")
+	equals(t, own.OGImage(), "/img/own.png")
+	assert(t, blog.child("own.og.png") == nil, "Unexpected image for page with its own")
+
+	data, err := blog.child("post.og.png").generate()
+	ok(t, err)
+	img, format, err := image.Decode(bytes.NewReader(data))
+	ok(t, err)
+	equals(t, format, "png")
+	equals(t, img.Bounds().Size(), image.Pt(1200, 630))
+	r, g, b, _ := img.At(0, 0).RGBA()
+	equals(t, []uint32{r >> 8, g >> 8, b >> 8}, []uint32{0x1e, 0x29, 0x3b})
+}
+
+func TestWrapText(t *testing.T) {
+	// Every character is 7 pixels wide.
+	d := &font.Drawer{Face: basicfont.Face7x13}
+	equals(t, wrapText(d, "aa bb cc", 35, 3), []string{"aa bb", "cc"})
+	equals(t, wrapText(d, "aa bb cc dd ee ff gg", 35, 2), []string{"aa bb", "cc…"})
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := parseColor("#ff8000")
+	ok(t, err)
+	equals(t, c, color.Color(color.RGBA{0xff, 0x80, 0x00, 0xff}))
+
+	for _, in := range []string{"", "ff8000", "#fff", "#gggggg"} {
+		_, err = parseColor(in)
+		assert(t, err != nil, "Expected error for %q", in)
+	}
+}
@@ -0,0 +1,266 @@
+package sitegen
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// output is where a build writes the site: the output directory, or memory
+// for BuildFS. Paths are file paths in the output directory.
+type output interface {
+	MkdirAll(p string) error
+	WriteFile(p string, data []byte) error
+	CopyFile(src, p string, progress func(n int64)) error
+	ReadFile(p string) ([]byte, error)
+	Stat(p string) (fs.FileInfo, error)
+}
+
+func (s *Site) output() output {
+	if s.out == nil {
+		return diskOutput{}
+	}
+	return s.out
+}
+
+// inMemory reports whether the build writes to memory.
+func (s *Site) inMemory() bool {
+	_, ok := s.out.(*memoryOutput)
+	return ok
+}
+
+type diskOutput struct{}
+
+func (diskOutput) MkdirAll(p string) error {
+	return os.MkdirAll(p, 0755)
+}
+
+// WriteFile leaves files that already hold data alone, so their
+// modification time only changes when they do and deploy tools don't upload
+// them again. Other files are replaced rather than written to: they may be
+// hard links to a source file (see copyFile).
+func (diskOutput) WriteFile(p string, data []byte) error {
+	if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() && info.Size() == int64(len(data)) {
+		existing, err := ioutil.ReadFile(p)
+		if err == nil && bytes.Equal(existing, data) {
+			return nil
+		}
+	}
+	err := os.Remove(p)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return ioutil.WriteFile(p, data, 0644)
+}
+
+func (diskOutput) CopyFile(src, p string, progress func(n int64)) error {
+	return copyFile(src, p, progress)
+}
+
+func (diskOutput) ReadFile(p string) ([]byte, error) {
+	return ioutil.ReadFile(p)
+}
+
+func (diskOutput) Stat(p string) (fs.FileInfo, error) {
+	return os.Stat(p)
+}
+
+// memoryOutput keeps the files of a build in memory, keyed by their
+// slash-separated path relative to the output directory.
+type memoryOutput struct {
+	root  string
+	files memoryFS
+	lock  sync.Mutex
+}
+
+func newMemoryOutput(root string) *memoryOutput {
+	return &memoryOutput{
+		root: filepath.Clean(root),
+		files: memoryFS{
+			files: make(map[string][]byte),
+			dirs:  map[string]bool{".": true},
+		},
+	}
+}
+
+// name returns the path of a file in the output directory, as used in the
+// file system.
+func (m *memoryOutput) name(p string) (string, error) {
+	rel, err := filepath.Rel(m.root, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &fs.PathError{Op: "write", Path: p, Err: fs.ErrInvalid}
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (m *memoryOutput) MkdirAll(p string) error {
+	name, err := m.name(p)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for ; name != "."; name = path.Dir(name) {
+		m.files.dirs[name] = true
+	}
+	return nil
+}
+
+func (m *memoryOutput) WriteFile(p string, data []byte) error {
+	name, err := m.name(p)
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.files.files[name] = data
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		m.files.dirs[dir] = true
+	}
+	return nil
+}
+
+func (m *memoryOutput) CopyFile(src, p string, progress func(n int64)) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(int64(len(data)))
+	}
+	return m.WriteFile(p, data)
+}
+
+func (m *memoryOutput) ReadFile(p string) ([]byte, error) {
+	name, err := m.name(p)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return fs.ReadFile(m.files, name)
+}
+
+func (m *memoryOutput) Stat(p string) (fs.FileInfo, error) {
+	name, err := m.name(p)
+	if err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return fs.Stat(m.files, name)
+}
+
+// memoryFS is a read-only file system of files in memory.
+type memoryFS struct {
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func (m memoryFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if data, ok := m.files[name]; ok {
+		return &memoryFile{
+			Reader: bytes.NewReader(data),
+			info:   memoryFileInfo{name: path.Base(name), size: int64(len(data))},
+		}, nil
+	}
+	if !m.dirs[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	entries := make(map[string]fs.DirEntry)
+	for p, data := range m.files {
+		if rest := strings.TrimPrefix(p, prefix); rest != p || prefix == "" {
+			if !strings.Contains(rest, "/") {
+				entries[rest] = fs.FileInfoToDirEntry(memoryFileInfo{name: rest, size: int64(len(data))})
+			}
+		}
+	}
+	for p := range m.dirs {
+		if rest := strings.TrimPrefix(p, prefix); (rest != p || prefix == "") && p != "." {
+			if !strings.Contains(rest, "/") {
+				entries[rest] = fs.FileInfoToDirEntry(memoryFileInfo{name: rest, dir: true})
+			}
+		}
+	}
+	list := make([]fs.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return &memoryDir{
+		info:    memoryFileInfo{name: path.Base(name), dir: true},
+		entries: list,
+	}, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+	info memoryFileInfo
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memoryFile) Close() error               { return nil }
+
+type memoryDir struct {
+	info    memoryFileInfo
+	entries []fs.DirEntry
+	read    int
+}
+
+func (d *memoryDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memoryDir) Close() error               { return nil }
+
+func (d *memoryDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memoryDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.read:]
+	if n <= 0 {
+		d.read = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.read += n
+	return rest[:n], nil
+}
+
+type memoryFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memoryFileInfo) Name() string       { return i.name }
+func (i memoryFileInfo) Size() int64        { return i.size }
+func (i memoryFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memoryFileInfo) IsDir() bool        { return i.dir }
+func (i memoryFileInfo) Sys() interface{}   { return nil }
+
+func (i memoryFileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
@@ -0,0 +1,121 @@
+package sitegen
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMemoryOutput(t *testing.T) {
+	out := newMemoryOutput("static")
+	ok(t, out.MkdirAll(filepath.Join("static", "empty", "dir")))
+	ok(t, out.WriteFile(filepath.Join("static", "index.html"), []byte("home")))
+	ok(t, out.WriteFile(filepath.Join("static", "blog", "post", "index.html"), []byte("post")))
+	assert(t, out.WriteFile(filepath.Join("other", "index.html"), nil) != nil, "Expected an error outside the output")
+
+	data, err := out.ReadFile(filepath.Join("static", "blog", "post", "index.html"))
+	ok(t, err)
+	equals(t, "post", string(data))
+	info, err := out.Stat(filepath.Join("static", "blog"))
+	ok(t, err)
+	assert(t, info.IsDir(), "Expected a directory")
+	_, err = out.Stat(filepath.Join("static", "missing.html"))
+	assert(t, os.IsNotExist(err), "Expected a missing file")
+
+	ok(t, fstest.TestFS(out.files, "index.html", "blog/post/index.html", "empty/dir"))
+}
+
+func TestBuildFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "static")
+	config.CheckLinks = "error"
+	ok(t, os.MkdirAll(filepath.Join(config.ContentDirs[0], "css"), 0755))
+	ok(t, os.MkdirAll(config.TemplateDir, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "page.html"), []byte(`<link href="/css/site.css">{{.Content}}`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(config.ContentDirs[0], "index.md"), []byte("Hi"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(config.ContentDirs[0], "css", "site.css"), []byte("a{}"), 0644))
+
+	site, err := NewSite(config).BuildFS()
+	ok(t, err)
+	data, err := fs.ReadFile(site, "index.html")
+	ok(t, err)
+	equals(t, "<link href=\"/css/site.css\"><p>Hi</p>\n", string(data))
+	data, err = fs.ReadFile(site, "css/site.css")
+	ok(t, err)
+	equals(t, "a{}", string(data))
+
+	_, err = os.Stat(config.OutputDir)
+	assert(t, os.IsNotExist(err), "Expected nothing on disk")
+}
+
+func TestDiskOutputSkipsUnchanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "index.html")
+	out := diskOutput{}
+	ok(t, out.WriteFile(file, []byte("hello")))
+	past := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ok(t, os.Chtimes(file, past, past))
+
+	ok(t, out.WriteFile(file, []byte("hello")))
+	info, err := os.Stat(file)
+	ok(t, err)
+	equals(t, info.ModTime().UTC(), past)
+
+	ok(t, out.WriteFile(file, []byte("world")))
+	info, err = os.Stat(file)
+	ok(t, err)
+	assert(t, info.ModTime().After(past), "Expected new modification time")
+	data, err := ioutil.ReadFile(file)
+	ok(t, err)
+	equals(t, string(data), "world")
+}
+
+func TestRebuildOverHardLink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "static")
+	ok(t, os.MkdirAll(config.ContentDirs[0], 0755))
+	ok(t, os.MkdirAll(config.TemplateDir, 0755))
+	source := filepath.Join(config.ContentDirs[0], "style.css")
+	ok(t, ioutil.WriteFile(source, []byte("body {\n  color: red;\n}\n"), 0644))
+
+	ok(t, NewSite(config).Build())
+
+	// The asset is now linked (or copied) into the output, minifying it
+	// must not write through to the source.
+	config.Minify = map[string]bool{"css": true}
+	ok(t, NewSite(config).Build())
+
+	data, err := ioutil.ReadFile(source)
+	ok(t, err)
+	equals(t, "body {\n  color: red;\n}\n", string(data))
+	data, err = ioutil.ReadFile(filepath.Join(config.OutputDir, "style.css"))
+	ok(t, err)
+	equals(t, "body{color:red}", string(data))
+
+	// Nor does copying another file over it.
+	ok(t, ioutil.WriteFile(filepath.Join(config.ContentDirs[0], "other.css"), []byte("a{}"), 0644))
+	ok(t, os.Link(filepath.Join(config.ContentDirs[0], "other.css"), filepath.Join(config.OutputDir, "copy.css")))
+	ok(t, copyFile(source, filepath.Join(config.OutputDir, "copy.css"), nil))
+	data, err = ioutil.ReadFile(filepath.Join(config.ContentDirs[0], "other.css"))
+	ok(t, err)
+	equals(t, "a{}", string(data))
+}
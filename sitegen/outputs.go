@@ -0,0 +1,148 @@
+package sitegen
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"text/template/parse"
+)
+
+// Extensions of the output formats, the others use their name (json).
+var outputExtensions = map[string]string{
+	"amp": "amp.html",
+}
+
+func outputExtension(format string) string {
+	if ext, ok := outputExtensions[format]; ok {
+		return ext
+	}
+	return format
+}
+
+// isHTMLFormat tells whether an output format is rendered with the HTML
+// templates, rather than as text.
+func isHTMLFormat(format string) bool {
+	return format == "html" || strings.HasSuffix(outputExtension(format), ".html")
+}
+
+// outputFormats returns the output formats of a page: from the outputs in
+// its front matter or the configuration, html otherwise.
+func (c *ContentItem) outputFormats() []string {
+	if formats := c.Metadata.Strings("outputs"); len(formats) > 0 {
+		return formats
+	}
+	if len(c.Site.Config.Outputs) > 0 {
+		return c.Site.Config.Outputs
+	}
+	return []string{"html"}
+}
+
+func (c *ContentItem) hasOutputFormat(format string) bool {
+	for _, v := range c.outputFormats() {
+		if v == format {
+			return true
+		}
+	}
+	return false
+}
+
+// addOutputs adds the other output formats of each page next to it, e.g.
+// post.json next to post.html.
+func (s *Site) addOutputs(root *ContentItem) {
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+				continue
+			}
+			if v.Type != Content {
+				continue
+			}
+
+			page := v
+			base := strings.TrimSuffix(path.Base(page.OutputPath()), ".html")
+			for _, format := range page.outputFormats() {
+				if format == "html" {
+					continue
+				}
+
+				format := format
+				item := c.addGenerated(base+"."+outputExtension(format), Metadata{}, func() ([]byte, error) {
+					return page.renderFormat(format)
+				})
+				if page.outputs == nil {
+					page.outputs = make(map[string]*ContentItem)
+				}
+				page.outputs[format] = item
+			}
+		}
+	}
+	walk(root)
+}
+
+// Output returns the URL of the page in another output format (e.g. for a
+// link to the AMP version), or "" if it doesn't have that format.
+func (c *ContentItem) Output(format string) string {
+	if format == "html" && c.hasOutputFormat("html") {
+		return c.Url
+	}
+	if item, ok := c.outputs[format]; ok {
+		return "/" + item.OutputPath()
+	}
+	return ""
+}
+
+// render executes the template of the page for an output format.
+func (c *ContentItem) render(format string) ([]byte, error) {
+	name, err := c.Site.templateForFormat(c, format)
+	if err != nil {
+		return nil, categorize(TemplateError, err)
+	}
+	c.trackLookup(format, name)
+
+	buf := &bytes.Buffer{}
+	if isHTMLFormat(format) {
+		c.trackTemplates(templateFiles(func(name string) *parse.Tree {
+			if t := c.Site.templates.Lookup(name); t != nil {
+				return t.Tree
+			}
+			return nil
+		}, name))
+		err = c.Site.templates.ExecuteTemplate(buf, name, c)
+	} else {
+		c.trackTemplates(templateFiles(func(name string) *parse.Tree {
+			if t := c.Site.textTemplates.Lookup(name); t != nil {
+				return t.Tree
+			}
+			return nil
+		}, name))
+		err = c.Site.textTemplates.ExecuteTemplate(buf, name, c)
+	}
+	if err != nil {
+		return nil, categorize(TemplateError, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderFormat renders the page in an output format other than html. The
+// txt format has a built-in version for pages without a template for it.
+func (c *ContentItem) renderFormat(format string) ([]byte, error) {
+	if format == "txt" {
+		if _, err := c.Site.templateForFormat(c, format); err != nil {
+			c.trackLookup(format, "")
+			return c.plainText()
+		}
+	}
+
+	data, err := c.render(format)
+	if err != nil || !isHTMLFormat(format) {
+		return data, err
+	}
+
+	html, err := highlightCode(c.OutputPath(), string(data))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(html), nil
+}
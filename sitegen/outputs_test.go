@@ -0,0 +1,73 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	templates := filepath.Join(dir, "templates")
+	ok(t, os.MkdirAll(content, 0755))
+	ok(t, os.MkdirAll(templates, 0755))
+
+	files := map[string]string{
+		"content/post.md":           "---\ntitle: Fish & chips\noutputs: [html, json, amp]\n---\nHello",
+		"content/api.md":            "---\ntitle: API\noutputs: json\n---\nData",
+		"content/about.md":          "About",
+		"templates/single.html":     `<h1>{{.Metadata.Title}}</h1>`,
+		"templates/single.json":     `{"title": {{jsonify .Metadata.Title}}, "url": "{{.Url}}"}`,
+		"templates/single.amp.html": `<html amp>{{.Metadata.Title}}</html>`,
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.TemplateDir = templates
+	site := NewSite(config)
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+	site.textTemplates, err = site.loadTextTemplates()
+	ok(t, err)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.addOutputs(root)
+	root.Process()
+
+	post := root.child("post.html")
+	equals(t, post.Output("html"), "/post.html")
+	equals(t, post.Output("json"), "/post.json")
+	equals(t, post.Output("amp"), "/post.amp.html")
+	equals(t, post.Output("xml"), "")
+
+	data, err := root.child("post.json").generate()
+	ok(t, err)
+	equals(t, string(data), `{"title": "Fish \u0026 chips", "url": "/post.html"}`)
+
+	data, err = root.child("post.amp.html").generate()
+	ok(t, err)
+	equals(t, string(data), `<html amp>Fish &amp; chips</html>`)
+
+	api := root.child("api.html")
+	equals(t, api.Output("html"), "")
+	equals(t, api.Output("json"), "/api.json")
+	out := filepath.Join(dir, "api.html")
+	ok(t, api.write(out))
+	_, err = os.Stat(out)
+	assert(t, os.IsNotExist(err), "Unexpected html output")
+
+	equals(t, root.child("about.json"), (*ContentItem)(nil))
+
+	_, err = root.child("about.html").render("xml")
+	assert(t, err != nil, "Expected missing template")
+	equals(t, Category(err), TemplateError)
+}
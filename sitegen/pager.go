@@ -0,0 +1,98 @@
+package sitegen
+
+import (
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Pager is one page of a listing that is split over multiple pages.
+type Pager struct {
+	// Page number, starting at 1.
+	Number int
+
+	// Items shown on this page.
+	Items []*ContentItem
+
+	// All pages of the listing.
+	Pages []*ContentItem
+}
+
+func (p *Pager) TotalPages() int {
+	return len(p.Pages)
+}
+
+func (p *Pager) First() *ContentItem {
+	return p.Pages[0]
+}
+
+func (p *Pager) Last() *ContentItem {
+	return p.Pages[len(p.Pages)-1]
+}
+
+// Prev returns the previous page, or nil on the first page.
+func (p *Pager) Prev() *ContentItem {
+	if p.Number <= 1 {
+		return nil
+	}
+	return p.Pages[p.Number-2]
+}
+
+// Next returns the next page, or nil on the last page.
+func (p *Pager) Next() *ContentItem {
+	if p.Number >= len(p.Pages) {
+		return nil
+	}
+	return p.Pages[p.Number]
+}
+
+// paginate adds a listing of items to dir, perPage items at a time. The first
+// page becomes dir/index.html, the others dir/page/N/index.html. A perPage
+// of 0 puts everything on a single page.
+func (s *Site) paginate(dir *ContentItem, metadata Metadata, items []*ContentItem, perPage int) {
+	if dir.child("index.html") != nil {
+		// Don't overwrite a page that was written by hand.
+		return
+	}
+
+	chunks := make([][]*ContentItem, 0)
+	for perPage > 0 && len(items) > perPage {
+		chunks = append(chunks, items[:perPage])
+		items = items[perPage:]
+	}
+	chunks = append(chunks, items)
+
+	pages := make([]*ContentItem, len(chunks))
+	for i, chunk := range chunks {
+		parent := dir
+		if i > 0 {
+			parent = dir.ensureDir("page").ensureDir(strconv.Itoa(i + 1))
+		}
+
+		page := &ContentItem{
+			Site:     s,
+			Filename: "index.html",
+			Path:     path.Join(parent.Path, "index.html"),
+			Type:     Content,
+			Metadata: metadata,
+			Pager: &Pager{
+				Number: i + 1,
+				Items:  chunk,
+				Pages:  pages,
+			},
+		}
+		pages[i] = page
+		parent.Children = append(parent.Children, page)
+	}
+}
+
+// byDate sorts items, newest first.
+type byDate []*ContentItem
+
+func (b byDate) Len() int           { return len(b) }
+func (b byDate) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byDate) Less(i, j int) bool { return b[i].Metadata.Date.After(b[j].Metadata.Date) }
+
+func sortByDate(items []*ContentItem) {
+	sort.Stable(byDate(items))
+}
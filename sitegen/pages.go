@@ -0,0 +1,59 @@
+package sitegen
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"strings"
+)
+
+// addedPage is a page added with AddPage.
+type addedPage struct {
+	path     string
+	metadata Metadata
+	body     []byte
+}
+
+// AddPage adds a page that doesn't exist as a file to the site, e.g. one
+// computed from an API. The path is relative to the content folder and works
+// like that of a content file: blog/archive.md is rendered from Markdown and
+// written to blog/archive.html, with a .html extension the body is used as is.
+//
+// Pages are added on every Build, so call this before building.
+func (s *Site) AddPage(p string, metadata Metadata, body []byte) {
+	s.addedPages = append(s.addedPages, addedPage{
+		path:     strings.Trim(path.Clean("/"+p), "/"),
+		metadata: metadata,
+		body:     body,
+	})
+}
+
+// addPages adds the pages from AddPage to the tree.
+func (s *Site) addPages(root *ContentItem) error {
+	for _, v := range s.addedPages {
+		if !isContentFile(v.path) {
+			return fmt.Errorf("%s: not a content file", v.path)
+		}
+
+		content, err := s.renderContent(formatFor(v.path), replaceRefShortcodes(v.body), v.path, v.metadata)
+		if err != nil {
+			return err
+		}
+
+		out := strings.TrimSuffix(v.path, path.Ext(v.path)) + ".html"
+		page := &ContentItem{
+			Site:       s,
+			FullPath:   v.path,
+			Path:       v.path,
+			Type:       Content,
+			Content:    template.HTML(s.sanitize(v.path, content)),
+			Metadata:   v.metadata,
+			source:     v.body,
+			sourcePath: v.path,
+		}
+		if existing := root.place(out, page); existing != nil {
+			return fmt.Errorf("%s: conflicts with %s", v.path, existing.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddPage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(filepath.Join(content, "blog"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "blog", "post.md"), []byte("Post"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	site := NewSite(config)
+	site.AddPage("blog/archive.md", Metadata{Title: "Archive", Template: "archive"}, []byte("# Archive\n\nAll *posts*."))
+	site.AddPage("/status.html", Metadata{Title: "Status"}, []byte("<p>All good</p>"))
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.addPages(root))
+	root.Process()
+
+	archive := root.child("blog").child("archive.html")
+	assert(t, archive != nil, "Expected archive page")
+	equals(t, archive.Path, "blog/archive.md")
+	equals(t, archive.Url, "/blog/archive.html")
+	equals(t, archive.Metadata.Title, "Archive")
+	equals(t, string(archive.Content), "<h1>Archive</h1>\n\n<p>All <em>posts</em>.</p>\n")
+
+	status := root.child("status.html")
+	equals(t, string(status.Content), "<p>All good</p>")
+
+	site.AddPage("blog/post.html", Metadata{}, nil)
+	root, err = site.crawlContent()
+	ok(t, err)
+	assert(t, site.addPages(root) != nil, "Expected conflict")
+}
@@ -0,0 +1,72 @@
+package sitegen
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"sync"
+)
+
+// parseFiles parses the content files in the tree, with as many at the same
+// time as there are workers. The metadata processors then run on the pages
+// one by one, in the order of the tree, as does error reporting: the error
+// of the first page that failed is returned.
+func (s *Site) parseFiles(root *ContentItem) error {
+	log.Println("==> Parsing")
+	pages := root.contentFiles()
+	errs := make([]error, len(pages))
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				page := pages[i]
+				errs[i] = page.parseContent(page.FullPath)
+				s.inferDate(page)
+				s.inferTitle(page)
+			}
+		}()
+	}
+	for i := range pages {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i, page := range pages {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		err := runMetadataProcessors(AfterParse, page)
+		if err != nil {
+			return fmt.Errorf("%s: %w", page.sourcePath, err)
+		}
+	}
+	return nil
+}
+
+// workers returns the number of content files parsed at the same time.
+func (s *Site) workers() int {
+	if s.Config.Workers > 0 {
+		return s.Config.Workers
+	}
+	return runtime.NumCPU()
+}
+
+// contentFiles returns the content items below c that were read from a file,
+// in the order of the tree.
+func (c *ContentItem) contentFiles() []*ContentItem {
+	result := make([]*ContentItem, 0)
+	for _, v := range c.Children {
+		switch {
+		case v.Type == Directory:
+			result = append(result, v.contentFiles()...)
+		case v.Type == Content && v.FullPath != "":
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package sitegen
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 20; i++ {
+		file := filepath.Join(dir, "posts", fmt.Sprintf("post-%02d.md", i))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte(fmt.Sprintf("# Post %d\n\nHello\n", i)), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Workers = 4
+	site := NewSite(config)
+
+	order := make([]string, 0)
+	defer func(saved map[MetadataPhase][]MetadataProcessor) { processors = saved }(processors)
+	processors = make(map[MetadataPhase][]MetadataProcessor)
+	AddMetadataProcessor(AfterParse, func(c *ContentItem) (interface{}, error) {
+		order = append(order, c.Metadata.Title)
+		return nil, nil
+	})
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	pages := root.contentFiles()
+	equals(t, len(pages), 20)
+	for i, page := range pages {
+		equals(t, page.Metadata.Title, fmt.Sprintf("Post %d", i))
+		equals(t, order[i], page.Metadata.Title)
+		assert(t, strings.Contains(string(page.Content), "Hello"), "Expected content: %s", page.Content)
+	}
+
+	// The first failing page, in the order of the tree, is reported.
+	AddMetadataProcessor(AfterParse, func(c *ContentItem) (interface{}, error) {
+		if c.Metadata.Title == "Post 3" || c.Metadata.Title == "Post 12" {
+			return nil, errors.New("Failed")
+		}
+		return nil, nil
+	})
+	_, err = site.crawlContent()
+	assert(t, err != nil, "Expected error")
+	equals(t, err.Error(), "posts/post-03.md: Failed")
+}
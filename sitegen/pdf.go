@@ -0,0 +1,141 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PDFConfig configures a tool that turns pages into PDFs after they're
+// rendered, e.g. wkhtmltopdf or headless Chromium.
+type PDFConfig struct {
+	// Command to run. {file} is replaced by the generated HTML file, {url}
+	// by the URL of the page and {output} by the PDF to write. For combined
+	// PDFs, an argument that's just {file} is repeated for every page.
+	Command []string `yaml:"command"`
+
+	// Pages that get a PDF next to them (page.html gets page.pdf), as glob
+	// patterns matched against their path in the content folder.
+	Pages []string `yaml:"pages"`
+
+	// PDFs with several pages in them.
+	Combined []CombinedPDF `yaml:"combined"`
+}
+
+// CombinedPDF is a single PDF made from several pages.
+type CombinedPDF struct {
+	// Path of the PDF, relative to the output folder.
+	Output string `yaml:"output"`
+
+	// Pages to include, as glob patterns matched against their path in the
+	// content folder. Pages are included in the order of the site.
+	Pages []string `yaml:"pages"`
+}
+
+// PDF returns the URL of the PDF version of the page, or "" if it doesn't
+// have one.
+func (c *ContentItem) PDF() string {
+	if !c.Site.exportsPDF(c) {
+		return ""
+	}
+	return "/" + pdfPath(c)
+}
+
+func (s *Site) exportsPDF(c *ContentItem) bool {
+	return len(s.Config.PDF.Command) > 0 && matchesAny(s.Config.PDF.Pages, c.Path)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func pdfPath(c *ContentItem) string {
+	return strings.TrimSuffix(c.OutputPath(), path.Ext(c.OutputPath())) + ".pdf"
+}
+
+// exportPDFs runs the PDF command for the pages that should have one and
+// for the combined PDFs.
+func (s *Site) exportPDFs() error {
+	config := s.Config.PDF
+	if len(config.Command) == 0 {
+		return nil
+	}
+
+	log.Println("==> Exporting PDFs")
+	pages := s.root.allPages()
+	for _, c := range pages {
+		if !s.exportsPDF(c) || !c.hasOutputFormat("html") {
+			continue
+		}
+		err := s.runPDFCommand(pdfPath(c), c.Url, []*ContentItem{c})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, combined := range config.Combined {
+		included := make([]*ContentItem, 0)
+		for _, c := range pages {
+			if matchesAny(combined.Pages, c.Path) && c.hasOutputFormat("html") {
+				included = append(included, c)
+			}
+		}
+		if len(included) == 0 {
+			return fmt.Errorf("No pages for %s", combined.Output)
+		}
+		err := s.runPDFCommand(combined.Output, "", included)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPDFCommand writes the PDF at out (relative to the output folder) from
+// the given pages.
+func (s *Site) runPDFCommand(out, url string, pages []*ContentItem) error {
+	files := make([]string, len(pages))
+	for i, c := range pages {
+		file, err := filepath.Abs(filepath.Join(s.Config.OutputDir, filepath.FromSlash(c.OutputPath())))
+		if err != nil {
+			return err
+		}
+		files[i] = file
+	}
+
+	output := filepath.Join(s.Config.OutputDir, filepath.FromSlash(out))
+	err := os.MkdirAll(filepath.Dir(output), 0755)
+	if err != nil {
+		return err
+	}
+
+	replacer := strings.NewReplacer("{file}", files[0], "{url}", url, "{output}", output)
+	args := make([]string, 0, len(s.Config.PDF.Command))
+	for _, arg := range s.Config.PDF.Command {
+		if arg == "{file}" {
+			args = append(args, files...)
+		} else {
+			args = append(args, replacer.Replace(arg))
+		}
+	}
+
+	log.Printf(" -> /%s\n", out)
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("PDF export of %s failed: %s\n%s", out, err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportPDFs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	output := filepath.Join(dir, "output")
+	ok(t, os.MkdirAll(filepath.Join(content, "docs"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(output, "docs"), 0755))
+	for _, name := range []string{"docs/a.md", "docs/b.md", "about.md"} {
+		ok(t, ioutil.WriteFile(filepath.Join(content, filepath.FromSlash(name)), []byte(name), 0644))
+		html := name[:len(name)-3] + ".html"
+		ok(t, ioutil.WriteFile(filepath.Join(output, filepath.FromSlash(html)), []byte("<"+html+">"), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.OutputDir = output
+	config.PDF = PDFConfig{
+		// Stands in for a real converter: concatenates the pages.
+		Command: []string{"sh", "-c", `cat "$@" > "$0"`, "{output}", "{file}"},
+		Pages:   []string{"docs/*.md"},
+		Combined: []CombinedPDF{
+			{Output: "manual.pdf", Pages: []string{"docs/**", "about.md"}},
+		},
+	}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	root.Process()
+	site.root = root
+
+	equals(t, root.child("docs").child("a.html").PDF(), "/docs/a.pdf")
+	equals(t, root.child("about.html").PDF(), "")
+
+	ok(t, site.exportPDFs())
+
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(output, filepath.FromSlash(name)))
+		ok(t, err)
+		return string(data)
+	}
+	equals(t, read("docs/a.pdf"), "<docs/a.html>")
+	equals(t, read("docs/b.pdf"), "<docs/b.html>")
+	equals(t, read("manual.pdf"), "<about.html><docs/a.html><docs/b.html>")
+	_, err = os.Stat(filepath.Join(output, "about.pdf"))
+	assert(t, os.IsNotExist(err), "Unexpected PDF")
+
+	config.PDF.Command = []string{"false"}
+	assert(t, site.exportPDFs() != nil, "Expected failure")
+}
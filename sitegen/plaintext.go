@@ -0,0 +1,83 @@
+package sitegen
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	blockEndRegex = regexp.MustCompile(`(?i)</(?:p|div|h[1-6]|li|ul|ol|pre|blockquote|table|tr)>|<br\s*/?>`)
+	blankRegex    = regexp.MustCompile(`\n{3,}`)
+
+	// Any shortcode ({{< name ... >}} or {{% name ... %}}), for those plain
+	// text has no use for.
+	shortcodeRegex = regexp.MustCompile(`(?s)\{\{[<%].*?[>%]\}\}`)
+)
+
+// plainText returns the built-in text version of a page, for the txt output
+// format when there's no template for it: the title followed by the body as
+// written (e.g. Markdown), or with the tags stripped for HTML pages.
+// Shortcodes are expanded: includes to the file, references to the URL of
+// the page. Others are left out.
+func (c *ContentItem) plainText() ([]byte, error) {
+	source, err := c.expandShortcodes(c.source)
+	if err != nil {
+		return nil, err
+	}
+
+	var body string
+	if path.Ext(c.Path) == ".html" {
+		body = htmlToText(string(source))
+	} else {
+		body = string(source)
+	}
+	body = strings.TrimSpace(strings.Replace(body, "\r\n", "\n", -1))
+
+	title := c.Metadata.Title
+	firstLine := strings.SplitN(body, "\n", 2)[0]
+	if title == "" || strings.TrimSpace(strings.TrimLeft(firstLine, "#")) == title {
+		return []byte(body + "\n"), nil
+	}
+	return []byte(title + "\n" + strings.Repeat("=", len([]rune(title))) + "\n\n" + body + "\n"), nil
+}
+
+// expandShortcodes replaces the shortcodes in the source of a page with
+// what they stand for in plain text.
+func (c *ContentItem) expandShortcodes(source []byte) ([]byte, error) {
+	if path.Ext(c.Path) != ".html" {
+		var err error
+		source, err = c.Site.includeFiles(c.sourcePath, source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var refErr error
+	source = refShortcodeRegex.ReplaceAllFunc(source, func(m []byte) []byte {
+		parts := refShortcodeRegex.FindSubmatch(m)
+		u, err := c.Site.ref(c, string(parts[2]))
+		if err != nil && refErr == nil {
+			refErr = fmt.Errorf("%s: %w", c.FullPath, err)
+		}
+		return []byte(u)
+	})
+	if refErr != nil {
+		return nil, refErr
+	}
+	return shortcodeRegex.ReplaceAll(source, nil), nil
+}
+
+// htmlToText strips the tags from HTML, keeping line breaks between blocks.
+func htmlToText(in string) string {
+	text := blockEndRegex.ReplaceAllString(in, "$0\n\n")
+	text = html.UnescapeString(tagRegex.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return blankRegex.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
@@ -0,0 +1,74 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	c := &ContentItem{
+		Path:     "blog/post.md",
+		Metadata: Metadata{Title: "My post"},
+		source:   []byte("\nSome *text*.\r\n\n- One\n- Two\n"),
+	}
+	data, err := c.plainText()
+	ok(t, err)
+	equals(t, string(data), "My post\n=======\n\nSome *text*.\n\n- One\n- Two\n")
+
+	c.source = []byte("# My post\n\nHello\n")
+	data, err = c.plainText()
+	ok(t, err)
+	equals(t, string(data), "# My post\n\nHello\n")
+
+	c = &ContentItem{
+		Path:     "about.html",
+		Metadata: Metadata{Title: "About"},
+		source:   []byte("<p>Fish &amp;\n   chips</p><ul><li>One</li><li>Two</li></ul>"),
+	}
+	data, err = c.plainText()
+	ok(t, err)
+	equals(t, string(data), "About\n=====\n\nFish &\nchips\n\nOne\n\nTwo\n")
+}
+
+func TestPlainTextOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	templates := filepath.Join(dir, "templates")
+	ok(t, os.MkdirAll(filepath.Join(content, "notes"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(templates, "notes"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "post.md"), []byte("---\ntitle: Post\n---\nHello *world*\n\nSee {{< ref \"notes/note.md\" >}}.\n{{< youtube id=\"abc\" >}}\n"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "notes", "note.md"), []byte("---\ntitle: Note\n---\nText"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(templates, "single.html"), []byte("{{.Content}}"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(templates, "notes", "single.txt"), []byte("Note: {{.Metadata.Title}}"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.TemplateDir = templates
+	config.Outputs = []string{"html", "txt"}
+	config.BaseURL = "https://example.com/"
+	site := NewSite(config)
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+	site.textTemplates, err = site.loadTextTemplates()
+	ok(t, err)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.addOutputs(root)
+	root.Process()
+	site.root = root
+
+	// References become URLs, other shortcodes are left out.
+	data, err := root.child("post.txt").generate()
+	ok(t, err)
+	equals(t, string(data), "Post\n====\n\nHello *world*\n\nSee https://example.com/notes/note.html.\n")
+
+	data, err = root.child("notes").child("note.txt").generate()
+	ok(t, err)
+	equals(t, string(data), "Note: Note")
+}
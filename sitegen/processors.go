@@ -0,0 +1,82 @@
+package sitegen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// AssetProcessor is a custom transformation of assets, for use by programs
+// that embed sitegen.
+type AssetProcessor interface {
+	// Match reports whether the processor handles the asset at the given
+	// path, relative to the content directory.
+	Match(path string) bool
+
+	// Process transforms the contents of an asset. The returned extension
+	// (e.g. ".css") replaces the one of the asset, leave it empty to keep
+	// the filename.
+	Process(in []byte) ([]byte, string, error)
+}
+
+var assetProcessors []AssetProcessor
+
+// RegisterAssetProcessor adds a processor for assets. When several match an
+// asset, the one registered first is used.
+func RegisterAssetProcessor(p AssetProcessor) {
+	assetProcessors = append(assetProcessors, p)
+}
+
+func assetProcessorFor(p string) AssetProcessor {
+	for _, v := range assetProcessors {
+		if v.Match(p) {
+			return v
+		}
+	}
+	return nil
+}
+
+// processAssets runs the registered asset processors. This happens before
+// anything else looks at the assets, as processors can rename them.
+func (s *Site) processAssets(root *ContentItem) error {
+	if len(assetProcessors) == 0 {
+		return nil
+	}
+
+	var walk func(c *ContentItem) error
+	walk = func(c *ContentItem) error {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				err := walk(v)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if v.Type != Asset {
+				continue
+			}
+
+			p := assetProcessorFor(v.Path)
+			if p == nil {
+				continue
+			}
+
+			in, err := ioutil.ReadFile(v.FullPath)
+			if err != nil {
+				return err
+			}
+			out, ext, err := p.Process(in)
+			if err != nil {
+				return fmt.Errorf("%s: %w", v.FullPath, err)
+			}
+			v.processed = out
+			if ext != "" {
+				v.Filename = strings.TrimSuffix(v.Filename, path.Ext(v.Filename)) + ext
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
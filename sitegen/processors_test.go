@@ -0,0 +1,47 @@
+package sitegen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+type upperProcessor struct{}
+
+func (upperProcessor) Match(p string) bool {
+	return path.Ext(p) == ".up"
+}
+
+func (upperProcessor) Process(in []byte) ([]byte, string, error) {
+	return bytes.ToUpper(in), ".txt", nil
+}
+
+func TestAssetProcessor(t *testing.T) {
+	defer func(old []AssetProcessor) { assetProcessors = old }(assetProcessors)
+	RegisterAssetProcessor(upperProcessor{})
+
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "shout.up"), []byte("hello"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.processAssets(root))
+
+	asset := root.child("shout.txt")
+	assert(t, asset != nil, "Expected asset to be renamed")
+	assert(t, site.transformsAsset(asset), "Expected processed asset to be transformed")
+
+	data, err := site.assetData(asset)
+	ok(t, err)
+	equals(t, string(data), "HELLO")
+}
@@ -0,0 +1,234 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"path"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+// redirectsFile is read from the root of the content directories.
+const redirectsFile = "_redirects.yaml"
+
+// Redirect sends visitors of one URL to another one.
+type Redirect struct {
+	From   string `yaml:"from"`
+	To     string `yaml:"to"`
+	Status int    `yaml:"status"`
+}
+
+var redirectBackends = map[string]func(s *Site, root *ContentItem){
+	"meta":    (*Site).addMetaRedirects,
+	"netlify": (*Site).addNetlifyRedirects,
+	"nginx":   (*Site).addNginxRedirects,
+}
+
+// loadRedirects reads the redirects file, if any, and takes it out of the
+// tree so it doesn't get copied to the output.
+func (s *Site) loadRedirects(root *ContentItem) error {
+	s.redirects = nil
+	for i, v := range root.Children {
+		if v.Filename != redirectsFile || v.Type != Asset {
+			continue
+		}
+		root.Children = append(root.Children[:i], root.Children[i+1:]...)
+
+		data, err := ioutil.ReadFile(v.FullPath)
+		if err != nil {
+			return err
+		}
+
+		redirects := make([]Redirect, 0)
+		err = yaml.Unmarshal(data, &redirects)
+		if err != nil {
+			return fmt.Errorf("%s: %w", v.FullPath, err)
+		}
+		for _, r := range redirects {
+			if r.Status == 0 {
+				r.Status = 301
+			}
+			s.redirects = append(s.redirects, r)
+		}
+		return nil
+	}
+	return nil
+}
+
+// checkRedirects verifies the redirects against the generated URLs: sources
+// shouldn't hide existing pages and local targets need to exist.
+func (s *Site) checkRedirects(root *ContentItem) error {
+	urls := root.urlSet()
+	for _, r := range s.redirects {
+		switch r.Status {
+		case 301, 302, 303, 307, 308:
+		default:
+			return categorize(ParseError, fmt.Errorf("Redirect %s: invalid status %d", r.From, r.Status))
+		}
+		err := checkRedirectPaths(r)
+		if err != nil {
+			return err
+		}
+		if urls[r.From] {
+			return fmt.Errorf("Redirect %s: source is an existing page", r.From)
+		}
+		if isLocalURL(r.To) && !urls[stripFragment(r.To)] {
+			return fmt.Errorf("Redirect %s: target %s does not exist", r.From, r.To)
+		}
+	}
+	return nil
+}
+
+// checkRedirectPaths verifies that a redirect stays within the output
+// directory and can be written to the redirect files as is: the source is a
+// clean path and neither the source nor the target holds whitespace or ;.
+func checkRedirectPaths(r Redirect) error {
+	if !strings.HasPrefix(r.From, "/") {
+		return categorize(ParseError, fmt.Errorf("Redirect %s: source should start with /", r.From))
+	}
+	clean := path.Clean(r.From)
+	if strings.HasSuffix(r.From, "/") && clean != "/" {
+		clean += "/"
+	}
+	if clean != r.From {
+		return categorize(ParseError, fmt.Errorf("Redirect %s: source should be a clean path (%s)", r.From, clean))
+	}
+	for _, v := range []string{r.From, r.To} {
+		if strings.IndexFunc(v, unicode.IsSpace) != -1 || strings.Contains(v, ";") {
+			return categorize(ParseError, fmt.Errorf("Redirect %s: invalid character in %q", r.From, v))
+		}
+	}
+	return nil
+}
+
+// addAliases adds a redirect for each of the aliases listed in the front
+// matter of the pages (aliases: [/old/path/]), pointing to the page. Aliases
+// are checked like the sources of other redirects.
+func (s *Site) addAliases(root *ContentItem) error {
+	for _, page := range root.allPages() {
+		for _, alias := range page.Metadata.Strings("aliases") {
+			if !strings.HasPrefix(alias, "/") {
+				alias = "/" + alias
+			}
+			r := Redirect{From: alias, To: page.Url, Status: 301}
+			err := checkRedirectPaths(r)
+			if err != nil {
+				return fmt.Errorf("%s: %w", page.FullPath, err)
+			}
+			s.redirects = append(s.redirects, r)
+		}
+	}
+	return nil
+}
+
+// addRedirects adds the output for all configured redirect backends.
+func (s *Site) addRedirects(root *ContentItem) error {
+	if len(s.redirects) == 0 {
+		return nil
+	}
+	for _, r := range s.redirects {
+		err := checkRedirectPaths(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range s.Config.Redirects {
+		backend, ok := redirectBackends[name]
+		if !ok {
+			return fmt.Errorf("Unknown redirect backend: %s", name)
+		}
+		backend(s, root)
+	}
+	return nil
+}
+
+func (s *Site) addMetaRedirects(root *ContentItem) {
+	for _, r := range s.redirects {
+		target := r.To
+		root.addGenerated(redirectPagePath(r.From), Metadata{}, func() ([]byte, error) {
+			return metaRedirectPage(target), nil
+		})
+	}
+}
+
+func (s *Site) addNetlifyRedirects(root *ContentItem) {
+	root.addGenerated("_redirects", Metadata{}, func() ([]byte, error) {
+		buf := &bytes.Buffer{}
+		for _, r := range s.redirects {
+			fmt.Fprintf(buf, "%s %s %d\n", r.From, r.To, r.Status)
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+func (s *Site) addNginxRedirects(root *ContentItem) {
+	root.addGenerated("redirects.nginx.conf", Metadata{}, func() ([]byte, error) {
+		buf := &bytes.Buffer{}
+		for _, r := range s.redirects {
+			fmt.Fprintf(buf, "location = %s { return %d %s; }\n", r.From, r.Status, r.To)
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// redirectPagePath returns the file a redirect page for the given URL is
+// written to: /old/ and /old become /old/index.html, /old.html stays.
+func redirectPagePath(from string) string {
+	if strings.HasSuffix(from, "/") {
+		return from + "index.html"
+	}
+	if path.Ext(from) == "" {
+		return from + "/index.html"
+	}
+	return from
+}
+
+func metaRedirectPage(to string) []byte {
+	to = html.EscapeString(to)
+	return []byte(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8" />
+<title>Redirecting…</title>
+<link rel="canonical" href="` + to + `" />
+<meta http-equiv="refresh" content="0; url=` + to + `" />
+</head>
+<body><a href="` + to + `">` + to + `</a></body>
+</html>
+`)
+}
+
+// urlSet returns all URLs that are generated below c. Pages can be reached
+// both with and without index.html.
+func (c *ContentItem) urlSet() map[string]bool {
+	urls := make(map[string]bool)
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		if c.Type != Directory {
+			urls[c.Url] = true
+			urls["/"+c.OutputPath()] = true
+		}
+		for _, v := range c.Children {
+			walk(v)
+		}
+	}
+	walk(c)
+	return urls
+}
+
+// isLocalURL reports whether u points to a page on this site.
+func isLocalURL(u string) bool {
+	return strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//")
+}
+
+func stripFragment(u string) string {
+	if i := strings.IndexAny(u, "?#"); i != -1 {
+		return u[:i]
+	}
+	return u
+}
@@ -0,0 +1,138 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.md"), []byte("# Home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "new.md"), []byte("# New"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, redirectsFile), []byte(`
+- from: /old/
+  to: /new.html
+- from: /gone.html
+  to: https://example.com/
+  status: 302
+`), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Redirects = []string{"meta", "netlify", "nginx"}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.loadRedirects(root))
+	assert(t, root.child(redirectsFile) == nil, "Redirects file should not be copied")
+	equals(t, site.redirects, []Redirect{
+		{From: "/old/", To: "/new.html", Status: 301},
+		{From: "/gone.html", To: "https://example.com/", Status: 302},
+	})
+
+	root.Process()
+	ok(t, site.checkRedirects(root))
+	ok(t, site.addRedirects(root))
+
+	page, err := root.child("old").child("index.html").generate()
+	ok(t, err)
+	assert(t, strings.Contains(string(page), `content="0; url=/new.html"`), "Missing refresh: %s", page)
+	assert(t, root.child("gone.html") != nil, "Missing redirect page")
+
+	netlify, err := root.child("_redirects").generate()
+	ok(t, err)
+	equals(t, string(netlify), "/old/ /new.html 301\n/gone.html https://example.com/ 302\n")
+
+	nginx, err := root.child("redirects.nginx.conf").generate()
+	ok(t, err)
+	equals(t, string(nginx), "location = /old/ { return 301 /new.html; }\nlocation = /gone.html { return 302 https://example.com/; }\n")
+}
+
+func TestCheckRedirects(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	root := &ContentItem{Site: site, Filename: ".", Type: Directory}
+	root.Children = append(root.Children, &ContentItem{Site: site, Filename: "index.html", Path: "index.md", Type: Content})
+	root.Process()
+
+	site.redirects = []Redirect{{From: "/", To: "/elsewhere/", Status: 301}}
+	assert(t, site.checkRedirects(root) != nil, "Expected error for existing source")
+
+	site.redirects = []Redirect{{From: "/old/", To: "/missing/", Status: 301}}
+	assert(t, site.checkRedirects(root) != nil, "Expected error for missing target")
+
+	site.redirects = []Redirect{{From: "/old/", To: "/#top", Status: 200}}
+	assert(t, site.checkRedirects(root) != nil, "Expected error for invalid status")
+
+	site.redirects = []Redirect{{From: "/old/", To: "/#top", Status: 307}}
+	ok(t, site.checkRedirects(root))
+
+	for _, r := range []Redirect{
+		{From: "/../../x.html", To: "/", Status: 301},
+		{From: "/a/../../x.html", To: "/", Status: 301},
+		{From: "old.html", To: "/", Status: 301},
+		{From: "/old.html", To: "/ ; return 200", Status: 301},
+		{From: "/old.html\n/x", To: "/", Status: 301},
+		{From: "/a;b", To: "/", Status: 301},
+	} {
+		site.redirects = []Redirect{r}
+		err := site.checkRedirects(root)
+		assert(t, err != nil, "Expected error for %q -> %q", r.From, r.To)
+		equals(t, Category(err), ParseError)
+		assert(t, site.addRedirects(root) != nil, "Expected error for %q -> %q", r.From, r.To)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "post.md"), []byte("---\ntitle: Post\naliases: [/2019/old-post/, old.html]\n---\nBody"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Redirects = []string{"meta", "netlify"}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.loadRedirects(root))
+	root.Process()
+	ok(t, site.addAliases(root))
+	equals(t, site.redirects, []Redirect{
+		{From: "/2019/old-post/", To: "/blog/post.html", Status: 301},
+		{From: "/old.html", To: "/blog/post.html", Status: 301},
+	})
+
+	ok(t, site.checkRedirects(root))
+	ok(t, site.addRedirects(root))
+	assert(t, root.child("2019").child("old-post").child("index.html") != nil, "Missing redirect page")
+
+	netlify, err := root.child("_redirects").generate()
+	ok(t, err)
+	equals(t, string(netlify), "/2019/old-post/ /blog/post.html 301\n/old.html /blog/post.html 301\n")
+}
+
+func TestInvalidAliases(t *testing.T) {
+	for _, alias := range []string{"/../../x.html", "../outside/", "/old post/", "/a;b"} {
+		site := NewSite(DefaultConfig())
+		root := &ContentItem{Site: site, Filename: ".", Type: Directory}
+		page := &ContentItem{Site: site, Filename: "post.html", Path: "post.md", Type: Content}
+		page.Metadata.Params = map[string]interface{}{"aliases": []interface{}{alias}}
+		root.Children = append(root.Children, page)
+		root.Process()
+
+		err := site.addAliases(root)
+		assert(t, err != nil, "Expected error for alias %q", alias)
+		equals(t, Category(err), ParseError)
+		equals(t, len(site.redirects), 0)
+	}
+}
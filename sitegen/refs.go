@@ -0,0 +1,144 @@
+package sitegen
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// {{< ref "posts/foo.md" >}} in content files.
+	refShortcodeRegex = regexp.MustCompile(`\{\{<\s*(rel)?ref\s+"([^"]+)"\s*>\}\}`)
+
+	// What the shortcodes are turned into while parsing, resolved once all
+	// URLs are known.
+	refPlaceholderRegex = regexp.MustCompile(`sitegen:(rel)?ref:([^"'\s<>()]+)`)
+)
+
+// replaceRefShortcodes turns ref shortcodes into placeholders that survive
+// Markdown rendering.
+func replaceRefShortcodes(body []byte) []byte {
+	return refShortcodeRegex.ReplaceAll(body, []byte("sitegen:${1}ref:${2}"))
+}
+
+// markdownLinkRef turns a relative link to a Markdown file (../other.md or
+// other.md#section) into a relref placeholder, so it points to the page
+// made from that file.
+func markdownLinkRef(link []byte) ([]byte, bool) {
+	p := string(link)
+	if i := strings.IndexAny(p, "#?"); i != -1 {
+		p = p[:i]
+	}
+	if strings.Contains(p, ":") || strings.HasPrefix(p, "//") || !strings.HasSuffix(strings.ToLower(p), ".md") {
+		return link, false
+	}
+	return append([]byte("sitegen:relref:"), link...), true
+}
+
+// resolveRefs replaces the ref placeholders in the content of each page with
+// the URL of the page they point to. Fragments keep theirs until they're
+// included, see fragment.
+func (s *Site) resolveRefs(root *ContentItem) error {
+	for _, page := range root.allPages() {
+		content, err := s.resolveRefsIn(page, page.Content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", page.FullPath, err)
+		}
+		page.Content = content
+	}
+	return nil
+}
+
+// resolveRefsIn replaces the ref placeholders in content, with paths taken
+// relative to the given page.
+func (s *Site) resolveRefsIn(from *ContentItem, content template.HTML) (template.HTML, error) {
+	var err error
+	result := refPlaceholderRegex.ReplaceAllStringFunc(string(content), func(m string) string {
+		parts := refPlaceholderRegex.FindStringSubmatch(m)
+		var u string
+		var refErr error
+		if parts[1] == "rel" {
+			u, refErr = s.relref(from, parts[2])
+		} else {
+			u, refErr = s.ref(from, parts[2])
+		}
+		if refErr != nil && err == nil {
+			err = refErr
+		}
+		return u
+	})
+	return template.HTML(result), err
+}
+
+// ref returns the full URL of the page with the given source path, e.g.
+// posts/foo.md or posts/foo.md#section. Used as the ref template function.
+func (s *Site) ref(from *ContentItem, p string) (string, error) {
+	page, fragment, err := s.findPage(from, p)
+	if err != nil {
+		return "", err
+	}
+	return page.Permalink + fragment, nil
+}
+
+// relref returns the URL of the page with the given source path, without
+// the host. Used as the relref template function.
+func (s *Site) relref(from *ContentItem, p string) (string, error) {
+	page, fragment, err := s.findPage(from, p)
+	if err != nil {
+		return "", err
+	}
+	return s.relativeURL(page.Url) + fragment, nil
+}
+
+// findPage looks up a page by the path of its source file: relative to the
+// page it's referenced from, relative to the content folder or, when that
+// doesn't match, by the end of the path (foo.md), which has to be unique.
+func (s *Site) findPage(from *ContentItem, ref string) (*ContentItem, string, error) {
+	p, fragment := ref, ""
+	if i := strings.Index(ref, "#"); i != -1 {
+		p, fragment = ref[:i], ref[i:]
+	}
+	p = normalizePath(p)
+
+	pages := s.root.allPages()
+	find := func(source string) *ContentItem {
+		for _, page := range pages {
+			if page.sourcePath != "" && page.sourcePath == source {
+				return page
+			}
+		}
+		return nil
+	}
+
+	if !strings.HasPrefix(p, "/") && from != nil && from.sourcePath != "" {
+		if page := find(path.Join(path.Dir(from.sourcePath), p)); page != nil {
+			return page, fragment, nil
+		}
+	}
+	clean := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if page := find(clean); page != nil {
+		return page, fragment, nil
+	}
+	if strings.HasPrefix(p, "/") {
+		return nil, "", categorize(LinkError, fmt.Errorf("Reference to unknown page: %s", ref))
+	}
+
+	matches := make([]string, 0)
+	var match *ContentItem
+	for _, page := range pages {
+		if strings.HasSuffix(page.sourcePath, "/"+clean) {
+			matches = append(matches, page.sourcePath)
+			match = page
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, "", categorize(LinkError, fmt.Errorf("Reference to unknown page: %s", ref))
+	case 1:
+		return match, fragment, nil
+	default:
+		return nil, "", categorize(LinkError, fmt.Errorf("Ambiguous reference %s: matches %s", ref, strings.Join(matches, ", ")))
+	}
+}
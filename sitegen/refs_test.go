@@ -0,0 +1,178 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "posts"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "docs"), 0755))
+	files := map[string]string{
+		"posts/foo.md":   "---\nurl: /foo/\n---\nFoo",
+		"posts/bar.md":   `See [foo]({{< relref "foo.md" >}}) and [intro]({{< ref "docs/intro.md#setup" >}}).`,
+		"docs/intro.md":  "Intro",
+		"docs/index.md":  "Docs",
+		"index.md":       "Home",
+		"posts/index.md": "Posts",
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.BaseURL = "https://example.com/"
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.applyURLOverrides(root))
+	root.Process()
+	site.root = root
+
+	ok(t, site.resolveRefs(root))
+	equals(t, string(root.child("posts").child("bar.html").Content),
+		"<p>See <a href=\"/foo/\">foo</a> and <a href=\"https://example.com/docs/intro.html#setup\">intro</a>.</p>\n")
+
+	home := root.child("index.html")
+	u, err := site.relref(home, "posts/foo.md")
+	ok(t, err)
+	equals(t, u, "/foo/")
+	u, err = site.ref(home, "/docs/intro.md")
+	ok(t, err)
+	equals(t, u, "https://example.com/docs/intro.html")
+	u, err = site.relref(root.child("docs").child("intro.html"), "../posts/bar.md")
+	ok(t, err)
+	equals(t, u, "/posts/bar.html")
+
+	_, err = site.relref(home, "missing.md")
+	assert(t, err != nil, "Expected unknown page")
+	equals(t, Category(err), LinkError)
+
+	_, err = site.relref(home, "/foo.md")
+	assert(t, err != nil, "Expected unknown page")
+
+	_, err = site.relref(nil, "index.md")
+	ok(t, err)
+	_, err = site.relref(home, "intro.md")
+	ok(t, err)
+	_, err = site.relref(root.child("posts").child("bar.html"), "/index.md")
+	ok(t, err)
+
+	// index.md exists in several folders, but the one next to the page wins.
+	u, err = site.relref(root.child("docs").child("intro.html"), "index.md")
+	ok(t, err)
+	equals(t, u, "/docs/")
+	_, err = site.relref(nil, "/posts/../posts/index.md")
+	ok(t, err)
+}
+
+func TestAmbiguousRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "a"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "b"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "a", "page.md"), []byte("A"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "b", "page.md"), []byte("B"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.md"), []byte(`[x]({{< relref "page.md" >}})`), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	root.Process()
+	site.root = root
+
+	err = site.resolveRefs(root)
+	assert(t, err != nil, "Expected ambiguous reference")
+	equals(t, err.Error(), filepath.Join(dir, "index.md")+": Ambiguous reference page.md: matches a/page.md, b/page.md")
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "posts"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "docs"), 0755))
+	files := map[string]string{
+		"posts/bar.md":  "---\nurl: /bar/\n---\nBar",
+		"docs/intro.md": "[Bar](../posts/bar.md#x) [Home](/index.md) [Ext](https://example.org/a.md) [Page](intro.html)",
+		"index.md":      "Home",
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Markdown.RewriteMarkdownLinks = true
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.applyURLOverrides(root))
+	root.Process()
+	site.root = root
+
+	ok(t, site.resolveRefs(root))
+	equals(t, string(root.child("docs").child("intro.html").Content),
+		"<p><a href=\"/bar/#x\">Bar</a> <a href=\"/\">Home</a> <a href=\"https://example.org/a.md\">Ext</a> <a href=\"intro.html\">Page</a></p>\n")
+}
+
+func TestFragmentRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{"posts", "docs", "shared"} {
+		ok(t, os.MkdirAll(filepath.Join(dir, d), 0755))
+	}
+	files := map[string]string{
+		"posts/foo.md":    "Foo",
+		"posts/bar.md":    "Bar",
+		"docs/foo.md":     "Docs foo",
+		"shared/note.md":  "---\nfragment: true\n---\nSee [foo]({{< relref \"foo.md\" >}}).",
+		"shared/about.md": "---\nfragment: true\n---\nSee [bar]({{< relref \"../posts/bar.md\" >}}).",
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.collectFragments(root)
+	root.Process()
+	site.root = root
+	ok(t, site.resolveRefs(root))
+
+	html, err := site.fragment("shared/note", root.child("posts").child("bar.html"))
+	ok(t, err)
+	equals(t, string(html), "<p>See <a href=\"/posts/foo.html\">foo</a>.</p>\n")
+	html, err = site.fragment("shared/note", root.child("docs").child("foo.html"))
+	ok(t, err)
+	equals(t, string(html), "<p>See <a href=\"/docs/foo.html\">foo</a>.</p>\n")
+
+	// Without a page, paths are relative to the fragment.
+	html, err = site.fragment("shared/about")
+	ok(t, err)
+	equals(t, string(html), "<p>See <a href=\"/posts/bar.html\">bar</a>.</p>\n")
+	_, err = site.fragment("shared/note")
+	assert(t, err != nil, "Expected ambiguous reference")
+	equals(t, Category(err), LinkError)
+}
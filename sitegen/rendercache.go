@@ -0,0 +1,87 @@
+package sitegen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// renderCacheVersion changes when sitegen renders Markdown differently, so
+// older results aren't used.
+const renderCacheVersion = "1"
+
+// renderCacheFile returns where the rendered Markdown for a body and its
+// settings is cached, or "" when it can't be: pages that use render hooks
+// depend on templates as well.
+func (s *Site) renderCacheFile(body []byte, config MarkdownConfig) string {
+	if !s.Config.RenderCache || config.hooks != nil {
+		return ""
+	}
+	settings, err := yaml.Marshal(config)
+	if err != nil {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, v := range []string{renderCacheVersion, string(settings), config.footnotePrefix, config.baseURL, config.lang} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return filepath.Join(s.Config.CacheDir, "markdown", hex.EncodeToString(h.Sum(nil))+".html")
+}
+
+// cachedMarkdown renders Markdown through render, unless the result is
+// already in the render cache.
+func (s *Site) cachedMarkdown(body []byte, config MarkdownConfig, render func() ([]byte, error)) ([]byte, error) {
+	cacheFile := s.renderCacheFile(body, config)
+	if cacheFile == "" {
+		return render()
+	}
+	if cached, err := ioutil.ReadFile(cacheFile); err == nil {
+		return cached, nil
+	}
+
+	content, err := render()
+	if err != nil {
+		return nil, err
+	}
+	err = writeCacheFile(cacheFile, content)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// writeCacheFile writes a file to the cache through a temporary file that
+// is renamed into place, so builds running at the same time never read a
+// partially written file.
+func writeCacheFile(cacheFile string, data []byte) error {
+	dir := filepath.Dir(cacheFile)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-"+filepath.Base(cacheFile))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cacheFile)
+}
@@ -0,0 +1,65 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.CacheDir = dir
+	config.RenderCache = true
+	site := NewSite(config)
+
+	out, err := site.markdown([]byte("*Hello*\n"), "hello.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<p><em>Hello</em></p>\n")
+
+	files, err := filepath.Glob(filepath.Join(dir, "markdown", "*.html"))
+	ok(t, err)
+	equals(t, len(files), 1)
+
+	// The cached result is used when the body and settings are the same.
+	ok(t, ioutil.WriteFile(files[0], []byte("cached"), 0644))
+	out, err = site.markdown([]byte("*Hello*\n"), "other.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "cached")
+
+	// Other settings render again.
+	out, err = site.markdown([]byte("*Hello*\n"), "hello.md", Metadata{Params: map[string]interface{}{
+		"markup": map[string]interface{}{"extensions": map[string]interface{}{"smartypants": false}},
+	}})
+	ok(t, err)
+	equals(t, string(out), "<p><em>Hello</em></p>\n")
+
+	// Without renderCache, nothing is read or written.
+	config.RenderCache = false
+	out, err = site.markdown([]byte("*Hello*\n"), "hello.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<p><em>Hello</em></p>\n")
+}
+
+func TestWriteCacheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	cacheFile := filepath.Join(dir, "markdown", "abc.html")
+	ok(t, writeCacheFile(cacheFile, []byte("<p>Hi</p>")))
+	ok(t, writeCacheFile(cacheFile, []byte("<p>Hello</p>")))
+
+	data, err := ioutil.ReadFile(cacheFile)
+	ok(t, err)
+	equals(t, string(data), "<p>Hello</p>")
+
+	// No temporary files are left behind.
+	files, err := ioutil.ReadDir(filepath.Dir(cacheFile))
+	ok(t, err)
+	equals(t, len(files), 1)
+}
@@ -0,0 +1,114 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+)
+
+// Templates that render Markdown links and images, in the template folder.
+const (
+	markupTemplateDir = "_markup"
+	linkHookTemplate  = markupTemplateDir + "/render-link.html"
+	imageHookTemplate = markupTemplateDir + "/render-image.html"
+)
+
+// RenderHookContext is passed to the link and image render hooks.
+type RenderHookContext struct {
+	// The URL of the link or image. Links to other pages (relref or
+	// rewritten .md links) hold a placeholder that's replaced with the URL
+	// of the page once all URLs are known.
+	Destination template.URL
+
+	// Title of the link or image, if it has one.
+	Title string
+
+	// Text of the link (rendered HTML), or the alt text of an image.
+	Text template.HTML
+
+	// Path of the page the link is on, relative to the content folder.
+	PagePath string
+}
+
+// renderHooks renders links and images with templates, rather than the
+// built-in markup.
+type renderHooks struct {
+	site     *Site
+	pagePath string
+	link     bool
+	image    bool
+
+	// First error of a hook, reported after rendering.
+	err error
+}
+
+// renderHooks returns the render hooks for a page, nil when there are
+// none.
+func (s *Site) renderHooks(sourcePath string) *renderHooks {
+	if s.templates == nil {
+		return nil
+	}
+	hooks := &renderHooks{
+		site:     s,
+		pagePath: sourcePath,
+		link:     s.findTemplate(linkHookTemplate) != "",
+		image:    s.findTemplate(imageHookTemplate) != "",
+	}
+	if !hooks.link && !hooks.image {
+		return nil
+	}
+	return hooks
+}
+
+func (h *renderHooks) render(out *bytes.Buffer, name string, link, title, text []byte) {
+	err := h.site.templates.ExecuteTemplate(out, name, &RenderHookContext{
+		Destination: hookURL(link),
+		Title:       string(title),
+		Text:        template.HTML(text),
+		PagePath:    h.pagePath,
+	})
+	if err != nil && h.err == nil {
+		h.err = fmt.Errorf("%s: %w", h.pagePath, err)
+	}
+}
+
+// hookURL marks the destination of a link or image as safe for the hook
+// templates when it's a reference to another page or uses a scheme
+// html/template allows. Others are filtered out the way html/template does.
+func hookURL(link []byte) template.URL {
+	u := string(link)
+	if refPlaceholderRegex.MatchString(u) && strings.HasPrefix(u, "sitegen:") {
+		return template.URL(u)
+	}
+	if i := strings.IndexAny(u, ":/?#"); i != -1 && u[i] == ':' {
+		switch strings.ToLower(u[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return "#ZgotmplZ"
+		}
+	}
+	return template.URL(u)
+}
+
+func (r *renderer) Link(out *bytes.Buffer, link []byte, title []byte, content []byte) {
+	if r.config.RewriteMarkdownLinks {
+		link, _ = markdownLinkRef(link)
+	}
+	if hooks := r.config.hooks; hooks != nil && hooks.link {
+		hooks.render(out, linkHookTemplate, link, title, content)
+		return
+	}
+	start := out.Len()
+	r.Html.Link(out, link, title, content)
+	r.markExternal(out, start, link)
+}
+
+func (r *renderer) Image(out *bytes.Buffer, link []byte, title []byte, alt []byte) {
+	if hooks := r.config.hooks; hooks != nil && hooks.image {
+		hooks.render(out, imageHookTemplate, link, title, []byte(html.EscapeString(string(alt))))
+		return
+	}
+	r.Html.Image(out, link, title, alt)
+}
@@ -0,0 +1,76 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "_markup"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "_markup", "render-image.html"),
+		[]byte(`<picture><source srcset="{{.Destination}}.webp"><img src="{{.Destination}}" alt="{{.Text}}"></picture>`), 0644))
+
+	config := DefaultConfig()
+	config.TemplateDir = dir
+	site := NewSite(config)
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+
+	input := []byte("![A <cat>](cat.jpg) [*Home*](/ \"Start\")\n")
+	out, err := site.markdown(input, "index.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<p><picture><source srcset=\"cat.jpg.webp\"><img src=\"cat.jpg\" alt=\"A &lt;cat&gt;\"></picture> <a href=\"/\" title=\"Start\"><em>Home</em></a></p>\n")
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "_markup", "render-link.html"),
+		[]byte(`<a class="link" href="{{.Destination}}">{{.Text}}</a>{{.PagePath}}{{.Missing}}`), 0644))
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+
+	_, err = site.markdown(input, "index.md", Metadata{})
+	assert(t, err != nil, "Expected an error for a failing hook")
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "_markup", "render-link.html"),
+		[]byte(`<a class="link" href="{{.Destination}}">{{.Text}}</a> on {{.PagePath}}`), 0644))
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+
+	out, err = site.markdown([]byte("[*Home*](/)\n"), "blog/post.md", Metadata{})
+	ok(t, err)
+	equals(t, string(out), "<p><a class=\"link\" href=\"/\"><em>Home</em></a> on blog/post.md</p>\n")
+}
+
+func TestRenderHookRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"content/posts/foo.md":               "Foo",
+		"content/posts/bar.md":               "[ref]({{< relref \"foo.md\" >}}) [md](foo.md#top) [js](javascript:alert(1))\n",
+		"templates/page.html":                "{{.Content}}",
+		"templates/_markup/render-link.html": `<a class="hook" href="{{.Destination}}">{{.Text}}</a>`,
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "out")
+	config.Markdown.RewriteMarkdownLinks = true
+	site := NewSite(config)
+	ok(t, site.Build())
+
+	data, err := ioutil.ReadFile(filepath.Join(config.OutputDir, "posts", "bar.html"))
+	ok(t, err)
+	equals(t, string(data), `<p><a class="hook" href="/posts/foo.html">ref</a> <a class="hook" href="/posts/foo.html#top">md</a> <a class="hook" href="#ZgotmplZ">js</a></p>`+"\n")
+}
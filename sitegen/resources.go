@@ -0,0 +1,174 @@
+package sitegen
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// Resource is a file that belongs to a page bundle: a directory with an
+// index page, where the other files (images, downloads, ...) belong to the
+// page. They're written next to the page.
+type Resource struct {
+	// Path relative to the bundle directory, e.g. images/photo.jpg.
+	Name string
+
+	// From the resources in the front matter of the page, the title
+	// defaults to the name.
+	Title  string
+	Params map[string]interface{}
+
+	Item *ContentItem
+}
+
+// ResourceMetadata sets the title and parameters of the resources of a
+// bundle whose name matches Src, a glob pattern. When several match, the
+// first one that sets a field wins.
+type ResourceMetadata struct {
+	Src    string
+	Title  string
+	Params map[string]interface{}
+}
+
+// Url returns the URL of the resource.
+func (r *Resource) Url() string {
+	return "/" + r.Item.OutputPath()
+}
+
+// MediaType returns the MIME type of the resource, based on its extension.
+func (r *Resource) MediaType() string {
+	t := mime.TypeByExtension(path.Ext(r.Name))
+	if t == "" {
+		return "application/octet-stream"
+	}
+	return strings.TrimSpace(strings.Split(t, ";")[0])
+}
+
+// Type returns the main type of the resource: image, video, audio, text or
+// application.
+func (r *Resource) Type() string {
+	return strings.Split(r.MediaType(), "/")[0]
+}
+
+// Resources is the list of resources of a page.
+type Resources []*Resource
+
+// Get returns the resource with the given name, or nil.
+func (r Resources) Get(name string) *Resource {
+	for _, v := range r {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Match returns the resources whose name matches a glob pattern.
+func (r Resources) Match(pattern string) Resources {
+	result := make(Resources, 0)
+	for _, v := range r {
+		if matchGlob(pattern, v.Name) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// ByType returns the resources of the given main type, e.g. image.
+func (r Resources) ByType(t string) Resources {
+	result := make(Resources, 0)
+	for _, v := range r {
+		if v.Type() == t {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// collectResources turns directories with an index page into page bundles:
+// the assets in them, including subdirectories that aren't bundles
+// themselves, become resources of the index page (and its translations).
+func (s *Site) collectResources(root *ContentItem) {
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+			}
+		}
+
+		pages := make([]*ContentItem, 0)
+		for _, v := range c.Children {
+			if v.Type == Content && strings.HasPrefix(v.Filename, "index.") {
+				pages = append(pages, v)
+			}
+		}
+		if len(pages) == 0 {
+			return
+		}
+
+		resources := bundleResources(c, c)
+		for _, page := range pages {
+			page.Resources = resources.withMetadata(page.Metadata.Resources)
+		}
+	}
+	walk(root)
+}
+
+func bundleResources(bundle, dir *ContentItem) Resources {
+	result := make(Resources, 0)
+	for _, v := range dir.Children {
+		switch v.Type {
+		case Asset:
+			result = append(result, &Resource{
+				Name: strings.TrimPrefix(v.Path, bundle.Path+"/"),
+				Item: v,
+			})
+		case Directory:
+			if !isBundle(v) {
+				result = append(result, bundleResources(bundle, v)...)
+			}
+		}
+	}
+	return result
+}
+
+// withMetadata returns a copy of the resources with the titles and
+// parameters from the front matter of a page applied.
+func (r Resources) withMetadata(metadata []ResourceMetadata) Resources {
+	result := make(Resources, 0, len(r))
+	for _, v := range r {
+		res := &Resource{
+			Name:   v.Name,
+			Item:   v.Item,
+			Params: make(map[string]interface{}),
+		}
+		for _, m := range metadata {
+			if !matchGlob(m.Src, res.Name) {
+				continue
+			}
+			if res.Title == "" {
+				res.Title = m.Title
+			}
+			for key, value := range m.Params {
+				if _, ok := res.Params[key]; !ok {
+					res.Params[key] = value
+				}
+			}
+		}
+		if res.Title == "" {
+			res.Title = res.Name
+		}
+		result = append(result, res)
+	}
+	return result
+}
+
+func isBundle(dir *ContentItem) bool {
+	for _, v := range dir.Children {
+		if v.Type == Content && strings.HasPrefix(v.Filename, "index.") {
+			return true
+		}
+	}
+	return false
+}
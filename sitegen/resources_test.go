@@ -0,0 +1,116 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPageBundles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	bundle := filepath.Join(content, "posts", "my-post")
+	ok(t, os.MkdirAll(filepath.Join(bundle, "images"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(bundle, "nested"), 0755))
+	files := map[string]string{
+		"index.md":            "---\ntitle: My post\n---\nHello",
+		"photo.jpg":           "jpg",
+		"images/diagram.png":  "png",
+		"nested/index.md":     "---\ntitle: Nested\n---\nNested",
+		"nested/download.zip": "zip",
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(bundle, filepath.FromSlash(name)), []byte(data), 0644))
+	}
+	ok(t, ioutil.WriteFile(filepath.Join(content, "posts", "other.png"), []byte("png"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.collectResources(root)
+	root.Process()
+
+	post := root.child("posts").child("my-post")
+	page := post.child("index.html")
+	equals(t, len(page.Resources), 2)
+	equals(t, page.Resources[0].Name, "images/diagram.png")
+	equals(t, page.Resources[0].Url(), "/posts/my-post/images/diagram.png")
+	equals(t, page.Resources[1].Name, "photo.jpg")
+	assert(t, page.Resources.Get("photo.jpg") == page.Resources[1], "Expected resource by name")
+	assert(t, page.Resources.Get("missing.jpg") == nil, "Unexpected resource")
+	equals(t, len(page.Resources.Match("**/*.png")), 1)
+
+	nested := post.child("nested").child("index.html")
+	equals(t, len(nested.Resources), 1)
+	equals(t, nested.Resources[0].Name, "download.zip")
+
+	assert(t, root.child("posts").child("other.png") != nil, "Expected asset outside of bundles")
+}
+
+func TestResourceMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	bundle := filepath.Join(content, "my-post")
+	ok(t, os.MkdirAll(bundle, 0755))
+	files := map[string]string{
+		"index.md": `---
+title: My post
+resources:
+  - src: cover.jpg
+    title: The cover
+    params:
+      credit: Someone
+  - src: "*.jpg"
+    title: A photo
+    params:
+      credit: Me
+      featured: true
+---
+Hello`,
+		"cover.jpg":  "jpg",
+		"other.jpg":  "jpg",
+		"report.pdf": "pdf",
+	}
+	for name, data := range files {
+		ok(t, ioutil.WriteFile(filepath.Join(bundle, name), []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.collectResources(root)
+
+	resources := root.child("my-post").child("index.html").Resources
+	equals(t, len(resources), 3)
+
+	cover := resources.Get("cover.jpg")
+	equals(t, cover.Title, "The cover")
+	equals(t, cover.Params["credit"], "Someone")
+	equals(t, cover.Params["featured"], true)
+
+	other := resources.Get("other.jpg")
+	equals(t, other.Title, "A photo")
+	equals(t, other.Params["credit"], "Me")
+
+	report := resources.Get("report.pdf")
+	equals(t, report.Title, "report.pdf")
+	equals(t, report.MediaType(), "application/pdf")
+	equals(t, report.Type(), "application")
+
+	images := resources.ByType("image")
+	equals(t, len(images), 2)
+	equals(t, images[0].MediaType(), "image/jpeg")
+}
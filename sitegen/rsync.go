@@ -0,0 +1,112 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SSHConfig holds the settings for deploying over SSH, with rsync.
+type SSHConfig struct {
+	// Server to deploy to.
+	Host string `yaml:"host"`
+
+	// User to log in as, defaults to the one of the SSH configuration.
+	User string `yaml:"user"`
+
+	// SSH port, defaults to 22.
+	Port int `yaml:"port"`
+
+	// Directory on the server the site is synced to.
+	Path string `yaml:"path"`
+
+	// Private key to log in with, defaults to the ones of the SSH agent
+	// and configuration.
+	Key string `yaml:"key"`
+
+	// The rsync executable, defaults to "rsync".
+	Command string `yaml:"command"`
+}
+
+// sshTarget fills in the settings from a target such as
+// ssh://user@example.com:2222/var/www, on top of the configured ones.
+func sshTarget(config SSHConfig, u *url.URL) (SSHConfig, error) {
+	if u.Host != "" {
+		config.Host = u.Hostname()
+		if port := u.Port(); port != "" {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return config, fmt.Errorf("Invalid port: %s", port)
+			}
+			config.Port = p
+		}
+	}
+	if u.User != nil {
+		config.User = u.User.Username()
+	}
+	if u.Path != "" {
+		config.Path = u.Path
+	}
+	if config.Host == "" || config.Path == "" {
+		return config, fmt.Errorf("No host and path to deploy to, use ssh://host/path or configure them")
+	}
+	return config, nil
+}
+
+// rsyncArgs returns the command that syncs the output directory to the
+// server. Only changed files are sent, files on the keep list are left out.
+func (s *Site) rsyncArgs(config SSHConfig, dryRun bool) []string {
+	command := config.Command
+	if command == "" {
+		command = "rsync"
+	}
+	args := []string{command, "--recursive", "--links", "--compress", "--checksum", "--itemize-changes"}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+	if s.Config.Deploy.Delete {
+		args = append(args, "--delete")
+	}
+	for _, pattern := range s.Config.Keep {
+		args = append(args, "--exclude=/"+pattern)
+	}
+
+	ssh := []string{"ssh"}
+	if config.Port != 0 {
+		ssh = append(ssh, "-p", strconv.Itoa(config.Port))
+	}
+	if config.Key != "" {
+		ssh = append(ssh, "-i", strconv.Quote(config.Key))
+	}
+	args = append(args, "--rsh="+strings.Join(ssh, " "))
+
+	dest := config.Host + ":" + strings.TrimSuffix(config.Path, "/") + "/"
+	if config.User != "" {
+		dest = config.User + "@" + dest
+	}
+	return append(args, filepath.Clean(s.Config.OutputDir)+string(filepath.Separator), dest)
+}
+
+// deploySSH syncs the output directory to a server with rsync.
+func (s *Site) deploySSH(u *url.URL, dryRun bool) error {
+	config, err := sshTarget(s.Config.Deploy.SSH, u)
+	if err != nil {
+		return err
+	}
+
+	args := s.rsyncArgs(config, dryRun)
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = stderr
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("rsync to %s failed: %s\n%s", config.Host, err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package sitegen
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSSHTarget(t *testing.T) {
+	u, err := url.Parse("ssh://deploy@example.com:2222/var/www/site")
+	ok(t, err)
+	config, err := sshTarget(SSHConfig{Key: "id_deploy", Port: 22}, u)
+	ok(t, err)
+	equals(t, SSHConfig{Host: "example.com", User: "deploy", Port: 2222, Path: "/var/www/site", Key: "id_deploy"}, config)
+
+	config, err = sshTarget(SSHConfig{Host: "example.com", Path: "/srv/www"}, &url.URL{})
+	ok(t, err)
+	equals(t, "example.com", config.Host)
+
+	_, err = sshTarget(SSHConfig{}, &url.URL{})
+	assert(t, err != nil, "Expected an error without host and path")
+}
+
+func TestRsyncArgs(t *testing.T) {
+	config := DefaultConfig()
+	config.OutputDir = "public"
+	config.Deploy.Delete = true
+	site := NewSite(config)
+
+	args := site.rsyncArgs(SSHConfig{Host: "example.com", User: "deploy", Port: 2222, Path: "/var/www/", Key: "keys/id_deploy"}, true)
+	equals(t, []string{
+		"rsync", "--recursive", "--links", "--compress", "--checksum", "--itemize-changes",
+		"--dry-run", "--delete", "--exclude=/CNAME", "--exclude=/.git",
+		`--rsh=ssh -p 2222 -i "keys/id_deploy"`,
+		"public/", "deploy@example.com:/var/www/",
+	}, args)
+
+	config.Deploy.Delete = false
+	args = site.rsyncArgs(SSHConfig{Host: "example.com", Path: "www", Command: "/usr/local/bin/rsync"}, false)
+	equals(t, []string{
+		"/usr/local/bin/rsync", "--recursive", "--links", "--compress", "--checksum", "--itemize-changes",
+		"--exclude=/CNAME", "--exclude=/.git", "--rsh=ssh",
+		"public/", "example.com:www/",
+	}, args)
+}
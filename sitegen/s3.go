@@ -0,0 +1,211 @@
+package sitegen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+)
+
+// S3Config holds the settings for deploying to S3 or compatible storage.
+type S3Config struct {
+	// Host of the storage service, defaults to s3.amazonaws.com. Set it
+	// for MinIO (minio.example.com:9000) or R2
+	// (<account>.r2.cloudflarestorage.com).
+	Endpoint string `yaml:"endpoint"`
+
+	// Region of the bucket, detected when left empty.
+	Region string `yaml:"region"`
+
+	// Use plain HTTP, e.g. for a local MinIO.
+	Insecure bool `yaml:"insecure"`
+
+	// CloudFront distribution to invalidate the changed files in.
+	CloudFront string `yaml:"cloudFront"`
+}
+
+// s3Plan is what a deploy to S3 changes.
+type s3Plan struct {
+	Upload []deployFile
+	Delete []string
+}
+
+// planS3 compares the local files with the objects in the bucket (keyed by
+// path, with their ETag). Objects uploaded in parts have an ETag that isn't
+// the MD5 of their contents, they're uploaded again.
+func planS3(files []deployFile, objects map[string]string, remove bool) s3Plan {
+	plan := s3Plan{}
+	local := make(map[string]bool)
+	for _, f := range files {
+		local[f.Path] = true
+		if etag, ok := objects[f.Path]; ok && strings.Trim(etag, `"`) == f.Hash {
+			continue
+		}
+		plan.Upload = append(plan.Upload, f)
+	}
+	if remove {
+		for p := range objects {
+			if !local[p] {
+				plan.Delete = append(plan.Delete, p)
+			}
+		}
+	}
+	return plan
+}
+
+// deployS3 syncs the output directory to a bucket, under the given prefix.
+// Only files that changed are uploaded.
+func (s *Site) deployS3(bucket, prefix string, dryRun bool) error {
+	config := s.Config.Deploy.S3
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+		&credentials.FileAWSCredentials{},
+		&credentials.IAM{},
+	})
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !config.Insecure,
+		Region: config.Region,
+	})
+	if err != nil {
+		return err
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	ctx := context.Background()
+	objects := make(map[string]string)
+	for object := range client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return fmt.Errorf("Listing %s failed: %s", bucket, object.Err)
+		}
+		objects[strings.TrimPrefix(object.Key, prefix)] = object.ETag
+	}
+
+	files, err := s.outputFiles()
+	if err != nil {
+		return err
+	}
+	plan := planS3(files, objects, s.Config.Deploy.Delete)
+	if len(plan.Upload) == 0 && len(plan.Delete) == 0 {
+		log.Println("No changes")
+		return nil
+	}
+
+	for _, f := range plan.Upload {
+		log.Printf(" -> %s\n", f.Path)
+		if dryRun {
+			continue
+		}
+		_, err := client.FPutObject(ctx, bucket, prefix+f.Path, filepath.Join(s.Config.OutputDir, filepath.FromSlash(f.Path)), minio.PutObjectOptions{
+			ContentType:  contentType(f.Path),
+			CacheControl: s.Config.Deploy.cacheControl(f.Path),
+		})
+		if err != nil {
+			return fmt.Errorf("Uploading %s failed: %w", f.Path, err)
+		}
+	}
+	for _, p := range plan.Delete {
+		log.Printf(" x %s\n", p)
+		if dryRun {
+			continue
+		}
+		err := client.RemoveObject(ctx, bucket, prefix+p, minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("Removing %s failed: %w", p, err)
+		}
+	}
+
+	if config.CloudFront == "" || dryRun {
+		return nil
+	}
+	value, err := creds.Get()
+	if err != nil {
+		return err
+	}
+	changed := make([]string, 0, len(plan.Upload)+len(plan.Delete))
+	for _, f := range plan.Upload {
+		changed = append(changed, f.Path)
+	}
+	changed = append(changed, plan.Delete...)
+	return invalidateCloudFront(config.CloudFront, invalidationPaths(changed), value)
+}
+
+// invalidationPaths returns the URL paths to invalidate for the changed
+// files: index pages also under the URL of their directory. Many changes
+// invalidate everything.
+func invalidationPaths(changed []string) []string {
+	if len(changed) > 100 {
+		return []string{"/*"}
+	}
+	paths := make([]string, 0, len(changed))
+	for _, p := range changed {
+		paths = append(paths, "/"+p)
+		if path.Base(p) == "index.html" {
+			paths = append(paths, strings.TrimSuffix("/"+p, "index.html"))
+		}
+	}
+	return paths
+}
+
+type cloudFrontInvalidation struct {
+	XMLName         xml.Name `xml:"http://cloudfront.amazonaws.com/doc/2020-05-31/ InvalidationBatch"`
+	Quantity        int      `xml:"Paths>Quantity"`
+	Items           []string `xml:"Paths>Items>Path"`
+	CallerReference string   `xml:"CallerReference"`
+}
+
+// invalidateCloudFront clears the given paths from the cache of a
+// CloudFront distribution.
+func invalidateCloudFront(distribution string, paths []string, creds credentials.Value) error {
+	body, err := xml.Marshal(cloudFrontInvalidation{
+		Quantity:        len(paths),
+		Items:           paths,
+		CallerReference: fmt.Sprintf("sitegen-%d", time.Now().UnixNano()),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", "https://cloudfront.amazonaws.com/2020-05-31/distribution/"+distribution+"/invalidation", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(body)
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(hash[:]))
+	req = signer.SignV4WithServiceType(*req, creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken, "us-east-1", "cloudfront")
+
+	log.Printf("==> Invalidating %d paths in %s\n", len(paths), distribution)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Invalidating %s failed: %s: %s", distribution, resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package sitegen
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestPlanS3(t *testing.T) {
+	files := []deployFile{
+		{Path: "index.html", Hash: "aaa"},
+		{Path: "blog/post.html", Hash: "bbb"},
+		{Path: "video.mp4", Hash: "ccc"},
+		{Path: "new.html", Hash: "ddd"},
+	}
+	objects := map[string]string{
+		"index.html":     `"aaa"`,
+		"blog/post.html": `"old"`,
+		"video.mp4":      `"ccc-3"`,
+		"gone.html":      `"eee"`,
+	}
+
+	plan := planS3(files, objects, false)
+	paths := make([]string, 0)
+	for _, f := range plan.Upload {
+		paths = append(paths, f.Path)
+	}
+	equals(t, []string{"blog/post.html", "video.mp4", "new.html"}, paths)
+	equals(t, 0, len(plan.Delete))
+
+	plan = planS3(files, objects, true)
+	sort.Strings(plan.Delete)
+	equals(t, []string{"gone.html"}, plan.Delete)
+}
+
+func TestInvalidationPaths(t *testing.T) {
+	equals(t, []string{"/index.html", "/", "/blog/post.html", "/blog/index.html", "/blog/"},
+		invalidationPaths([]string{"index.html", "blog/post.html", "blog/index.html"}))
+
+	many := make([]string, 101)
+	equals(t, []string{"/*"}, invalidationPaths(many))
+}
@@ -0,0 +1,99 @@
+package sitegen
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	sanitizePlaceholderRegex = regexp.MustCompile(`sitegencode(\d+)x`)
+	safeCodeAttributeRegex   = regexp.MustCompile(`^[^"<>&\\]*$`)
+)
+
+// Attributes of code blocks that survive sanitizing.
+var codeAttributes = []string{"language", "title", "linenos", "linenostart", "hl_lines", "class"}
+
+// SanitizeConfig configures the cleaning of HTML in content, for sites with
+// content from less trusted authors.
+type SanitizeConfig struct {
+	// Remove scripts, event handlers and other unsafe HTML from pages. The
+	// policy is that of user generated content on most sites: formatting,
+	// links, images and tables are fine, links to other sites get
+	// rel="nofollow".
+	Enabled bool `yaml:"enabled"`
+
+	// Pages that are sanitized, as glob patterns matched against their
+	// source path. All pages when empty.
+	Paths []string `yaml:"paths"`
+
+	// Extra elements that are allowed, e.g. [iframe].
+	AllowElements []string `yaml:"allowElements"`
+
+	// Extra attributes that are allowed, keyed by element, e.g. {iframe:
+	// [src, width, height]}.
+	AllowAttributes map[string][]string `yaml:"allowAttributes"`
+}
+
+// sanitizePolicy returns the policy content is sanitized with. Besides the
+// configured HTML, it allows the markup sitegen adds itself: classes, ids,
+// task list checkboxes and references to other pages.
+func (s *Site) sanitizePolicy() *bluemonday.Policy {
+	s.policyOnce.Do(func() {
+		config := s.Config.Sanitize
+		p := bluemonday.UGCPolicy()
+		p.RequireNoFollowOnLinks(false)
+		p.RequireNoFollowOnFullyQualifiedLinks(true)
+		p.AllowAttrs("class").Globally()
+		p.AllowAttrs("aria-hidden").OnElements("a")
+		p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+		p.AllowAttrs("disabled", "checked").OnElements("input")
+		p.AllowURLSchemes("sitegen")
+		p.AllowElements(config.AllowElements...)
+		for element, attrs := range config.AllowAttributes {
+			p.AllowAttrs(attrs...).OnElements(element)
+		}
+		s.policy = p
+	})
+	return s.policy
+}
+
+// sanitize cleans up the HTML of a page, when it should be. Code blocks
+// are kept aside, their content is escaped when they're highlighted.
+func (s *Site) sanitize(sourcePath string, content []byte) []byte {
+	config := s.Config.Sanitize
+	if !config.Enabled || (len(config.Paths) > 0 && !matchesAny(config.Paths, sourcePath)) {
+		return content
+	}
+
+	blocks := make([][]byte, 0)
+	content = codeRegex.ReplaceAllFunc(content, func(m []byte) []byte {
+		blocks = append(blocks, m)
+		return []byte("sitegencode" + strconv.Itoa(len(blocks)-1) + "x")
+	})
+
+	content = s.sanitizePolicy().SanitizeBytes(content)
+
+	return sanitizePlaceholderRegex.ReplaceAllFunc(content, func(m []byte) []byte {
+		i, _ := strconv.Atoi(string(sanitizePlaceholderRegex.FindSubmatch(m)[1]))
+		if i >= len(blocks) {
+			return m
+		}
+		parts := codeRegex.FindSubmatch(blocks[i])
+		attrs := parseAttributes(string(parts[1]))
+
+		out := &bytes.Buffer{}
+		out.WriteString("<highlight")
+		for _, key := range codeAttributes {
+			if value, ok := attrs[key]; ok && safeCodeAttributeRegex.MatchString(value) {
+				out.WriteString(" " + key + `="` + value + `"`)
+			}
+		}
+		out.WriteString(">")
+		out.Write(parts[2])
+		out.WriteString("</highlight>")
+		return out.Bytes()
+	})
+}
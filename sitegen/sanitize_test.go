@@ -0,0 +1,31 @@
+package sitegen
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	config := DefaultConfig()
+	config.Sanitize = SanitizeConfig{
+		Enabled:         true,
+		Paths:           []string{"community/**"},
+		AllowElements:   []string{"iframe"},
+		AllowAttributes: map[string][]string{"iframe": {"src"}},
+	}
+	site := NewSite(config)
+
+	input := []byte(`<p class="lead" onclick="evil()">Hi <script>alert(1)</script><a href="javascript:x()">x</a> ` +
+		`<a href="sitegen:relref:other.md">other</a> <a href="https://example.org/">ext</a></p>` +
+		`<iframe src="https://example.org/embed"></iframe>` +
+		`<highlight language="html" title="index.html">` + "<script>\nalert(1)\n</script>" + `</highlight>` +
+		`<highlight language='x" onclick="y'>code</highlight>`)
+
+	equals(t, string(site.sanitize("community/post.md", input)),
+		`<p class="lead">Hi x `+
+			`<a href="sitegen:relref:other.md">other</a> <a href="https://example.org/" rel="nofollow">ext</a></p>`+
+			`<iframe src="https://example.org/embed"></iframe>`+
+			`<highlight language="html" title="index.html">`+"<script>\nalert(1)\n</script>"+`</highlight>`+
+			`<highlight>code</highlight>`)
+
+	equals(t, string(site.sanitize("blog/post.md", input)), string(input))
+}
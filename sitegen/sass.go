@@ -0,0 +1,67 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+func isSassFile(filename string) bool {
+	ext := path.Ext(filename)
+	return ext == ".scss" || ext == ".sass"
+}
+
+// prepareSass renames Sass files to the CSS they compile to (so they're
+// found as such by asset and the fingerprint patterns) and drops
+// partials (files starting with an underscore), which are only imported.
+func (s *Site) prepareSass(root *ContentItem) {
+	if !s.Config.Sass.Enabled {
+		return
+	}
+
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		children := c.Children[:0]
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+			} else if v.Type == Asset && isSassFile(v.Filename) {
+				if strings.HasPrefix(v.Filename, "_") {
+					continue
+				}
+				v.Filename = strings.TrimSuffix(v.Filename, path.Ext(v.Filename)) + ".css"
+				v.Path = path.Join(path.Dir(v.Path), v.Filename)
+			}
+			children = append(children, v)
+		}
+		c.Children = children
+	}
+	walk(root)
+}
+
+// compileSass runs a Sass file through the sass compiler. Production builds
+// are compressed, other environments get an embedded source map.
+func (s *Site) compileSass(filename string) ([]byte, error) {
+	config := s.Config.Sass
+	args := make([]string, 0)
+	if s.isProduction() {
+		args = append(args, "--style=compressed", "--no-source-map")
+	} else {
+		args = append(args, "--embed-source-map")
+	}
+	for _, p := range config.LoadPaths {
+		args = append(args, "--load-path="+p)
+	}
+	args = append(args, filename)
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(config.Command, args...)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sass failed for %s: %s\n%s", filename, err, stderr.String())
+	}
+	return out, nil
+}
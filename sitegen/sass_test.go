@@ -0,0 +1,75 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSass(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	// Stand-in for sass that prints its arguments.
+	command := filepath.Join(dir, "fake-sass")
+	ok(t, ioutil.WriteFile(command, []byte("#!/bin/sh\necho \"$@\"\n"), 0755))
+
+	content := filepath.Join(dir, "content")
+	ok(t, os.MkdirAll(content, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "style.scss"), []byte("a { b: c }"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(content, "_vars.scss"), []byte("$x: 1;"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	config.Sass = SassConfig{Enabled: true, Command: command, LoadPaths: []string{"lib"}}
+	site := NewSite(config)
+	site.BuildInfo.Environment = "production"
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.prepareSass(root)
+
+	equals(t, len(root.Children), 1)
+	style := root.Children[0]
+	equals(t, style.Filename, "style.css")
+	equals(t, style.Path, "style.css")
+	assert(t, site.transformsAsset(style), "Sass should be compiled")
+
+	out, err := site.assetData(style)
+	ok(t, err)
+	equals(t, string(out), "--style=compressed --no-source-map --load-path=lib "+style.FullPath+"\n")
+
+	site.BuildInfo.Environment = "development"
+	out, err = site.assetData(style)
+	ok(t, err)
+	equals(t, string(out), "--embed-source-map --load-path=lib "+style.FullPath+"\n")
+}
+
+func TestSassAsset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "css"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "css", "style.scss"), []byte("a { b: c }"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Sass = SassConfig{Enabled: true, Command: "true"}
+	config.Fingerprint = []string{"*.css"}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.prepareSass(root)
+	ok(t, site.fingerprintAssets(root))
+
+	u, err := site.assetURL("css/style.css")
+	ok(t, err)
+	assert(t, strings.HasPrefix(u, "/css/style.") && strings.HasSuffix(u, ".css") && u != "/css/style.css", "Expected fingerprinted CSS: %s", u)
+	_, err = site.assetURL("css/style.scss")
+	assert(t, err != nil, "Unexpected Sass asset")
+}
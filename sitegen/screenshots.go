@@ -0,0 +1,81 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ScreenshotConfig configures a tool that takes a screenshot of every page
+// that changed in a build, e.g. for visual regression testing.
+type ScreenshotConfig struct {
+	// Command to run for each page. {file} is replaced by the generated
+	// HTML file, {url} by the URL of the page and {output} by the image to
+	// write.
+	Command []string `yaml:"command"`
+
+	// Directory the screenshots are stored in.
+	ReportDir string `yaml:"reportDir"`
+}
+
+// markChanged records a page whose output differs from the previous build.
+func (s *Site) markChanged(c *ContentItem) {
+	s.changedLock.Lock()
+	defer s.changedLock.Unlock()
+	s.changed = append(s.changed, c)
+}
+
+// Changed returns the pages whose output changed in the last build.
+func (s *Site) Changed() []*ContentItem {
+	s.changedLock.Lock()
+	defer s.changedLock.Unlock()
+	return append([]*ContentItem(nil), s.changed...)
+}
+
+// takeScreenshots runs the screenshot command for every changed page.
+func (s *Site) takeScreenshots() error {
+	config := s.Config.Screenshots
+	if len(config.Command) == 0 {
+		return nil
+	}
+
+	changed := s.Changed()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	log.Println("==> Taking screenshots")
+	for _, c := range changed {
+		file, err := filepath.Abs(filepath.Join(s.Config.OutputDir, filepath.FromSlash(c.OutputPath())))
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(c.OutputPath(), filepath.Ext(c.OutputPath())) + ".png"
+		output := filepath.Join(config.ReportDir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(output), 0755)
+		if err != nil {
+			return err
+		}
+
+		replacer := strings.NewReplacer("{file}", file, "{url}", c.Url, "{output}", output)
+		args := make([]string, len(config.Command))
+		for i, arg := range config.Command {
+			args[i] = replacer.Replace(arg)
+		}
+
+		log.Printf(" -> %s\n", output)
+		stderr := &bytes.Buffer{}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stderr = stderr
+		err = cmd.Run()
+		if err != nil {
+			return fmt.Errorf("Screenshot of %s failed: %s\n%s", c.Url, err, stderr.String())
+		}
+	}
+	return nil
+}
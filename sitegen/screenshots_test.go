@@ -0,0 +1,47 @@
+package sitegen
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScreenshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	config := DefaultConfig()
+	config.OutputDir = filepath.Join(dir, "output")
+	config.Screenshots = ScreenshotConfig{
+		Command:   []string{"cp", "{file}", "{output}"},
+		ReportDir: filepath.Join(dir, "reports"),
+	}
+	site := NewSite(config)
+	site.templates = template.Must(template.New("page").Parse(`{{.Metadata.Title}}`))
+	ok(t, os.MkdirAll(config.OutputDir, 0755))
+
+	page := &ContentItem{
+		Site:     site,
+		Filename: "index.html",
+		Path:     "index.html",
+		Type:     Content,
+		Metadata: Metadata{Title: "Home", Template: "page"},
+	}
+	out := filepath.Join(config.OutputDir, "index.html")
+
+	ok(t, page.WriteContent(out))
+	equals(t, len(site.Changed()), 1)
+	ok(t, site.takeScreenshots())
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "reports", "index.png"))
+	ok(t, err)
+	equals(t, string(data), "Home")
+
+	// Unchanged output doesn't count.
+	site.changed = nil
+	ok(t, page.WriteContent(out))
+	equals(t, len(site.Changed()), 0)
+}
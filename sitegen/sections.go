@@ -0,0 +1,72 @@
+package sitegen
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Section is a top-level directory of the content, with the pages in it.
+type Section struct {
+	Name string
+
+	// Page for the directory itself (its index.html), if any.
+	Index *ContentItem
+
+	// Pages in the section, at any depth, newest first.
+	Pages []*ContentItem
+}
+
+// Title returns the title of the index page of the section, or its name
+// made readable.
+func (s *Section) Title() string {
+	if s.Index != nil && s.Index.Metadata.Title != "" {
+		return s.Index.Metadata.Title
+	}
+	return humanizeFilename(path.Join(s.Name, "index"))
+}
+
+// Url returns the URL of the section.
+func (s *Section) Url() string {
+	if s.Index != nil {
+		return s.Index.Url
+	}
+	return "/" + s.Name + "/"
+}
+
+// section returns the section of an item: the top-level directory it's in,
+// not counting the directory of its language. Items in the root have none.
+func (s *Site) section(c *ContentItem) string {
+	p := c.Path
+	if top := sectionName(p); top != "" && s.Config.Languages[top] != nil {
+		p = strings.TrimPrefix(p, top+"/")
+	}
+	return sectionName(p)
+}
+
+// buildSections collects the sections of the site, sorted by name.
+func (s *Site) buildSections(root *ContentItem) {
+	sections := make(map[string]*Section)
+	for _, page := range root.listedPages() {
+		if page.Section == "" {
+			continue
+		}
+		section, ok := sections[page.Section]
+		if !ok {
+			section = &Section{Name: page.Section}
+			sections[page.Section] = section
+		}
+		if path.Dir(page.OutputPath()) == section.Name && path.Base(page.OutputPath()) == "index.html" {
+			section.Index = page
+			continue
+		}
+		section.Pages = append(section.Pages, page)
+	}
+
+	s.Sections = make([]*Section, 0, len(sections))
+	for _, section := range sections {
+		sortByDate(section.Pages)
+		s.Sections = append(s.Sections, section)
+	}
+	sort.Slice(s.Sections, func(i, j int) bool { return s.Sections[i].Name < s.Sections[j].Name })
+}
@@ -0,0 +1,52 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSections(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog", "2024"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "docs"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "nl", "blog"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "index.md"), []byte("# Home"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "index.md"), []byte("# The blog"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "old.md"), []byte("---\ndate: 2023-01-01\n---\nOld"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "2024", "new.md"), []byte("---\ndate: 2024-01-01\n---\nNew"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "docs", "intro.md"), []byte("# Intro"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "nl", "blog", "post.md"), []byte("# Post"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	config.Languages = map[string]*LanguageConfig{"en": {}, "nl": {}}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	site.assignLanguages(root)
+	root.Process()
+	site.buildSections(root)
+
+	equals(t, root.child("index.html").Section, "")
+	equals(t, root.child("blog").child("2024").child("new.html").Section, "blog")
+	equals(t, root.child("nl").child("blog").child("post.html").Section, "blog")
+
+	equals(t, len(site.Sections), 2)
+	blog, docs := site.Sections[0], site.Sections[1]
+	equals(t, blog.Name, "blog")
+	equals(t, blog.Title(), "The blog")
+	equals(t, blog.Url(), "/blog/")
+	equals(t, blog.Pages, []*ContentItem{
+		root.child("blog").child("2024").child("new.html"),
+		root.child("blog").child("old.html"),
+		root.child("nl").child("blog").child("post.html"),
+	})
+	equals(t, docs.Title(), "Docs")
+	equals(t, docs.Url(), "/docs/")
+}
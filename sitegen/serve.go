@@ -0,0 +1,200 @@
+package sitegen
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// How often the sources are checked for changes while serving.
+const watchInterval = time.Second
+
+// Serve builds the site, serves the output directory over HTTP and rebuilds
+// whenever the sources change. Changes to existing templates only re-render
+// the pages they affect.
+func (s *Site) Serve(addr string) error {
+	// Always to the output directory, even with an archive configured:
+	// that's what's served.
+	err := s.build()
+	if err != nil {
+		return err
+	}
+
+	go s.watch()
+
+	log.Printf("==> Serving on http://%s/\n", addr)
+	return http.ListenAndServe(addr, s.handler())
+}
+
+func (s *Site) watch() {
+	templates := newWatcher(s.Config.TemplateDir, s.Config.I18nDir)
+	sources := append([]string{}, s.Config.ContentDirs...)
+	for _, v := range s.Config.DataPages {
+		sources = append(sources, v.Source)
+	}
+	content := newWatcher(sources...)
+
+	for {
+		time.Sleep(watchInterval)
+
+		contentChanged := content.changed()
+		modified, added := templates.changes()
+
+		var err error
+		if contentChanged {
+			log.Println("==> Content changed, rebuilding")
+			err = s.build()
+		} else if added || len(modified) > 0 {
+			if files, ok := s.changedTemplates(modified, added); ok {
+				log.Println("==> Templates changed, re-rendering")
+				err = s.reloadTemplates(files)
+			} else {
+				log.Println("==> Templates changed, rebuilding")
+				err = s.build()
+			}
+		}
+		if err != nil {
+			log.Printf("Rebuild failed: %s\n", err)
+		}
+	}
+}
+
+// changedTemplates turns the files that changed in the template and i18n
+// directories into the template files to re-render the pages of. It returns
+// false when the change can't be pinned to those pages and the site has to
+// be built again: templates that were added or removed, translations and
+// render hooks, which are used while parsing Markdown.
+func (s *Site) changedTemplates(modified []string, added bool) ([]string, bool) {
+	if added {
+		return nil, false
+	}
+	files := make([]string, 0, len(modified))
+	for _, p := range modified {
+		if !isWithin(p, s.Config.TemplateDir) {
+			return nil, false
+		}
+		rel, err := filepath.Rel(s.Config.TemplateDir, p)
+		if err != nil {
+			return nil, false
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasPrefix(rel, markupTemplateDir+"/") {
+			return nil, false
+		}
+		files = append(files, rel)
+	}
+	return files, true
+}
+
+// reloadTemplates re-parses the templates and renders the pages that use
+// one of the changed template files again, as well as the pages that are
+// now rendered with another template.
+func (s *Site) reloadTemplates(changed []string) error {
+	t, err := s.loadTemplates()
+	if err != nil {
+		return categorize(TemplateError, err)
+	}
+	s.templates = t
+	s.textTemplates, err = s.loadTextTemplates()
+	if err != nil {
+		return categorize(TemplateError, err)
+	}
+	s.changed = nil
+
+	written := make([]string, 0)
+	for _, page := range s.root.allPages() {
+		if !page.usesTemplate(changed) && !page.lookupChanged() {
+			continue
+		}
+		out := filepath.Join(s.Config.OutputDir, filepath.FromSlash(page.OutputPath()))
+		err := page.write(out)
+		if err != nil {
+			return err
+		}
+		if page.hasOutputFormat("html") {
+			written = append(written, out)
+		}
+		for _, v := range page.outputs {
+			out := filepath.Join(s.Config.OutputDir, filepath.FromSlash(v.OutputPath()))
+			err := v.write(out)
+			if err != nil {
+				return err
+			}
+			written = append(written, out)
+		}
+	}
+	err = s.checkLinks(s.root)
+	if err != nil {
+		return err
+	}
+	err = s.exportPDFs()
+	if err != nil {
+		return err
+	}
+	err = s.compressFiles(written)
+	if err != nil {
+		return err
+	}
+	return s.takeScreenshots()
+}
+
+// watcher detects changes to the files in a set of directories by
+// comparing modification times.
+type watcher struct {
+	dirs  []string
+	state map[string]time.Time
+}
+
+func newWatcher(dirs ...string) *watcher {
+	w := &watcher{dirs: dirs}
+	w.state = w.scan()
+	return w
+}
+
+// changed reports whether any files were added, removed or modified since
+// the last call.
+func (w *watcher) changed() bool {
+	modified, added := w.changes()
+	return added || len(modified) > 0
+}
+
+// changes returns the files that were modified since the last call, and
+// whether any were added or removed.
+func (w *watcher) changes() (modified []string, added bool) {
+	state := w.scan()
+	defer func() { w.state = state }()
+
+	for path, mtime := range state {
+		old, ok := w.state[path]
+		if !ok {
+			added = true
+		} else if !old.Equal(mtime) {
+			modified = append(modified, path)
+		}
+	}
+	for path := range w.state {
+		if _, ok := state[path]; !ok {
+			added = true
+		}
+	}
+	sort.Strings(modified)
+	return modified, added
+}
+
+func (w *watcher) scan() map[string]time.Time {
+	state := make(map[string]time.Time)
+	for _, dir := range w.dirs {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			state[path] = info.ModTime()
+			return nil
+		})
+	}
+	return state
+}
@@ -0,0 +1,157 @@
+package sitegen
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "page.html")
+	ok(t, ioutil.WriteFile(file, []byte("a"), 0644))
+
+	w := newWatcher(dir)
+	assert(t, !w.changed(), "Unexpected change")
+
+	future := time.Now().Add(time.Hour)
+	ok(t, os.Chtimes(file, future, future))
+	assert(t, w.changed(), "Modification not detected")
+	assert(t, !w.changed(), "Unexpected change")
+
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "other.html"), []byte("b"), 0644))
+	assert(t, w.changed(), "New file not detected")
+
+	ok(t, os.Remove(file))
+	assert(t, w.changed(), "Removal not detected")
+}
+
+func TestWatcherChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "page.html")
+	ok(t, ioutil.WriteFile(file, []byte("a"), 0644))
+	w := newWatcher(dir)
+
+	future := time.Now().Add(time.Hour)
+	ok(t, os.Chtimes(file, future, future))
+	modified, added := w.changes()
+	equals(t, modified, []string{file})
+	assert(t, !added, "Unexpected new file")
+
+	ok(t, os.Remove(file))
+	_, added = w.changes()
+	assert(t, added, "Removal not detected")
+}
+
+func TestReloadChangedTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"content/a.md":                 "---\ntitle: A\ntemplate: post\n---\nA\n",
+		"content/b.md":                 "---\ntitle: B\n---\nB\n",
+		"templates/post.html":          `{{template "partials/head.html" .}}post {{.Content}}`,
+		"templates/page.html":          `page {{.Content}}`,
+		"templates/partials/head.html": `<title>{{.Metadata.Title}}</title>`,
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "out")
+	site := NewSite(config)
+	ok(t, site.Build())
+
+	page := site.root.child("a.html")
+	assert(t, page.usesTemplate([]string{"partials/head.html"}), "Expected partial to be tracked")
+	assert(t, !page.usesTemplate([]string{"page.html"}), "Unexpected template")
+
+	// Only the page using the partial is rendered again.
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "partials", "head.html"), []byte(`<title>New</title>`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "page.html"), []byte(`changed`), 0644))
+	changed, precise := site.changedTemplates([]string{filepath.Join(config.TemplateDir, "partials", "head.html")}, false)
+	assert(t, precise, "Expected the change to be attributed")
+	ok(t, site.reloadTemplates(changed))
+
+	data, err := ioutil.ReadFile(filepath.Join(config.OutputDir, "a.html"))
+	ok(t, err)
+	assert(t, strings.Contains(string(data), "<title>New</title>"), "Expected new partial: %s", data)
+	data, err = ioutil.ReadFile(filepath.Join(config.OutputDir, "b.html"))
+	ok(t, err)
+	assert(t, strings.HasPrefix(string(data), "page"), "Unexpected re-render: %s", data)
+
+	// Anything else builds the site again.
+	_, precise = site.changedTemplates(nil, true)
+	assert(t, !precise, "Expected a rebuild for new templates")
+	_, precise = site.changedTemplates([]string{filepath.Join(config.I18nDir, "en.yaml")}, false)
+	assert(t, !precise, "Expected a rebuild for translations")
+	_, precise = site.changedTemplates([]string{filepath.Join(config.TemplateDir, "_markup", "render-link.html")}, false)
+	assert(t, !precise, "Expected a rebuild for render hooks")
+}
+
+func TestReloadTemplateLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"content/posts/a.md":  "A [b](b.html)\n",
+		"templates/page.html": `page {{.Content}}`,
+		"templates/defs.html": `{{define "note"}}note{{end}}`,
+	}
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		ok(t, os.MkdirAll(filepath.Dir(file), 0755))
+		ok(t, ioutil.WriteFile(file, []byte(data), 0644))
+	}
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "content")}
+	config.TemplateDir = filepath.Join(dir, "templates")
+	config.OutputDir = filepath.Join(dir, "out")
+	config.Compress.Gzip = true
+	config.Compress.MinSize = 0
+	site := NewSite(config)
+	ok(t, site.Build())
+
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(config.OutputDir, "posts", name))
+		ok(t, err)
+		return string(data)
+	}
+	readGzip := func(name string) string {
+		f, err := os.Open(filepath.Join(config.OutputDir, "posts", name+".gz"))
+		ok(t, err)
+		defer f.Close()
+		z, err := gzip.NewReader(f)
+		ok(t, err)
+		data, err := ioutil.ReadAll(z)
+		ok(t, err)
+		return string(data)
+	}
+	assert(t, strings.HasPrefix(read("a.html"), "page"), "Unexpected output: %s", read("a.html"))
+
+	// A more specific template in a file the page didn't use takes over,
+	// and the compressed copy follows.
+	ok(t, ioutil.WriteFile(filepath.Join(config.TemplateDir, "defs.html"), []byte(`{{define "posts/single"}}single {{.Content}}{{end}}`), 0644))
+	ok(t, site.reloadTemplates([]string{"defs.html"}))
+	assert(t, strings.HasPrefix(read("a.html"), "single"), "Lookup change missed: %s", read("a.html"))
+	equals(t, readGzip("a.html"), read("a.html"))
+
+}
@@ -3,96 +3,461 @@ package sitegen
 import (
 	"bytes"
 	"errors"
+	"flag"
 	"fmt"
 	"html/template"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	texttemplate "text/template"
 	"time"
 	"unicode"
 
 	"github.com/cheggaaa/pb"
+	"github.com/microcosm-cc/bluemonday"
 	"github.com/rubenv/pygmentize"
 	"github.com/russross/blackfriday"
+	"github.com/tdewolff/minify/v2"
 	"gopkg.in/yaml.v2"
 )
 
 func Start() {
-	templates = template.Must(template.ParseGlob("templates/*.html"))
+	flag.Parse()
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		exit(err)
+	}
+	if outputDir != "" {
+		config.OutputDir = filepath.Clean(outputDir)
+	}
+	if templateDir != "" {
+		config.TemplateDir = filepath.Clean(templateDir)
+	}
+	if dirs := splitDirs(contentDirs); len(dirs) > 0 {
+		config.ContentDirs = dirs
+	}
+	if archive != "" {
+		config.Archive = archive
+	}
+	if workers > 0 {
+		config.Workers = workers
+	}
+	if baseURL != "" {
+		err = checkBaseURL(baseURL)
+		if err != nil {
+			exit(err)
+		}
+		config.BaseURL = baseURL
+	}
+	site := NewSite(config)
+	site.env = environment
+
+	switch cmd := flag.Arg(0); cmd {
+	case "", "build":
+		if diffMode {
+			err = site.Diff(os.Stdout, diffHTML)
+		} else {
+			err = site.Build()
+		}
+	case "clean":
+		err = site.Clean()
+	case "serve":
+		err = site.Serve(serveAddr)
+	case "new":
+		err = site.NewContent(flag.Arg(1))
+	case "check":
+		err = site.Check(flag.Args()[1:])
+	case "deploy":
+		err = site.Deploy(flag.Args()[1:])
+	default:
+		err = categorize(ConfigError, fmt.Errorf("Unknown command: %s", cmd))
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+// splitDirs turns a comma-separated list of folders, as given on the command
+// line, into their paths. Spaces around entries and empty entries are
+// ignored ("content, shared/content,").
+func splitDirs(list string) []string {
+	dirs := make([]string, 0)
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			dirs = append(dirs, filepath.Clean(v))
+		}
+	}
+	return dirs
+}
+
+// exit stops the program, with an exit code that tells what went wrong.
+func exit(err error) {
+	log.Printf("%s: %s", Category(err), err)
+	os.Exit(ExitCode(err))
+}
+
+// Site is a single site being generated.
+type Site struct {
+	Config     *Config
+	BuildInfo  BuildInfo
+	Taxonomies map[string]Taxonomy
+	Sections   []*Section
+
+	root      *ContentItem
+	templates *template.Template
+	redirects []Redirect
+	assets    map[string]*ContentItem
+	fragments map[string]*ContentItem
+	minifier  *minify.M
+
+	// Templates of output formats that aren't HTML.
+	textTemplates *texttemplate.Template
+
+	integrity     map[string]string
+	integrityLock sync.Mutex
+
+	changed     []*ContentItem
+	changedLock sync.Mutex
+
+	translations      map[string][]*ContentItem
+	translationTables map[string]map[string]translation
+
+	images     map[string]*imageVariant
+	imagesLock sync.Mutex
+
+	addedPages []addedPage
+
+	policy     *bluemonday.Policy
+	policyOnce sync.Once
+
+	// Where the build writes to, the output directory when nil.
+	out output
+
+	// Whether the build is packed into the archive, rather than written to
+	// the output directory.
+	archive bool
+
+	// Build environment from -env, which beats $SITEGEN_ENV and the
+	// environment setting.
+	env string
+}
+
+func NewSite(config *Config) *Site {
+	return &Site{
+		Config:   config,
+		minifier: newMinifier(),
+	}
+}
+
+// Build generates the full site into the output directory, or into the
+// archive when one is configured.
+func (s *Site) Build() error {
+	if s.Config.Archive == "" {
+		return s.build()
+	}
+
+	s.out = newMemoryOutput(s.Config.OutputDir)
+	s.archive = true
+	defer func() {
+		s.out = nil
+		s.archive = false
+	}()
+	return s.build()
+}
+
+// BuildFS generates the full site like Build, but into memory rather than
+// the output directory, and returns the generated files. Steps that work on
+// the files on disk (compression, archives, PDFs and screenshots) are
+// skipped.
+func (s *Site) BuildFS() (fs.FS, error) {
+	out := newMemoryOutput(s.Config.OutputDir)
+	s.out = out
+	defer func() { s.out = nil }()
+
+	err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	return out.files, nil
+}
+
+func (s *Site) build() error {
+	processError = nil
+
+	// Hooks are for builds that end up on disk, in-memory builds (BuildFS,
+	// -diff) leave them out.
+	if !s.inMemory() || s.archive {
+		err := runBuildHooks(beforeBuildHooks, s)
+		if err != nil {
+			return err
+		}
+	}
+
+	info, err := s.buildInfo()
+	if err != nil {
+		return categorize(ConfigError, err)
+	}
+	s.BuildInfo = info
+
+	s.templates, err = s.loadTemplates()
+	if err != nil {
+		return categorize(TemplateError, err)
+	}
+	s.textTemplates, err = s.loadTextTemplates()
+	if err != nil {
+		return categorize(TemplateError, err)
+	}
+	s.translationTables, err = s.loadTranslations()
+	if err != nil {
+		return categorize(ParseError, err)
+	}
+	s.integrity = nil
+	s.assets = nil
+	s.changed = nil
+	s.images = nil
 
 	// Crawl the filesystem tree.
 	log.Println("==> Crawling")
-	content, err := crawlContent()
+	content, err := s.crawlContent()
+	if err != nil {
+		return categorize(ParseError, err)
+	}
+	s.removeDrafts(content)
+
+	err = s.addGitInfo(content)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	s.collectResources(content)
+	s.assignLanguages(content)
+	err = s.applyURLOverrides(content)
+	if err != nil {
+		return categorize(ParseError, err)
+	}
+	err = s.addDataPages(content)
+	if err != nil {
+		return categorize(ParseError, err)
+	}
+	err = s.addPages(content)
+	if err != nil {
+		return categorize(ParseError, err)
+	}
+	err = s.loadRedirects(content)
+	if err != nil {
+		return categorize(ParseError, err)
+	}
+
+	s.collectFragments(content)
+	s.prepareSass(content)
+	err = s.processAssets(content)
+	if err != nil {
+		return err
+	}
+
+	s.addBundles(content)
+	err = s.fingerprintAssets(content)
+	if err != nil {
+		return err
+	}
+	s.addChecksums(content)
+	err = s.addImageFormats(content)
+	if err != nil {
+		return err
+	}
+	err = s.buildGalleries(content)
+	if err != nil {
+		return err
 	}
+	s.addIndexPages(content)
 
-	// Wait for parsing
-	log.Println("==> Parsing")
-	if parseError != nil {
-		log.Fatal(parseError)
+	s.buildTaxonomies(content)
+	err = s.addOGImages(content)
+	if err != nil {
+		return err
 	}
+	s.addOutputs(content)
 
 	// Allow processing metadata
-	if processor != nil {
-		log.Println("==> Processing")
-		content.Process()
-		if processError != nil {
-			log.Fatal(processError)
-		}
+	log.Println("==> Processing")
+	content.Process()
+	if processError != nil {
+		return processError
+	}
+	s.buildSections(content)
+	s.root = content
+	err = s.resolveRefs(content)
+	if err != nil {
+		return categorize(LinkError, err)
+	}
+
+	err = s.addAliases(content)
+	if err != nil {
+		return err
+	}
+	err = s.checkRedirects(content)
+	if err != nil {
+		return categorize(LinkError, err)
+	}
+	err = s.addRedirects(content)
+	if err != nil {
+		return err
+	}
+	err = s.addGitHubPagesFiles(content)
+	if err != nil {
+		return err
 	}
+	s.addRobotsTxt(content)
 
 	// Generate the output
 	log.Println("==> Generating")
-	err = os.MkdirAll("static", 0755)
+	err = s.output().MkdirAll(s.Config.OutputDir)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	queue := NewContentQueue()
-	content.Write("static", queue)
-	queue.Wait()
-	if generateError != nil {
-		log.Printf("Failed to generate: %#v\n", generateError.Error())
+	content.Write(s.Config.OutputDir, queue)
+	err = queue.Wait()
+	if err != nil {
+		return fmt.Errorf("Failed to generate: %w", err)
+	}
+	err = s.checkLinks(s.root)
+	if err != nil {
+		return err
 	}
+	if !s.inMemory() {
+		err = s.exportPDFs()
+		if err != nil {
+			return err
+		}
+		err = s.compressOutput()
+		if err != nil {
+			return err
+		}
+		err = s.takeScreenshots()
+		if err != nil {
+			return err
+		}
+	}
+	if s.archive {
+		err = s.archiveOutput(s.out.(*memoryOutput).files)
+		if err != nil {
+			return err
+		}
+	}
+	if s.inMemory() && !s.archive {
+		return nil
+	}
+	return runBuildHooks(afterBuildHooks, s)
 }
 
 var (
-	parseError    error = nil
-	processError  error = nil
-	generateError error = nil
-	templates     *template.Template
+	processError error = nil
 
-	processor MetadataProcessor
-	queue     *ContentQueue
+	processors = make(map[MetadataPhase][]MetadataProcessor)
 )
 
 type ContentItem struct {
-	Filename string
-	FullPath string
-	Url      string
-	Type     ContentType
-	Content  template.HTML
-	Children []*ContentItem
-	Metadata Metadata
-	Extra    interface{}
+	Site      *Site
+	Filename  string
+	FullPath  string
+	Path      string // Source path, relative to the content directory
+	Url       string
+	Permalink string // Full URL, if baseURL is configured
+	Lang      string // Language code of pages
+	Section   string // Top-level content directory, "" in the root
+	Type      ContentType
+	Content   template.HTML
+	Children  []*ContentItem
+	Metadata  Metadata
+	Extra     interface{}
+
+	// Last change of the source file: the last commit with gitInfo
+	// enabled, the modification time otherwise.
+	Lastmod time.Time
+
+	// Author and hash of the last commit, with gitInfo enabled.
+	GitAuthor string
+	GitCommit string
+
+	// Set on listing pages that are split over multiple pages.
+	Pager *Pager
+
+	// Set on generated gallery pages.
+	Gallery *Gallery
+
+	// Files of a page bundle, set on its index page.
+	Resources Resources
+
+	// Generated social card, see OGImage.
+	ogImage *ContentItem
+
+	// Other output formats of the page, see Output.
+	outputs map[string]*ContentItem
+
+	// Pages with the same key are translations of each other.
+	translationKey string
+
+	// Produces the output of Generated items.
+	generate func() ([]byte, error)
+
+	// Output of an AssetProcessor, for assets that matched one.
+	processed []byte
+
+	// Contents of an asset as written, once worked out (see
+	// fingerprintAssets).
+	written []byte
+
+	// Body of content files as written, without the front matter.
+	source []byte
+
+	// Path of the content file, relative to the content folder, even when
+	// the page was moved (see url).
+	sourcePath string
+
+	// Assets that are symlinks, written as symlinks (symlinks: copy).
+	symlink bool
+
+	// Entry in the write queue, which tracks the progress of copies.
+	queued *ContentQueueItem
+
+	// Template files the page was rendered with, so watch mode can
+	// re-render only the pages that use a changed template.
+	templateFiles map[string]bool
+
+	// Template picked for each output format, "" for the built-in one, so
+	// watch mode notices when another template takes over.
+	templateLookups map[string]string
+
+	// Values stored with Set.
+	lock   sync.RWMutex
+	values map[string]interface{}
 }
 
 type Metadata struct {
 	Title    string
 	Template string
-	Date     time.Time
-}
+	Date     time.Time `yaml:"-"`
 
-type metadataTime struct {
-	Title    string
-	Template string
-	Date     string
+	// Fragments are only included in other pages, see collectFragments.
+	Fragment bool
+
+	// Titles and parameters for the files of a page bundle.
+	Resources []ResourceMetadata
+
+	// All front matter fields, including the ones above.
+	Params map[string]interface{} `yaml:"-"`
 }
 
 type ContentType int
@@ -101,22 +466,51 @@ const (
 	Content ContentType = iota
 	Directory
 	Asset
+	Generated
 )
 
-func crawlContent() (*ContentItem, error) {
-	return readDir(".", "content")
+// crawlContent reads all content directories into a single tree.
+func (s *Site) crawlContent() (*ContentItem, error) {
+	var root *ContentItem
+	for _, dir := range s.Config.ContentDirs {
+		tree, err := s.readDir(dir, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if root == nil {
+			root = tree
+		} else {
+			err = root.merge(tree)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	err := s.parseFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	return root, nil
 }
 
-func readDir(name, path string) (*ContentItem, error) {
-	fullPath := path + "/" + name
+func (s *Site) readDir(root, rel string) (*ContentItem, error) {
+	fullPath := filepath.Join(root, filepath.FromSlash(rel))
 	files, err := ioutil.ReadDir(fullPath)
 	if err != nil {
 		return nil, err
 	}
 
+	name := path.Base(normalizePath(rel))
+	if rel == "" {
+		name = "."
+	}
+
 	c := &ContentItem{
+		Site:     s,
 		Filename: name,
 		FullPath: fullPath,
+		Path:     normalizePath(rel),
 		Type:     Directory,
 		Children: make([]*ContentItem, 0),
 	}
@@ -125,24 +519,56 @@ func readDir(name, path string) (*ContentItem, error) {
 		var child *ContentItem
 
 		filename := v.Name()
-		if isContentFile(filename) {
-			parts := strings.Split(filename, ".")
+		childPath := filepath.Join(fullPath, filename)
+		childRel := path.Join(rel, filename)
+		outRel := normalizePath(childRel)
+		if !s.includeFile(outRel) {
+			continue
+		}
+		symlink := v.Mode()&os.ModeSymlink != 0
+		if symlink {
+			v, err = s.resolveSymlink(childPath, v)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			symlink = v.Mode()&os.ModeSymlink != 0
+		}
+
+		if symlink {
+			child = &ContentItem{
+				Site:     s,
+				Filename: path.Base(outRel),
+				FullPath: childPath,
+				Path:     outRel,
+				Type:     Asset,
+				symlink:  true,
+			}
+		} else if isContentFile(filename) {
+			parts := strings.Split(path.Base(outRel), ".")
 			outname := strings.Join(parts[0:len(parts)-1], ".") + ".html"
 			child = &ContentItem{
-				Filename: outname,
-				FullPath: fullPath + "/" + filename,
-				Type:     Content,
+				Site:       s,
+				Filename:   outname,
+				FullPath:   childPath,
+				Path:       outRel,
+				Type:       Content,
+				Lastmod:    v.ModTime().In(location()),
+				sourcePath: outRel,
 			}
-			child.Parse(fullPath + "/" + filename)
 		} else if v.IsDir() {
-			child, err = readDir(filename, fullPath)
+			child, err = s.readDir(root, childRel)
 			if err != nil {
 				return nil, err
 			}
 		} else {
 			child = &ContentItem{
-				Filename: filename,
-				FullPath: fullPath + "/" + filename,
+				Site:     s,
+				Filename: path.Base(outRel),
+				FullPath: childPath,
+				Path:     outRel,
 				Type:     Asset,
 			}
 		}
@@ -152,39 +578,102 @@ func readDir(name, path string) (*ContentItem, error) {
 	return c, nil
 }
 
-func isContentFile(filename string) bool {
-	return strings.HasSuffix(filename, ".html") || strings.HasSuffix(filename, ".md")
+// merge adds the children of another directory, read from a different
+// content root, into this one.
+func (c *ContentItem) merge(other *ContentItem) error {
+	for _, o := range other.Children {
+		existing := c.child(o.Filename)
+		if existing == nil {
+			c.Children = append(c.Children, o)
+			continue
+		}
+
+		if existing.Type != Directory || o.Type != Directory {
+			return fmt.Errorf("%s conflicts with %s", o.FullPath, existing.FullPath)
+		}
+		err := existing.merge(o)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+func (c *ContentItem) child(filename string) *ContentItem {
+	for _, v := range c.Children {
+		if v.Filename == filename {
+			return v
+		}
+	}
+	return nil
+}
+
+// OutputPath returns the slash-separated path of the generated file,
+// relative to the output directory.
+func (c *ContentItem) OutputPath() string {
+	if c.Type == Directory {
+		return c.Path
+	}
+	return path.Join(path.Dir(c.Path), c.Filename)
+}
+
+// splitContent separates the front matter from the body, using the default
+// delimiter.
 func splitContent(content []byte) (frontMatter, body []byte, err error) {
-	startDelim := []byte("---\n")
-	endDelim := []byte("\n---\n\n")
-	if bytes.HasPrefix(content, startDelim) {
-		endIndex := bytes.Index(content, endDelim)
-		if endIndex == -1 {
-			err = errors.New("No end delimiter found for metadata!")
-			return
+	return splitFrontMatter(content, "---")
+}
+
+// splitFrontMatter separates the front matter, enclosed in lines holding
+// delim, from the body. The closing line can also be "...". Both LF and CRLF
+// line endings are accepted and a blank line after the front matter is
+// dropped.
+func splitFrontMatter(content []byte, delim string) (frontMatter, body []byte, err error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	line, rest := nextLine(content)
+	if string(trimLineEnd(line)) != delim {
+		return nil, content, nil
+	}
+
+	start := len(content) - len(rest)
+	for pos := start; pos < len(content); {
+		line, rest = nextLine(content[pos:])
+		if l := string(trimLineEnd(line)); l == delim || l == "..." {
+			frontMatter = trimLineEnd(content[start:pos])
+			body = rest
+			if blank, after := nextLine(body); len(blank) > 0 && len(trimLineEnd(blank)) == 0 {
+				body = after
+			}
+			return frontMatter, body, nil
 		}
+		pos += len(line)
+	}
 
-		frontMatter = content[len(startDelim):endIndex]
-		body = content[endIndex+len(endDelim) : len(content)]
-	} else {
-		frontMatter = nil
-		body = content
+	return nil, nil, errors.New("No end delimiter found for metadata!")
+}
+
+// nextLine splits off the first line, including its line ending.
+func nextLine(b []byte) (line, rest []byte) {
+	i := bytes.IndexByte(b, '\n')
+	if i == -1 {
+		return b, nil
 	}
-	return
+	return b[:i+1], b[i+1:]
+}
+
+func trimLineEnd(b []byte) []byte {
+	return bytes.TrimRight(b, " \t\r\n")
 }
 
 func (c *ContentItem) parseContent(filename string) error {
-	printName := strings.TrimPrefix(filename, "content/.")
-	log.Printf(" -> %s\n", printName)
+	log.Printf(" -> %s\n", c.Path)
 
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
 
-	frontMatter, body, err := splitContent(data)
+	frontMatter, body, err := splitFrontMatter(data, c.Site.Config.FrontMatterDelimiter)
 	if err != nil {
 		return err
 	}
@@ -193,56 +682,104 @@ func (c *ContentItem) parseContent(filename string) error {
 		yaml.Unmarshal(frontMatter, &c.Metadata)
 	}
 
-	if c.Metadata.Template == "" {
-		c.Metadata.Template = "page"
-	}
+	c.source = body
+	body = replaceRefShortcodes(body)
 
-	var content []byte
-	if strings.HasSuffix(filename, ".md") {
-		content = RenderMarkdown(body)
-	} else {
-		content = body
+	content, err := c.Site.renderContent(formatFor(filename), body, c.sourcePath, c.Metadata)
+	if err != nil {
+		return err
 	}
-	c.Content = template.HTML(content)
+	c.Content = template.HTML(c.Site.sanitize(c.sourcePath, content))
 	return nil
 }
 
+// RenderMarkdown renders Markdown with the default settings.
 func RenderMarkdown(input []byte) []byte {
+	return renderMarkdown(input, MarkdownConfig{})
+}
+
+func renderMarkdown(input []byte, config MarkdownConfig) []byte {
+	extensions, htmlFlags := config.flags()
+	enabled := config.enabled()
+
 	// set up the HTML renderer
-	htmlFlags := 0
 	htmlFlags |= blackfriday.HTML_USE_XHTML
-	htmlFlags |= blackfriday.HTML_USE_SMARTYPANTS
-	htmlFlags |= blackfriday.HTML_SMARTYPANTS_FRACTIONS
-	htmlFlags |= blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
 	htmlFlags |= blackfriday.HTML_FOOTNOTE_RETURN_LINKS
+	returnLink := config.Footnotes.ReturnLink
+	if returnLink == "" {
+		returnLink = "↩"
+	}
 	renderer := &renderer{
+		config:     config,
+		taskLists:  enabled["taskLists"],
+		attributes: enabled["attributes"],
 		Html: blackfriday.HtmlRendererWithParameters(htmlFlags, "", "", blackfriday.HtmlRendererParameters{
-			FootnoteReturnLinkContents: "↩",
+			FootnoteReturnLinkContents: returnLink,
+			FootnoteAnchorPrefix:       config.footnotePrefix,
 		}).(*blackfriday.Html),
 	}
 
+	var math []mathSpan
+	if config.Math.Enabled {
+		input, math = protectMath(input)
+	}
+
 	// set up the parser
-	extensions := 0
-	extensions |= blackfriday.EXTENSION_NO_INTRA_EMPHASIS
-	extensions |= blackfriday.EXTENSION_TABLES
-	extensions |= blackfriday.EXTENSION_FENCED_CODE
-	extensions |= blackfriday.EXTENSION_AUTOLINK
-	extensions |= blackfriday.EXTENSION_STRIKETHROUGH
-	extensions |= blackfriday.EXTENSION_SPACE_HEADERS
-	extensions |= blackfriday.EXTENSION_HEADER_IDS
-	extensions |= blackfriday.EXTENSION_FOOTNOTES
+	if config.HeadingIDs || config.HeadingAnchors {
+		if enabled["headingIDs"] {
+			renderer.explicitIDs = explicitHeadingIDs(input, extensions)
+		}
+		extensions |= blackfriday.EXTENSION_AUTO_HEADER_IDS
+	}
 
-	return blackfriday.Markdown(input, renderer, extensions)
+	output := blackfriday.Markdown(input, renderer, extensions)
+	if config.Math.Enabled {
+		output = restoreMath(output, math)
+	}
+	if renderer.attributes {
+		output = addSpanAttributes(output, config.unsafeHTML())
+	}
+	if config.Emoji {
+		output = replaceEmoji(output, config.EmojiImages)
+	}
+	if enabled["smartypants"] {
+		// Checked by validate.
+		quotes, _ := config.quoteStyle()
+		output = localizeQuotes(output, quotes)
+	}
+	return output
 }
 
 type renderer struct {
 	*blackfriday.Html
+	config     MarkdownConfig
+	taskLists  bool
+	attributes bool
+
+	// Where the last block written to each buffer starts, for attribute
+	// lists.
+	lastBlock map[*bytes.Buffer]int
+
+	// The id set by hand on each heading, in order ("" for none), which
+	// generated ids make room for, and the ids handed out so far.
+	explicitIDs []string
+	headings    int
+	headingIDs  map[string]bool
 }
 
-func (r *renderer) BlockCode(out *bytes.Buffer, text []byte, lang string) {
+func (r *renderer) BlockCode(out *bytes.Buffer, text []byte, info string) {
+	defer r.markBlock(out, out.Len())
+	lang, options := parseCodeInfo(info)
+	if _, ok := r.config.Diagrams[lang]; ok {
+		r.BlockDiagram(out, text, lang)
+		return
+	}
+
 	out.WriteString("<highlight language=\"")
 	out.WriteString(lang)
-	out.WriteString("\">")
+	out.WriteString("\"")
+	writeCodeAttributes(out, options)
+	out.WriteString(">")
 
 	code := string(text)
 	code = strings.TrimRightFunc(code, unicode.IsSpace)
@@ -251,21 +788,23 @@ func (r *renderer) BlockCode(out *bytes.Buffer, text []byte, lang string) {
 	out.WriteString("</highlight>")
 }
 
-func (c *ContentItem) Parse(filename string) {
-	err := c.parseContent(filename)
-	if err != nil {
-		parseError = err
-	}
-}
-
 func (c *ContentItem) Process() {
-	c.Url = strings.TrimSuffix(strings.TrimPrefix(c.FullPath, "content/."), "index.html")
-	extra, err := processor(c)
+	c.Url = strings.TrimSuffix("/"+c.OutputPath(), "index.html")
+	if c.Type == Directory && !strings.HasSuffix(c.Url, "/") {
+		c.Url += "/"
+	}
+	if c.Site != nil {
+		if c.Type == Content && c.Lang == "" {
+			c.Lang = c.Site.Config.DefaultLanguage
+		}
+		c.Permalink = c.Site.permalink(c)
+		c.Section = c.Site.section(c)
+	}
+	err := runMetadataProcessors(AfterProcess, c)
 	if err != nil {
 		processError = err
 		return
 	}
-	c.Extra = extra
 
 	for _, v := range c.Children {
 		v.Process()
@@ -273,21 +812,26 @@ func (c *ContentItem) Process() {
 }
 
 func (c *ContentItem) Write(path string, queue *ContentQueue) {
+	if queue.Failed() {
+		return
+	}
+
 	fullPath := path + "/" + c.Filename
-	printName := strings.TrimPrefix(fullPath, "static/.")
-	if printName != "" {
-		log.Printf(" -> %s\n", printName)
+	if c.Path != "" {
+		log.Printf(" -> /%s\n", c.OutputPath())
 	}
 
 	ci := queue.Insert(c)
+	c.queued = ci
 
-	go func() {
-		err := c.write(fullPath)
-		if err != nil {
-			generateError = err
-		}
-		ci.Result <- true
-	}()
+	if c.Type == Directory {
+		// Children can only be written once the directory exists.
+		queue.Done(c.write(fullPath))
+	} else {
+		queue.Run(ci, func() error {
+			return c.write(fullPath)
+		})
+	}
 
 	for _, v := range c.Children {
 		v.Write(fullPath, queue)
@@ -295,19 +839,48 @@ func (c *ContentItem) Write(path string, queue *ContentQueue) {
 }
 
 func (c *ContentItem) write(path string) error {
+	out := c.Site.output()
 	if c.Type == Directory {
-		err := os.MkdirAll(path, 0755)
+		err := out.MkdirAll(path)
 		if err != nil {
 			return err
 		}
 	} else if c.Type == Content {
+		if !c.hasOutputFormat("html") {
+			return nil
+		}
 		err := c.WriteContent(path)
 		if err != nil {
-			return fmt.Errorf("write failed for %s: %s", path, err)
+			return fmt.Errorf("write failed for %s: %w", path, err)
+		}
+		if !c.Site.inMemory() {
+			err = runPageHooks(c, path)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
 		}
 	} else if c.Type == Asset {
-		out := strings.Replace(c.FullPath, "content/.", "static", 1)
-		err := copyFile(c.FullPath, out)
+		var err error
+		if c.symlink {
+			err = c.writeSymlink(path)
+		} else if c.Site.transformsAsset(c) {
+			var data []byte
+			data, err = c.Site.assetData(c)
+			if err == nil {
+				err = out.WriteFile(path, data)
+			}
+		} else {
+			err = out.CopyFile(c.FullPath, path, c.queued.progress)
+		}
+		if err != nil {
+			return err
+		}
+	} else if c.Type == Generated {
+		data, err := c.Site.assetData(c)
+		if err != nil {
+			return fmt.Errorf("write failed for %s: %w", path, err)
+		}
+		err = out.WriteFile(path, data)
 		if err != nil {
 			return err
 		}
@@ -319,21 +892,44 @@ func (c *ContentItem) write(path string) error {
 var codeRegex = regexp.MustCompile(`(?s)<highlight(.*?)>(.*?)</highlight>`)
 
 func (c *ContentItem) WriteContent(path string) error {
-	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	// Kept to see whether the page changed.
+	previous, _ := c.Site.output().ReadFile(path)
+
+	buf, err := c.render("html")
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
-	buf := &bytes.Buffer{}
-	err = templates.ExecuteTemplate(buf, c.Metadata.Template, c)
+	html, err := highlightCode(path, string(buf))
 	if err != nil {
 		return err
 	}
 
+	filtered, err := filterPage(c, []byte(html))
+	if err != nil {
+		return err
+	}
+
+	minified, err := c.Site.minify("html", filtered)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(previous, minified) {
+		c.Site.markChanged(c)
+	}
+
+	return c.Site.output().WriteFile(path, minified)
+}
+
+// highlightCode replaces the code blocks in a rendered page with their
+// highlighted version. Line numbers can be left out (linenos=false), start
+// elsewhere (linenostart=10) and lines can be marked (hl_lines="3-5 7",
+// counted from the first line of the block).
+func highlightCode(path, html string) (string, error) {
 	var innerErr error = nil
 	var badCode string = ""
-	html := codeRegex.ReplaceAllStringFunc(string(buf.Bytes()), func(in string) string {
+	result := codeRegex.ReplaceAllStringFunc(html, func(in string) string {
 		parts := codeRegex.FindStringSubmatch(in)
 		attrs := parseAttributes(parts[1])
 		code := strings.TrimRightFunc(parts[2], unicode.IsSpace)
@@ -347,6 +943,21 @@ func (c *ContentItem) WriteContent(path string) error {
 		}
 
 		lines := strings.Split(strings.TrimRightFunc(formatted, unicode.IsSpace), "\n")
+		highlighted, err := parseLineRanges(attrs["hl_lines"])
+		if err != nil {
+			innerErr = err
+			badCode = code
+			return ""
+		}
+		first := 1
+		if attrs["linenostart"] != "" {
+			first, err = strconv.Atoi(attrs["linenostart"])
+			if err != nil {
+				innerErr = fmt.Errorf("Invalid linenostart: %s", attrs["linenostart"])
+				badCode = code
+				return ""
+			}
+		}
 
 		var out bytes.Buffer
 		out.WriteString(`<div class="code`)
@@ -354,101 +965,291 @@ func (c *ContentItem) WriteContent(path string) error {
 			out.WriteString(" code-")
 			out.WriteString(attrs["language"])
 		}
+		if attrs["class"] != "" {
+			out.WriteString(" ")
+			out.WriteString(attrs["class"])
+		}
 		out.WriteString(`">`)
 		if attrs["title"] != "" {
 			out.WriteString(`<div class="title">`)
 			out.WriteString(attrs["title"])
 			out.WriteString(`</div>`)
 		}
-		out.WriteString(`<div class="scroller"><table><tr><td class="nrs">`)
-		for i, _ := range lines {
-			out.WriteString(`<div class="nr">`)
-			out.WriteString(strconv.Itoa(i + 1))
-			out.WriteString(`</div>`)
+		out.WriteString(`<div class="scroller"><table><tr>`)
+		if attrs["linenos"] != "false" {
+			out.WriteString(`<td class="nrs">`)
+			for i, _ := range lines {
+				if highlighted[i+1] {
+					out.WriteString(`<div class="nr hl">`)
+				} else {
+					out.WriteString(`<div class="nr">`)
+				}
+				out.WriteString(strconv.Itoa(i + first))
+				out.WriteString(`</div>`)
+			}
+			out.WriteString(`</td>`)
 		}
-		out.WriteString(`</td><td class="src"><pre>`)
+		out.WriteString(`<td class="src"><pre>`)
 		out.WriteString("<pre>")
-		out.WriteString(strings.TrimRightFunc(formatted, unicode.IsSpace))
+		for i, line := range lines {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			if highlighted[i+1] {
+				out.WriteString(`<span class="hl">`)
+				out.WriteString(line)
+				out.WriteString(`</span>`)
+			} else {
+				out.WriteString(line)
+			}
+		}
 		out.WriteString(`</pre></td></tr></table></div></div>`)
 		return string(out.Bytes())
 	})
 	if innerErr != nil {
 		log.Printf("%s %#v\n", path, innerErr.Error())
 		log.Println(badCode)
-		return innerErr
+		return "", innerErr
 	}
-
-	_, err = out.WriteString(html)
-	return err
+	return result, nil
 }
 
 // Metadata processing
 type MetadataProcessor func(item *ContentItem) (interface{}, error)
 
+// MetadataPhase is the moment metadata processors run.
+type MetadataPhase int
+
+const (
+	// After a content file is read: the metadata and content are known,
+	// the URL and section aren't yet.
+	AfterParse MetadataPhase = iota
+
+	// After the URL, permalink and section are set, for every item
+	// (including directories and assets). The default.
+	AfterProcess
+)
+
+// SetMetadataProcessor sets the processor that runs after processing,
+// replacing the ones added before.
 func SetMetadataProcessor(f MetadataProcessor) {
-	processor = f
+	processors[AfterProcess] = []MetadataProcessor{f}
+}
+
+// AddMetadataProcessor adds a processor for the given phase. Processors run
+// in the order they're added, each one can see the result of the previous one
+// in item.Extra, which gets the result of the last one.
+func AddMetadataProcessor(phase MetadataPhase, f MetadataProcessor) {
+	processors[phase] = append(processors[phase], f)
+}
+
+func runMetadataProcessors(phase MetadataPhase, c *ContentItem) error {
+	for _, f := range processors[phase] {
+		extra, err := f(c)
+		if err != nil {
+			return err
+		}
+		c.Extra = extra
+	}
+	return nil
 }
 
 // Time handling
 func (m *Metadata) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	md := &metadataTime{}
+	// Decode everything but the date into the fields, using a type that
+	// doesn't have this method.
+	type plainMetadata Metadata
+	md := &struct {
+		plainMetadata `yaml:",inline"`
+		Date          string `yaml:"date"`
+	}{
+		plainMetadata: plainMetadata(*m),
+	}
 	if err := unmarshal(md); err != nil {
 		return err
 	}
+	*m = Metadata(md.plainMetadata)
 
-	loc, _ := time.LoadLocation("Europe/Brussels")
-	t, err := time.ParseInLocation("2006-01-02 15:04:05", md.Date, loc)
-	if err != nil {
-		return err
+	if md.Date != "" {
+		t, err := parseMetadataDate(md.Date)
+		if err != nil {
+			return err
+		}
+		m.Date = t
 	}
 
-	// TODO: Use reflection to copy all fields.
-	m.Title = md.Title
-	m.Template = md.Template
-	m.Date = t
-	return nil
+	// Keep all fields around, for things like taxonomies.
+	return unmarshal(&m.Params)
+}
+
+// parseMetadataDate parses a date from the front matter, with a time
+// (2006-01-02 15:04:05) or without (2006-01-02, midnight).
+func parseMetadataDate(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, location())
+	if err == nil {
+		return t, nil
+	}
+	if t, ok := parseDate(value); ok {
+		return t, nil
+	}
+	return t, err
+}
+
+func location() *time.Location {
+	loc, err := time.LoadLocation("Europe/Brussels")
+	if err != nil {
+		return time.Local
+	}
+	return loc
 }
 
 // Processing queue
 
+// ContentQueue writes content items concurrently. Directories are written
+// before their children are queued, everything else in its own goroutine.
+// After the first error, items that haven't started are skipped.
 type ContentQueue struct {
-	lock  *sync.Mutex
-	items []*ContentQueueItem
+	wg       sync.WaitGroup
+	total    int64
+	finished int64
+
+	errOnce sync.Once
+	err     error
+	failed  chan struct{}
+
+	// Large assets that are being copied, for the progress bar.
+	lock   sync.Mutex
+	copies []*ContentQueueItem
 }
 
 type ContentQueueItem struct {
-	item   *ContentItem
-	Result chan bool
+	item *ContentItem
+
+	// Size and bytes copied so far, for large assets.
+	size   int64
+	copied int64
 }
 
+// Assets from this size on show the progress of their copy.
+const largeAsset = 8 << 20
+
 func NewContentQueue() *ContentQueue {
 	return &ContentQueue{
-		lock:  &sync.Mutex{},
-		items: make([]*ContentQueueItem, 0),
+		failed: make(chan struct{}),
 	}
 }
 
 func (c *ContentQueue) Insert(i *ContentItem) *ContentQueueItem {
+	atomic.AddInt64(&c.total, 1)
+	ci := &ContentQueueItem{
+		item: i,
+	}
+	if i.Type == Asset && !i.symlink {
+		if info, err := os.Stat(i.FullPath); err == nil && info.Size() >= largeAsset {
+			ci.size = info.Size()
+		}
+	}
+	return ci
+}
+
+// Run writes an item in the background.
+func (c *ContentQueue) Run(ci *ContentQueueItem, write func() error) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if c.Failed() {
+			return
+		}
+		if ci.size > 0 {
+			c.startCopy(ci)
+			defer c.endCopy(ci)
+		}
+		c.Done(write())
+	}()
+}
+
+// Done marks an item as written, with the error writing it, if any.
+func (c *ContentQueue) Done(err error) {
+	atomic.AddInt64(&c.finished, 1)
+	if err != nil {
+		c.errOnce.Do(func() {
+			c.err = err
+			close(c.failed)
+		})
+	}
+}
+
+// Failed reports whether writing an item failed.
+func (c *ContentQueue) Failed() bool {
+	select {
+	case <-c.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait shows the progress until all items are written and returns the first
+// error.
+func (c *ContentQueue) Wait() error {
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	bar := pb.StartNew(int(atomic.LoadInt64(&c.total)))
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			bar.Postfix("")
+			bar.Set64(atomic.LoadInt64(&c.finished))
+			bar.Finish()
+			return c.err
+		case <-ticker.C:
+			bar.Postfix(c.copyProgress())
+			bar.Set64(atomic.LoadInt64(&c.finished))
+		}
+	}
+}
+
+func (c *ContentQueue) startCopy(ci *ContentQueueItem) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	c.copies = append(c.copies, ci)
+}
 
-	ci := &ContentQueueItem{
-		item:   i,
-		Result: make(chan bool, 1),
+func (c *ContentQueue) endCopy(ci *ContentQueueItem) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i, v := range c.copies {
+		if v == ci {
+			c.copies = append(c.copies[:i], c.copies[i+1:]...)
+			break
+		}
 	}
-	c.items = append(c.items, ci)
-	return ci
 }
 
-func (c *ContentQueue) Wait() {
-	finished := 0
-	bar := pb.StartNew(len(c.items))
-	for finished < len(c.items) {
-		<-c.items[finished].Result
-		finished++
-		bar.Increment()
+// copyProgress describes the oldest large copy that's still going, to show
+// after the bar.
+func (c *ContentQueue) copyProgress() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.copies) == 0 {
+		return ""
+	}
+	ci := c.copies[0]
+	copied := atomic.LoadInt64(&ci.copied)
+	return fmt.Sprintf(" %s %d%%", ci.item.OutputPath(), copied*100/ci.size)
+}
+
+// progress counts bytes copied for the item. It can be called on nil.
+func (ci *ContentQueueItem) progress(n int64) {
+	if ci != nil {
+		atomic.AddInt64(&ci.copied, n)
 	}
-	bar.Finish()
 }
 
 // Utilities
@@ -494,6 +1295,7 @@ func parseAttributes(in string) map[string]string {
 				val += in[pos : pos+1]
 				pos++
 				start = pos
+				continue
 			} else if rune(in[pos]) == quote {
 				val += in[start:pos]
 				attrs[key] = val
@@ -520,8 +1322,11 @@ func fileExists(path string) bool {
 
 // copyFile copies a file from src to dst. If src and dst files exist, and are
 // the same, then return success. Otherise, attempt to create a hard link
-// between the two files. If that fail, copy the file contents from src to dst.
-func copyFile(src, dst string) (err error) {
+// between the two files. If that fail, copy the file contents from src to dst,
+// keeping the mode and modification time of src. A copy with the same size
+// and modification time as src is left alone. Progress, if given, is
+// called with the number of bytes copied as the copy goes.
+func copyFile(src, dst string, progress func(n int64)) (err error) {
 	sfi, err := os.Stat(src)
 	if err != nil {
 		return
@@ -543,18 +1348,36 @@ func copyFile(src, dst string) (err error) {
 		if os.SameFile(sfi, dfi) {
 			return
 		}
+		if dfi.Size() == sfi.Size() && dfi.ModTime().Equal(sfi.ModTime()) {
+			// Copied before, with the mode and time of src.
+			return
+		}
+		// Replace it rather than write to it, it may be a hard link to
+		// another source file.
+		if err = os.Remove(dst); err != nil {
+			return
+		}
 	}
 	if err = os.Link(src, dst); err == nil {
+		if progress != nil {
+			progress(sfi.Size())
+		}
 		return
 	}
-	return copyFileContents(src, dst)
+	if err = copyFileContents(src, dst, progress); err != nil {
+		return
+	}
+	if err = os.Chmod(dst, sfi.Mode().Perm()); err != nil {
+		return
+	}
+	return os.Chtimes(dst, sfi.ModTime(), sfi.ModTime())
 }
 
 // copyFileContents copies the contents of the file named src to the file named
 // by dst. The file will be created if it does not already exist. If the
 // destination file exists, all it's contents will be replaced by the contents
-// of the source file.
-func copyFileContents(src, dst string) (err error) {
+// of the source file. The contents are streamed through a reused buffer.
+func copyFileContents(src, dst string, progress func(n int64)) (err error) {
 	in, err := os.Open(src)
 	if err != nil {
 		return
@@ -570,9 +1393,34 @@ func copyFileContents(src, dst string) (err error) {
 			err = cerr
 		}
 	}()
-	if _, err = io.Copy(out, in); err != nil {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	var w io.Writer = out
+	if progress != nil {
+		w = progressWriter{out, progress}
+	}
+	if _, err = io.CopyBuffer(w, in, *buf); err != nil {
 		return
 	}
 	err = out.Sync()
 	return
 }
+
+var copyBuffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 256<<10)
+		return &buf
+	},
+}
+
+// progressWriter reports the bytes written to w.
+type progressWriter struct {
+	w        io.Writer
+	progress func(n int64)
+}
+
+func (p progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.progress(int64(n))
+	return n, err
+}
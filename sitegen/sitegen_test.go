@@ -1,11 +1,18 @@
 package sitegen
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 // assert fails the test if the condition is false.
@@ -58,6 +65,35 @@ func TestSplit2(t *testing.T) {
 	equals(t, string(body), "Just some text")
 }
 
+func TestSplitVariants(t *testing.T) {
+	tests := []string{
+		"---\ntitle: Test\n---\nBody\n",
+		"---\r\ntitle: Test\r\n---\r\n\r\nBody\n",
+		"---\ntitle: Test\n...\n\nBody\n",
+		"---  \ntitle: Test\n---\t\nBody\n",
+		"\xef\xbb\xbf---\ntitle: Test\n---\n\nBody\n",
+	}
+	for _, in := range tests {
+		frontMatter, body, err := splitContent([]byte(in))
+		ok(t, err)
+		equals(t, string(frontMatter), "title: Test")
+		equals(t, string(body), "Body\n")
+	}
+
+	frontMatter, body, err := splitContent([]byte("---\n---\nBody"))
+	ok(t, err)
+	equals(t, string(frontMatter), "")
+	equals(t, string(body), "Body")
+
+	_, _, err = splitContent([]byte("---\ntitle: Test\n\nBody\n"))
+	assert(t, err != nil, "Expected error for missing end delimiter")
+
+	frontMatter, body, err = splitFrontMatter([]byte("+++\ntitle: Test\n+++\nBody"), "+++")
+	ok(t, err)
+	equals(t, string(frontMatter), "title: Test")
+	equals(t, string(body), "Body")
+}
+
 func TestHighlight(t *testing.T) {
 	in := `console.log("Test");`
 	out := ""
@@ -98,3 +134,122 @@ func TestParseAttrs(t *testing.T) {
 	assert(t, len(attrs) == 0, "Unexpected length")
 
 }
+
+func TestCrawlMultipleRoots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		ok(t, os.MkdirAll(filepath.Dir(p), 0755))
+		ok(t, ioutil.WriteFile(p, []byte(content), 0644))
+	}
+	write("a/index.md", "# Home")
+	write("a/blog/post.md", "# Post")
+	write("b/blog/other.md", "# Other")
+	write("b/style.css", "body {}")
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	equals(t, len(root.Children), 3)
+
+	blog := root.child("blog")
+	assert(t, blog != nil, "Missing blog directory")
+	equals(t, len(blog.Children), 2)
+
+	post := blog.child("post.html")
+	equals(t, post.Path, "blog/post.md")
+	equals(t, post.OutputPath(), "blog/post.html")
+	equals(t, post.FullPath, filepath.Join(dir, "a", "blog", "post.md"))
+	equals(t, blog.child("other.html").FullPath, filepath.Join(dir, "b", "blog", "other.md"))
+
+	write("b/index.md", "# Conflict")
+	_, err = site.crawlContent()
+	assert(t, err != nil, "Expected conflict error")
+}
+
+func TestParseMetadata(t *testing.T) {
+	in := []byte(`title: Hello
+template: post
+date: 2024-05-01 12:00:00
+tags: [go, yaml]
+`)
+
+	var m Metadata
+	ok(t, yaml.Unmarshal(in, &m))
+	equals(t, m.Title, "Hello")
+	equals(t, m.Template, "post")
+	equals(t, m.Date.Format("2006-01-02 15:04"), "2024-05-01 12:00")
+	equals(t, m.Params["tags"], []interface{}{"go", "yaml"})
+
+	m = Metadata{}
+	ok(t, yaml.Unmarshal([]byte("date: 2024-05-01\n"), &m))
+	equals(t, m.Date.Format("2006-01-02 15:04"), "2024-05-01 00:00")
+
+	m = Metadata{}
+	assert(t, yaml.Unmarshal([]byte("date: May 1st\n"), &m) != nil, "Expected error for an invalid date")
+}
+
+func TestSplitDirs(t *testing.T) {
+	equals(t, splitDirs("content"), []string{"content"})
+	equals(t, splitDirs(" content , shared/content/,"), []string{"content", "shared/content"})
+	equals(t, splitDirs(" , "), []string{})
+}
+
+func TestCopyFileKeepsModeAndTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "run.sh")
+	ok(t, ioutil.WriteFile(src, []byte("#!/bin/sh\n"), 0755))
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ok(t, os.Chtimes(src, mtime, mtime))
+
+	// An existing file can't be replaced by a hard link, so it gets copied.
+	dst := filepath.Join(dir, "out.sh")
+	ok(t, ioutil.WriteFile(dst, []byte("old"), 0600))
+	copied := int64(0)
+	ok(t, copyFile(src, dst, func(n int64) { copied += n }))
+
+	info, err := os.Stat(dst)
+	ok(t, err)
+	equals(t, info.Mode().Perm(), os.FileMode(0755))
+	equals(t, info.ModTime().UTC(), mtime)
+	data, err := ioutil.ReadFile(dst)
+	ok(t, err)
+	equals(t, string(data), "#!/bin/sh\n")
+	equals(t, copied, int64(10))
+}
+
+func TestContentQueue(t *testing.T) {
+	queue := NewContentQueue()
+	item := &ContentItem{Type: Generated, Filename: "a.txt"}
+	written := int64(0)
+	for i := 0; i < 10; i++ {
+		queue.Run(queue.Insert(item), func() error {
+			atomic.AddInt64(&written, 1)
+			return nil
+		})
+	}
+	ok(t, queue.Wait())
+	equals(t, written, int64(10))
+
+	// After an error, items that haven't started are skipped.
+	queue = NewContentQueue()
+	queue.Run(queue.Insert(item), func() error {
+		return errors.New("Failed")
+	})
+	equals(t, queue.Wait(), errors.New("Failed"))
+	assert(t, queue.Failed(), "Expected failed queue")
+	queue.Run(queue.Insert(item), func() error {
+		t.Error("Unexpected write")
+		return nil
+	})
+	equals(t, queue.Wait().Error(), "Failed")
+}
@@ -0,0 +1,193 @@
+// Package sitegentest builds sites for tests and compares them with golden
+// output, so changes to templates, content processing and custom processors
+// can be checked against what the site used to look like.
+//
+//	func TestSite(t *testing.T) {
+//		sitegentest.Golden(t, "testdata/site", "testdata/golden")
+//	}
+//
+// Run the tests with -update to write the golden output.
+package sitegentest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rubenv/sitegen/sitegen"
+)
+
+var update = flag.Bool("update", false, "Write the golden output of sitegentest.Golden")
+
+// BuildTime is the build time of sites that don't pin one, so their output
+// doesn't change from run to run.
+const BuildTime = "2000-01-01 00:00:00"
+
+// Build builds the site in dir, with its sitegen.yaml, and returns the
+// generated files keyed by their slash-separated path. The site is built in
+// memory, paths in the configuration are relative to dir. Builds use global
+// state, so don't run them in parallel.
+func Build(dir string) (map[string][]byte, error) {
+	config, err := sitegen.LoadConfig(filepath.Join(dir, "sitegen.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	// For cached results, e.g. resized images.
+	cache, err := ioutil.TempDir("", "sitegentest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(cache)
+
+	for i, v := range config.ContentDirs {
+		config.ContentDirs[i] = relativeTo(dir, v)
+	}
+	config.TemplateDir = relativeTo(dir, config.TemplateDir)
+	config.I18nDir = relativeTo(dir, config.I18nDir)
+	config.ArchetypeDir = relativeTo(dir, config.ArchetypeDir)
+	config.CacheDir = cache
+	config.OutputDir = relativeTo(dir, config.OutputDir)
+	if config.BuildTime == "" {
+		config.BuildTime = BuildTime
+	}
+
+	site, err := sitegen.NewSite(config).BuildFS()
+	if err != nil {
+		return nil, err
+	}
+	return readFS(site)
+}
+
+func relativeTo(dir, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// ReadDir returns the files in a directory and its subdirectories, keyed by
+// their slash-separated path.
+func ReadDir(dir string) (map[string][]byte, error) {
+	return readFS(os.DirFS(dir))
+}
+
+func readFS(fsys fs.FS) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		files[p] = data
+		return nil
+	})
+	return files, err
+}
+
+// Golden builds the site in dir and compares the output with the files in
+// golden, failing the test with the differences. With -update, the golden
+// output is written instead.
+func Golden(t testing.TB, dir, golden string) {
+	t.Helper()
+
+	got, err := Build(dir)
+	if err != nil {
+		t.Fatalf("Building %s failed: %s", dir, err)
+	}
+
+	if *update {
+		err = writeDir(golden, got)
+		if err != nil {
+			t.Fatalf("Writing %s failed: %s", golden, err)
+		}
+		return
+	}
+
+	want, err := ReadDir(golden)
+	if err != nil {
+		t.Fatalf("Reading %s failed: %s (run with -update to write it)", golden, err)
+	}
+	if diff := Diff(want, got); len(diff) > 0 {
+		t.Errorf("Output of %s differs from %s (run with -update to accept it):\n%s", dir, golden, strings.Join(diff, "\n"))
+	}
+}
+
+func writeDir(dir string, files map[string][]byte) error {
+	err := os.RemoveAll(dir)
+	if err != nil {
+		return err
+	}
+	for p, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(p))
+		err = os.MkdirAll(filepath.Dir(file), 0755)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(file, data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Diff lists the differences between two sets of files, sorted by path:
+// files that are missing, extra or changed (with the first line that
+// differs).
+func Diff(want, got map[string][]byte) []string {
+	paths := make([]string, 0, len(want)+len(got))
+	for p := range want {
+		paths = append(paths, p)
+	}
+	for p := range got {
+		if _, ok := want[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	diff := make([]string, 0)
+	for _, p := range paths {
+		w, inWant := want[p]
+		g, inGot := got[p]
+		switch {
+		case !inGot:
+			diff = append(diff, "missing: "+p)
+		case !inWant:
+			diff = append(diff, "extra: "+p)
+		case !bytes.Equal(w, g):
+			diff = append(diff, "changed: "+p+firstDifference(w, g))
+		}
+	}
+	return diff
+}
+
+// firstDifference describes the first line that differs.
+func firstDifference(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return fmt.Sprintf(", line %d\n\twant: %q\n\tgot:  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package sitegentest
+
+import (
+	"testing"
+)
+
+func TestExample(t *testing.T) {
+	Golden(t, "../../example", "testdata/example")
+}
+
+func TestDiff(t *testing.T) {
+	want := map[string][]byte{
+		"index.html": []byte("<h1>Hi</h1>\n<p>Text</p>\n"),
+		"gone.html":  []byte("gone"),
+		"same.css":   []byte("a{}"),
+	}
+	got := map[string][]byte{
+		"index.html": []byte("<h1>Hi</h1>\n<p>Other</p>\n"),
+		"new.html":   []byte("new"),
+		"same.css":   []byte("a{}"),
+	}
+	diff := Diff(want, got)
+	exp := []string{
+		"missing: gone.html",
+		"changed: index.html, line 2\n\twant: \"<p>Text</p>\"\n\tgot:  \"<p>Other</p>\"",
+		"extra: new.html",
+	}
+	if len(diff) != len(exp) {
+		t.Fatalf("Expected %q, got %q", exp, diff)
+	}
+	for i := range exp {
+		if diff[i] != exp[i] {
+			t.Errorf("Expected %q, got %q", exp[i], diff[i])
+		}
+	}
+
+	if diff := Diff(want, want); len(diff) != 0 {
+		t.Errorf("Expected no differences, got %q", diff)
+	}
+}
@@ -0,0 +1,75 @@
+package sitegen
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var paragraphRegex = regexp.MustCompile(`(?is)<p(?:\s[^>]*)?>(.*?)</p>`)
+
+// Description returns a short description of the page: the description
+// from the front matter, or the start of the first paragraph (or of the
+// text, for pages without paragraphs).
+func (c *ContentItem) Description() string {
+	if d := c.Metadata.String("description"); d != "" {
+		return d
+	}
+	if m := paragraphRegex.FindStringSubmatch(string(c.Content)); m != nil {
+		return truncateText(stripTags(m[1]), 160)
+	}
+	return truncateText(c.Plain(), 160)
+}
+
+// truncateText cuts text off at a word boundary, so it's at most max
+// characters long (including the ellipsis).
+func truncateText(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)[:max-1]
+	cut := string(runes)
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:.") + "…"
+}
+
+// socialMeta returns the Open Graph and Twitter card meta tags for a page.
+// Used as the socialMeta template function.
+func (s *Site) socialMeta(c *ContentItem) template.HTML {
+	buf := &bytes.Buffer{}
+	tag := func(attr, name, content string) {
+		if content != "" {
+			fmt.Fprintf(buf, `<meta %s="%s" content="%s">`, attr, name, html.EscapeString(content))
+		}
+	}
+
+	kind := "website"
+	if !c.Metadata.Date.IsZero() {
+		kind = "article"
+	}
+	image := s.absoluteURL(c.OGImage())
+	card := "summary"
+	if image != "" {
+		card = "summary_large_image"
+	}
+
+	tag("property", "og:title", c.Metadata.Title)
+	tag("property", "og:description", c.Description())
+	tag("property", "og:url", s.absoluteURL(c.Url))
+	tag("property", "og:type", kind)
+	tag("property", "og:site_name", s.Config.Title)
+	tag("property", "og:image", image)
+	if kind == "article" {
+		tag("property", "article:published_time", c.Metadata.Date.Format("2006-01-02T15:04:05Z07:00"))
+	}
+	tag("name", "twitter:card", card)
+	tag("name", "twitter:site", s.Config.Twitter)
+	return template.HTML(buf.String())
+}
@@ -0,0 +1,57 @@
+package sitegen
+
+import (
+	"html/template"
+	"testing"
+	"time"
+)
+
+func TestDescription(t *testing.T) {
+	c := &ContentItem{Content: template.HTML(`<h1>Title</h1><p>First <em>paragraph</em> &amp; more.</p><p>Second</p>`)}
+	equals(t, c.Description(), "First paragraph & more.")
+
+	c.Metadata.Params = map[string]interface{}{"description": "Set by hand"}
+	equals(t, c.Description(), "Set by hand")
+
+	c = &ContentItem{Content: template.HTML("<ul><li>One</li><li>Two</li></ul>")}
+	equals(t, c.Description(), "One Two")
+
+	equals(t, truncateText("one two three four", 12), "one two…")
+	equals(t, truncateText("short", 12), "short")
+}
+
+func TestSocialMeta(t *testing.T) {
+	config := DefaultConfig()
+	config.Title = "Example"
+	config.BaseURL = "https://example.com/"
+	config.Twitter = "@example"
+	site := NewSite(config)
+
+	c := &ContentItem{
+		Site:    site,
+		Url:     "/blog/post/",
+		Content: template.HTML(`<p>Hello "world"</p>`),
+		Metadata: Metadata{
+			Title:  "Post",
+			Date:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			Params: map[string]interface{}{"image": "/img/card.png"},
+		},
+	}
+	equals(t, string(site.socialMeta(c)), `<meta property="og:title" content="Post">`+
+		`<meta property="og:description" content="Hello &#34;world&#34;">`+
+		`<meta property="og:url" content="https://example.com/blog/post/">`+
+		`<meta property="og:type" content="article">`+
+		`<meta property="og:site_name" content="Example">`+
+		`<meta property="og:image" content="https://example.com/img/card.png">`+
+		`<meta property="article:published_time" content="2024-05-01T00:00:00Z">`+
+		`<meta name="twitter:card" content="summary_large_image">`+
+		`<meta name="twitter:site" content="@example">`)
+
+	c = &ContentItem{Site: site, Url: "/", Metadata: Metadata{Title: "Home"}}
+	equals(t, string(site.socialMeta(c)), `<meta property="og:title" content="Home">`+
+		`<meta property="og:url" content="https://example.com/">`+
+		`<meta property="og:type" content="website">`+
+		`<meta property="og:site_name" content="Example">`+
+		`<meta name="twitter:card" content="summary">`+
+		`<meta name="twitter:site" content="@example">`)
+}
@@ -0,0 +1,66 @@
+package sitegen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// resolveSymlink applies the symlinks setting to a symlink in the content:
+// follow (the default) returns the file it points to, copy returns the
+// symlink itself (written as a symlink) and skip returns nil. Broken links
+// and links to a directory they're in are skipped with a warning.
+func (s *Site) resolveSymlink(p string, info os.FileInfo) (os.FileInfo, error) {
+	switch s.Config.Symlinks {
+	case "skip":
+		log.Printf("Skipping symlink %s\n", p)
+		return nil, nil
+	case "copy":
+		return info, nil
+	}
+
+	target, err := os.Stat(p)
+	if err != nil {
+		log.Printf("Skipping broken symlink %s: %s\n", p, err)
+		return nil, nil
+	}
+	if target.IsDir() {
+		real, err := filepath.EvalSymlinks(p)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := filepath.EvalSymlinks(filepath.Dir(p))
+		if err != nil {
+			return nil, err
+		}
+		if isWithin(dir, real) {
+			log.Printf("Skipping symlink %s: it points to a directory it's in\n", p)
+			return nil, nil
+		}
+	}
+	return target, nil
+}
+
+// writeSymlink writes a symlink to the output with the same target as the
+// one in the content. In memory, the file it points to is copied.
+func (c *ContentItem) writeSymlink(p string) error {
+	if c.Site.inMemory() {
+		return c.Site.output().CopyFile(c.FullPath, p, c.queued.progress)
+	}
+
+	target, err := os.Readlink(c.FullPath)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.Lstat(p); err == nil {
+		if existing.IsDir() {
+			return fmt.Errorf("Cannot write symlink %s: a directory is in the way", p)
+		}
+		err = os.Remove(p)
+		if err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, p)
+}
@@ -0,0 +1,59 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	content := filepath.Join(dir, "content")
+	shared := filepath.Join(dir, "shared")
+	ok(t, os.MkdirAll(content, 0755))
+	ok(t, os.MkdirAll(shared, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(shared, "logo.svg"), []byte("<svg/>"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(shared, "about.md"), []byte("About"), 0644))
+	ok(t, os.Symlink(shared, filepath.Join(content, "shared")))
+	ok(t, os.Symlink(filepath.Join(shared, "about.md"), filepath.Join(content, "about.md")))
+	ok(t, os.Symlink(filepath.Join(dir, "missing"), filepath.Join(content, "broken.css")))
+	ok(t, os.Symlink(content, filepath.Join(content, "loop")))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{content}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	equals(t, Content, root.child("about.html").Type)
+	equals(t, "<p>About</p>\n", string(root.child("about.html").Content))
+	equals(t, Directory, root.child("shared").Type)
+	equals(t, Asset, root.child("shared").child("logo.svg").Type)
+	assert(t, root.child("broken.css") == nil, "Expected broken symlinks to be skipped")
+	assert(t, root.child("loop") == nil, "Expected loops to be skipped")
+
+	config.Symlinks = "skip"
+	root, err = site.crawlContent()
+	ok(t, err)
+	equals(t, 0, len(root.Children))
+
+	config.Symlinks = "copy"
+	root, err = site.crawlContent()
+	ok(t, err)
+	item := root.child("shared")
+	equals(t, Asset, item.Type)
+	assert(t, item.symlink, "Expected a symlink")
+
+	out := filepath.Join(dir, "static")
+	ok(t, os.MkdirAll(out, 0755))
+	ok(t, item.write(filepath.Join(out, "shared")))
+	target, err := os.Readlink(filepath.Join(out, "shared"))
+	ok(t, err)
+	equals(t, shared, target)
+	// Written again on the next build.
+	ok(t, item.write(filepath.Join(out, "shared")))
+}
@@ -0,0 +1,132 @@
+package sitegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Taxonomy maps each term (e.g. a tag) to the pages that use it, newest
+// first.
+type Taxonomy map[string][]*ContentItem
+
+// Terms returns the terms of the taxonomy, sorted alphabetically.
+func (t Taxonomy) Terms() []string {
+	terms := make([]string, 0, len(t))
+	for term := range t {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// buildTaxonomies collects the terms used in the front matter of all pages
+// and adds a paginated listing plus a feed for every term, e.g.
+// /tags/go/, /tags/go/page/2/ and /tags/go/index.xml.
+func (s *Site) buildTaxonomies(root *ContentItem) {
+	s.Taxonomies = make(map[string]Taxonomy)
+
+	names := make([]string, 0, len(s.Config.Taxonomies))
+	for name := range s.Config.Taxonomies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pages := root.listedPages()
+	for _, name := range names {
+		config := s.Config.Taxonomy(name)
+
+		// Group on the slug, so "Go" and "go" end up on the same page.
+		taxonomy := make(Taxonomy)
+		terms := make(map[string]string)
+		for _, page := range pages {
+			for _, term := range page.Metadata.Strings(name) {
+				slug := slugify(term)
+				if slug == "" {
+					continue
+				}
+				if _, ok := terms[slug]; !ok {
+					terms[slug] = term
+				}
+				taxonomy[terms[slug]] = append(taxonomy[terms[slug]], page)
+			}
+		}
+		s.Taxonomies[name] = taxonomy
+		if len(taxonomy) == 0 {
+			continue
+		}
+
+		dir := root.ensureDir(name)
+		for _, term := range taxonomy.Terms() {
+			items := taxonomy[term]
+			sortByDate(items)
+
+			termDir := dir.ensureDir(slugify(term))
+			metadata := Metadata{
+				Title:    term,
+				Template: config.Template,
+			}
+			s.paginate(termDir, metadata, items, config.Paginate)
+			s.addFeed(termDir, fmt.Sprintf("%s: %s", name, term), items)
+		}
+	}
+}
+
+// allPages returns all content pages below c.
+func (c *ContentItem) allPages() []*ContentItem {
+	pages := make([]*ContentItem, 0)
+	for _, v := range c.Children {
+		if v.Type == Content {
+			pages = append(pages, v)
+		} else if v.Type == Directory {
+			pages = append(pages, v.allPages()...)
+		}
+	}
+	return pages
+}
+
+// Strings returns a front matter field as a list of strings. A single value
+// is returned as a list of one.
+func (m Metadata) Strings(key string) []string {
+	switch v := m.Params[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				result = append(result, fmt.Sprint(item))
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// String returns a front matter field as a string, or "" if it's missing.
+func (m Metadata) String(key string) string {
+	v, ok := m.Params[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// slugify turns a term into something that can be used in a URL.
+func slugify(in string) string {
+	var out []rune
+	dash := false
+	for _, r := range strings.ToLower(in) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && len(out) > 0 {
+				out = append(out, '-')
+			}
+			out = append(out, r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return string(out)
+}
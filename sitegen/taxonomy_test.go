@@ -0,0 +1,69 @@
+package sitegen
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlugify(t *testing.T) {
+	equals(t, slugify("Go"), "go")
+	equals(t, slugify("  Static site generators! "), "static-site-generators")
+	equals(t, slugify("C++"), "c")
+	equals(t, slugify("Één ding"), "één-ding")
+	equals(t, slugify("--"), "")
+}
+
+func TestBuildTaxonomies(t *testing.T) {
+	config := DefaultConfig()
+	config.Taxonomies["tags"] = &TaxonomyConfig{Paginate: 2}
+	site := NewSite(config)
+
+	root := &ContentItem{Site: site, Filename: ".", Type: Directory}
+	blog := root.ensureDir("blog")
+	for i, tags := range []interface{}{
+		[]interface{}{"Go", "yaml"},
+		"go",
+		[]interface{}{"go"},
+		nil,
+	} {
+		blog.Children = append(blog.Children, &ContentItem{
+			Site:     site,
+			Filename: string('a'+rune(i)) + ".html",
+			Path:     "blog/" + string('a'+rune(i)) + ".md",
+			Type:     Content,
+			Metadata: Metadata{
+				Title:  string('A' + rune(i)),
+				Date:   time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC),
+				Params: map[string]interface{}{"tags": tags},
+			},
+		})
+	}
+
+	site.buildTaxonomies(root)
+	root.Process()
+
+	equals(t, site.Taxonomies["tags"].Terms(), []string{"Go", "yaml"})
+	equals(t, len(site.Taxonomies["tags"]["Go"]), 3)
+
+	tags := root.child("tags")
+	assert(t, tags != nil, "Missing tags directory")
+
+	page1 := tags.child("go").child("index.html")
+	equals(t, page1.Url, "/tags/go/")
+	equals(t, page1.Metadata.Template, "taxonomy")
+	equals(t, page1.Pager.TotalPages(), 2)
+	equals(t, page1.Pager.Items[0].Metadata.Title, "C")
+	equals(t, page1.Pager.Next().Url, "/tags/go/page/2/")
+	assert(t, page1.Pager.Prev() == nil, "Unexpected previous page")
+
+	page2 := page1.Pager.Next()
+	equals(t, len(page2.Pager.Items), 1)
+	equals(t, page2.Pager.Prev(), page1)
+
+	feed := tags.child("go").child("index.xml")
+	equals(t, feed.Url, "/tags/go/index.xml")
+	out, err := feed.generate()
+	ok(t, err)
+	assert(t, strings.Contains(string(out), "<link>/blog/c.html</link>"), "Missing feed item: %s", out)
+}
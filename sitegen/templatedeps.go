@@ -0,0 +1,119 @@
+package sitegen
+
+import (
+	"text/template/parse"
+)
+
+// templateFiles returns the template files a template depends on: the one
+// it's defined in and those of the templates it includes, recursively.
+// Lookup gives the parse tree of a template by name.
+func templateFiles(lookup func(name string) *parse.Tree, name string) []string {
+	files := make([]string, 0)
+	seenFiles := make(map[string]bool)
+	seen := make(map[string]bool)
+
+	var visit func(name string)
+	var walk func(node parse.Node)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		tree := lookup(name)
+		if tree == nil {
+			return
+		}
+		if !seenFiles[tree.ParseName] {
+			seenFiles[tree.ParseName] = true
+			files = append(files, tree.ParseName)
+		}
+		if tree.Root != nil {
+			walk(tree.Root)
+		}
+	}
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, v := range n.Nodes {
+				walk(v)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			visit(n.Name)
+		}
+	}
+
+	visit(name)
+	return files
+}
+
+// trackTemplates remembers the template files used to render a page in one
+// of its formats.
+func (c *ContentItem) trackTemplates(files []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.templateFiles == nil {
+		c.templateFiles = make(map[string]bool)
+	}
+	for _, v := range files {
+		c.templateFiles[v] = true
+	}
+}
+
+// trackLookup remembers the template picked to render a page in one of its
+// formats.
+func (c *ContentItem) trackLookup(format, name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.templateLookups == nil {
+		c.templateLookups = make(map[string]string)
+	}
+	c.templateLookups[format] = name
+}
+
+// lookupChanged reports whether the page would now be rendered with another
+// template than before in any of its formats, e.g. because a more specific
+// one was defined.
+func (c *ContentItem) lookupChanged() bool {
+	c.lock.RLock()
+	lookups := make(map[string]string, len(c.templateLookups))
+	for format, name := range c.templateLookups {
+		lookups[format] = name
+	}
+	c.lock.RUnlock()
+
+	for format, name := range lookups {
+		current, err := c.Site.templateForFormat(c, format)
+		if err != nil {
+			current = ""
+		}
+		if current != name {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTemplate reports whether any of the given template files (relative to
+// the template directory) were used to render the page.
+func (c *ContentItem) usesTemplate(files []string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for _, v := range files {
+		if c.templateFiles[v] {
+			return true
+		}
+	}
+	return false
+}
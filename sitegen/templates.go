@@ -0,0 +1,175 @@
+package sitegen
+
+import (
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// loadTemplates parses all templates in the template directory and its
+// subdirectories. Each file is registered under its path relative to the
+// template directory (e.g. partials/header.html), next to any templates it
+// defines.
+func (s *Site) loadTemplates() (*template.Template, error) {
+	t := template.New("").Funcs(s.templateFuncs())
+	err := s.walkTemplates(func(name, data string) error {
+		if !strings.HasSuffix(name, ".html") {
+			return nil
+		}
+		_, err := t.New(name).Parse(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// loadTextTemplates parses all files in the template directory as text
+// templates, for output formats that aren't HTML (e.g. single.json).
+func (s *Site) loadTextTemplates() (*texttemplate.Template, error) {
+	t := texttemplate.New("").Funcs(texttemplate.FuncMap(s.templateFuncs()))
+	err := s.walkTemplates(func(name, data string) error {
+		_, err := t.New(name).Parse(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// walkTemplates calls fn with the slash-separated path (relative to the
+// template directory) and contents of each file in the template directory.
+func (s *Site) walkTemplates(fn func(name, data string) error) error {
+	dir := s.Config.TemplateDir
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel), string(data))
+	})
+}
+
+// templateFor picks the template to render a page with. The first one that
+// exists wins:
+//
+//  1. the template given in the front matter (which has to exist)
+//  2. <type>/<kind>, if the front matter has a type
+//  3. the template configured for the section, for single pages (which
+//     has to exist)
+//  4. <section>/<kind>, for the top-level folder the page is in
+//  5. <kind>
+//  6. page (the historical default)
+//  7. baseof
+//
+// Kind is "list" for index pages and other listings, "single" otherwise. Each name
+// can be a defined template or a file (with .html).
+func (s *Site) templateFor(c *ContentItem) (string, error) {
+	return s.templateForFormat(c, "html")
+}
+
+// templateForFormat picks the template to render a page with in the given
+// output format. It's looked up like templateFor, with the extension of the
+// format added to each name (single.json, single.amp.html). Formats that
+// aren't HTML use the text templates.
+func (s *Site) templateForFormat(c *ContentItem, format string) (string, error) {
+	suffix := ""
+	find := s.findTemplate
+	if format != "html" {
+		suffix = "." + outputExtension(format)
+	}
+	if !isHTMLFormat(format) {
+		find = s.findTextTemplate
+	}
+
+	if name := c.Metadata.Template; name != "" {
+		if found := find(name + suffix); found != "" {
+			return found, nil
+		}
+		return "", fmt.Errorf("Template not found: %s", name+suffix)
+	}
+
+	kind := "single"
+	if c.isList() {
+		kind = "list"
+	}
+
+	candidates := make([]string, 0, 5)
+	if t := c.Metadata.String("type"); t != "" {
+		candidates = append(candidates, t+"/"+kind)
+		if found := find(t + "/" + kind + suffix); found != "" {
+			return found, nil
+		}
+	}
+	section := s.section(c)
+	if name := s.Config.Section(section).Template; name != "" && kind == "single" {
+		if found := find(name + suffix); found != "" {
+			return found, nil
+		}
+		return "", fmt.Errorf("Template of section %s not found: %s", section, name+suffix)
+	}
+	if section != "" {
+		candidates = append(candidates, section+"/"+kind)
+	}
+	candidates = append(candidates, kind, "page", "baseof")
+
+	for i, name := range candidates {
+		candidates[i] = name + suffix
+		if found := find(candidates[i]); found != "" {
+			return found, nil
+		}
+	}
+	return "", fmt.Errorf("No template found for %s, tried %s", c.Path, strings.Join(candidates, ", "))
+}
+
+// findTemplate returns the name under which a template exists, either as
+// given or as a file with .html. Files that only define other templates
+// don't count.
+func (s *Site) findTemplate(name string) string {
+	for _, n := range []string{name, name + ".html"} {
+		if t := s.templates.Lookup(n); t != nil && hasContent(t.Tree) {
+			return n
+		}
+	}
+	return ""
+}
+
+// findTextTemplate returns name if it exists as a text template.
+func (s *Site) findTextTemplate(name string) string {
+	if t := s.textTemplates.Lookup(name); t != nil && hasContent(t.Tree) {
+		return name
+	}
+	return ""
+}
+
+func hasContent(tree *parse.Tree) bool {
+	if tree == nil || tree.Root == nil {
+		return false
+	}
+	for _, node := range tree.Root.Nodes {
+		if text, ok := node.(*parse.TextNode); ok && len(strings.TrimSpace(string(text.Text))) == 0 {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package sitegen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "partials"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "partials", "header.html"), []byte(`<h1>{{.}}</h1>`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(`{{define "page"}}{{template "partials/header.html" .}}{{end}}`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`{{`), 0644))
+
+	config := DefaultConfig()
+	config.TemplateDir = dir
+	site := NewSite(config)
+
+	tmpl, err := site.loadTemplates()
+	ok(t, err)
+
+	buf := &bytes.Buffer{}
+	ok(t, tmpl.ExecuteTemplate(buf, "page", "Hi"))
+	equals(t, buf.String(), "<h1>Hi</h1>")
+}
+
+func TestTemplateFor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog"), 0755))
+	ok(t, os.MkdirAll(filepath.Join(dir, "recipe"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "single.html"), []byte(`blog`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "recipe", "single.html"), []byte(`recipe`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "page.html"), []byte(`{{define "page"}}page{{end}}`), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "list.html"), []byte(`list`), 0644))
+
+	config := DefaultConfig()
+	config.TemplateDir = dir
+	site := NewSite(config)
+	site.templates, err = site.loadTemplates()
+	ok(t, err)
+
+	tests := []struct {
+		item *ContentItem
+		exp  string
+	}{
+		{&ContentItem{Path: "about.md"}, "page"},
+		{&ContentItem{Path: "blog/post.md"}, "blog/single.html"},
+		{&ContentItem{Path: "blog/post.md", Metadata: Metadata{Params: map[string]interface{}{"type": "recipe"}}}, "recipe/single.html"},
+		{&ContentItem{Path: "blog/post.md", Metadata: Metadata{Template: "page"}}, "page"},
+		{&ContentItem{Path: "tags/index.html", Pager: &Pager{}}, "list.html"},
+		{&ContentItem{Path: "blog/index.md", Filename: "index.html", Type: Content}, "list.html"},
+	}
+	for _, test := range tests {
+		name, err := site.templateFor(test.item)
+		ok(t, err)
+		equals(t, name, test.exp)
+	}
+
+	_, err = site.templateFor(&ContentItem{Metadata: Metadata{Template: "missing"}})
+	assert(t, err != nil, "Expected error for missing template")
+
+	config.Sections["docs"] = &SectionConfig{Template: "recipe/single"}
+	config.Sections["news"] = &SectionConfig{Template: "missing"}
+	tests = []struct {
+		item *ContentItem
+		exp  string
+	}{
+		{&ContentItem{Path: "docs/intro.md"}, "recipe/single.html"},
+		{&ContentItem{Path: "docs/intro.md", Metadata: Metadata{Template: "page"}}, "page"},
+		{&ContentItem{Path: "docs/index.md", Filename: "index.html", Type: Content}, "list.html"},
+		{&ContentItem{Path: "about.md"}, "page"},
+	}
+	for _, test := range tests {
+		name, err := site.templateFor(test.item)
+		ok(t, err)
+		equals(t, name, test.exp)
+	}
+	_, err = site.templateFor(&ContentItem{Path: "news/today.md"})
+	assert(t, err != nil, "Expected error for missing section template")
+}
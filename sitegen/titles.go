@@ -0,0 +1,92 @@
+package sitegen
+
+import (
+	"html"
+	"html/template"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	headingRegex = regexp.MustCompile(`(?is)<h1(?:\s[^>]*)?>(.*?)</h1>\s*`)
+	tagRegex     = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// inferTitle fills in a missing title, using the first top-level heading of
+// the content or, failing that, the filename. Headings in code blocks don't
+// count.
+func (s *Site) inferTitle(c *ContentItem) {
+	if c.Metadata.Title != "" {
+		return
+	}
+
+	content := string(c.Content)
+	if loc := findHeading(content); loc != nil {
+		title := stripTags(content[loc[2]:loc[3]])
+		if title != "" {
+			c.Metadata.Title = title
+			if s.Config.RemoveTitleHeading {
+				c.Content = template.HTML(content[:loc[0]] + content[loc[1]:])
+			}
+			return
+		}
+	}
+
+	c.Metadata.Title = humanizeFilename(c.Path)
+}
+
+// findHeading returns the location of the first top-level heading outside
+// of code blocks, as a headingRegex submatch index.
+func findHeading(content string) []int {
+	code := codeRegex.FindAllStringIndex(content, -1)
+	for _, loc := range headingRegex.FindAllStringSubmatchIndex(content, -1) {
+		inCode := false
+		for _, block := range code {
+			if loc[0] < block[1] && loc[1] > block[0] {
+				inCode = true
+				break
+			}
+		}
+		if !inCode {
+			return loc
+		}
+	}
+	return nil
+}
+
+// stripTags turns an HTML snippet into plain text.
+func stripTags(in string) string {
+	return strings.TrimSpace(html.UnescapeString(tagRegex.ReplaceAllString(in, "")))
+}
+
+// humanizeFilename turns a/2006-01-02-my_first-post.md into "My first post".
+// Index files are named after their directory.
+func humanizeFilename(p string) string {
+	name := path.Base(p)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "index" {
+		dir := path.Dir(p)
+		if dir == "." || dir == "/" {
+			return ""
+		}
+		name = path.Base(dir)
+	}
+
+	name = filenameDateRegex.ReplaceAllString(name, "")
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' {
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+
+	first, size := utf8.DecodeRuneInString(name)
+	if first == utf8.RuneError {
+		return name
+	}
+	return string(unicode.ToUpper(first)) + name[size:]
+}
@@ -0,0 +1,65 @@
+package sitegen
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestHumanizeFilename(t *testing.T) {
+	equals(t, humanizeFilename("notes/my_first-post.md"), "My first post")
+	equals(t, humanizeFilename("blog/2024-05-01-hello-world.md"), "Hello world")
+	equals(t, humanizeFilename("docs/getting-started/index.md"), "Getting started")
+	equals(t, humanizeFilename("index.md"), "")
+	equals(t, humanizeFilename("über.html"), "Über")
+}
+
+func TestInferTitle(t *testing.T) {
+	site := NewSite(DefaultConfig())
+
+	c := &ContentItem{
+		Path:    "notes/some-note.md",
+		Content: template.HTML("<h1 id=\"x\">Fish &amp; <em>Chips</em></h1>\n<p>Body</p>"),
+	}
+	site.inferTitle(c)
+	equals(t, c.Metadata.Title, "Fish & Chips")
+	equals(t, string(c.Content), "<h1 id=\"x\">Fish &amp; <em>Chips</em></h1>\n<p>Body</p>")
+
+	c = &ContentItem{
+		Path:    "notes/some-note.md",
+		Content: template.HTML("<p>Body</p>"),
+	}
+	site.inferTitle(c)
+	equals(t, c.Metadata.Title, "Some note")
+
+	c = &ContentItem{
+		Path:     "notes/some-note.md",
+		Content:  template.HTML("<p>Body</p>"),
+		Metadata: Metadata{Title: "Given"},
+	}
+	site.inferTitle(c)
+	equals(t, c.Metadata.Title, "Given")
+
+	// A heading in a code sample isn't the title.
+	c = &ContentItem{
+		Path:    "notes/some-note.md",
+		Content: template.HTML("<highlight lang=\"html\"><h1>Sample</h1></highlight>\n<h1>Real</h1>"),
+	}
+	site.inferTitle(c)
+	equals(t, c.Metadata.Title, "Real")
+
+	c = &ContentItem{
+		Path:    "notes/some-note.md",
+		Content: template.HTML("<highlight lang=\"html\"><h1>Sample</h1></highlight>"),
+	}
+	site.inferTitle(c)
+	equals(t, c.Metadata.Title, "Some note")
+
+	site.Config.RemoveTitleHeading = true
+	c = &ContentItem{
+		Path:    "notes/some-note.md",
+		Content: template.HTML("<h1>Heading</h1>\n<p>Body</p>"),
+	}
+	site.inferTitle(c)
+	equals(t, c.Metadata.Title, "Heading")
+	equals(t, string(c.Content), "<p>Body</p>")
+}
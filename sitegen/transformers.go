@@ -0,0 +1,73 @@
+package sitegen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Transformer pipes assets with the given extensions through an external
+// command, which reads the file on stdin and writes the result to stdout.
+type Transformer struct {
+	Extensions []string `yaml:"extensions"`
+	Command    []string `yaml:"command"`
+}
+
+// transformersFor returns the transformers that apply to a file, in the
+// order they're configured.
+func (s *Site) transformersFor(filename string) []Transformer {
+	ext := strings.ToLower(path.Ext(filename))
+	result := make([]Transformer, 0)
+	for _, t := range s.Config.Transformers {
+		for _, e := range t.Extensions {
+			if strings.ToLower(e) == ext {
+				result = append(result, t)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// transform runs data through a transformer. Results are cached on disk,
+// keyed on the command and the input, so unchanged assets don't hit the
+// external command again.
+func (s *Site) transform(t Transformer, data []byte) ([]byte, error) {
+	if len(t.Command) == 0 {
+		return nil, errors.New("Transformer without command")
+	}
+
+	h := sha256.New()
+	for _, arg := range t.Command {
+		h.Write([]byte(arg))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	cacheFile := filepath.Join(s.Config.CacheDir, "transform", hex.EncodeToString(h.Sum(nil)))
+
+	if cached, err := ioutil.ReadFile(cacheFile); err == nil {
+		return cached, nil
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command(t.Command[0], t.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %s\n%s", t.Command[0], err, stderr.String())
+	}
+
+	err = writeCacheFile(cacheFile, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,40 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTransformers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	// Upper-cases its input and counts how often it runs.
+	counter := filepath.Join(dir, "count")
+	command := filepath.Join(dir, "upper")
+	ok(t, ioutil.WriteFile(command, []byte("#!/bin/sh\necho x >> "+counter+"\ntr a-z A-Z\n"), 0755))
+
+	config := DefaultConfig()
+	config.CacheDir = filepath.Join(dir, "cache")
+	config.Transformers = []Transformer{
+		{Extensions: []string{".css"}, Command: []string{command}},
+	}
+	site := NewSite(config)
+
+	equals(t, len(site.transformersFor("STYLE.CSS")), 1)
+	equals(t, len(site.transformersFor("app.js")), 0)
+
+	tr := site.transformersFor("style.css")[0]
+	for i := 0; i < 2; i++ {
+		out, err := site.transform(tr, []byte("body {}"))
+		ok(t, err)
+		equals(t, string(out), "BODY {}")
+	}
+
+	runs, err := ioutil.ReadFile(counter)
+	ok(t, err)
+	equals(t, string(runs), "x\n")
+}
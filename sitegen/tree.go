@@ -0,0 +1,71 @@
+package sitegen
+
+import (
+	"path"
+	"strings"
+)
+
+// ensureDir returns the child directory with the given name, adding it if
+// needed.
+func (c *ContentItem) ensureDir(name string) *ContentItem {
+	if existing := c.child(name); existing != nil {
+		return existing
+	}
+
+	dir := &ContentItem{
+		Site:     c.Site,
+		Filename: name,
+		Path:     path.Join(c.Path, name),
+		Type:     Directory,
+		Children: make([]*ContentItem, 0),
+	}
+	c.Children = append(c.Children, dir)
+	return dir
+}
+
+// addGenerated adds a Generated item at the given slash-separated path,
+// relative to c, creating directories along the way.
+func (c *ContentItem) addGenerated(p string, metadata Metadata, generate func() ([]byte, error)) *ContentItem {
+	dir := c
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for _, part := range parts[:len(parts)-1] {
+		dir = dir.ensureDir(part)
+	}
+
+	name := parts[len(parts)-1]
+	item := &ContentItem{
+		Site:     c.Site,
+		Filename: name,
+		Path:     path.Join(dir.Path, name),
+		Type:     Generated,
+		Metadata: metadata,
+		generate: generate,
+	}
+	dir.Children = append(dir.Children, item)
+	return item
+}
+
+// place moves item to the given slash-separated output path, relative to c,
+// creating directories along the way. It returns the item that's in the way
+// if the path is taken, in which case nothing is changed.
+func (c *ContentItem) place(out string, item *ContentItem) *ContentItem {
+	dir := c
+	if d := path.Dir(out); d != "." {
+		for _, part := range strings.Split(d, "/") {
+			next := dir.child(part)
+			if next != nil && next.Type != Directory {
+				return next
+			}
+			dir = dir.ensureDir(part)
+		}
+	}
+	name := path.Base(out)
+	if existing := dir.child(name); existing != nil {
+		return existing
+	}
+
+	item.Filename = name
+	item.Path = path.Join(dir.Path, path.Base(item.Path))
+	dir.Children = append(dir.Children, item)
+	return nil
+}
@@ -0,0 +1,117 @@
+package sitegen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Quotes of smart punctuation, per style: opening and closing double
+// quotes, then opening and closing single quotes. Styles are named after
+// their language, and can be picked by language code.
+var quoteStyles = map[string][4]string{
+	"english":  {"“", "”", "‘", "’"},
+	"german":   {"„", "“", "‚", "‘"},
+	"dutch":    {"„", "”", "‚", "’"},
+	"french":   {"« ", " »", "‹ ", " ›"},
+	"spanish":  {"«", "»", "“", "”"},
+	"italian":  {"«", "»", "“", "”"},
+	"swedish":  {"”", "”", "’", "’"},
+	"polish":   {"„", "”", "‚", "’"},
+	"czech":    {"„", "“", "‚", "‘"},
+	"russian":  {"«", "»", "„", "“"},
+	"straight": {`"`, `"`, `'`, `'`},
+}
+
+var quoteStyleLanguages = map[string]string{
+	"en": "english",
+	"de": "german",
+	"nl": "dutch",
+	"fr": "french",
+	"es": "spanish",
+	"it": "italian",
+	"sv": "swedish",
+	"pl": "polish",
+	"cs": "czech",
+	"ru": "russian",
+}
+
+// quoteStyle returns the quotes to use: the configured style, or the four
+// quotes given (e.g. „“‚‘), the style of the language of the page
+// otherwise.
+func (c MarkdownConfig) quoteStyle() ([4]string, error) {
+	name := c.Quotes
+	if name == "" {
+		name = quoteStyleLanguages[c.lang]
+	}
+	if name == "" {
+		return quoteStyles["english"], nil
+	}
+	if style, ok := quoteStyles[name]; ok {
+		return style, nil
+	}
+	if style, ok := quoteStyles[quoteStyleLanguages[name]]; ok {
+		return style, nil
+	}
+	if utf8.RuneCountInString(name) == 4 {
+		var style [4]string
+		for i, r := range []rune(name) {
+			style[i] = string(r)
+		}
+		return style, nil
+	}
+	return [4]string{}, fmt.Errorf("Unknown quote style: %s", name)
+}
+
+// localizeQuotes replaces the English quotes of smart punctuation in the
+// text of rendered HTML with the given ones. Closing single quotes are only
+// replaced when they close a quote, the others are apostrophes (don’t),
+// unless quotes are straight.
+func localizeQuotes(in []byte, style [4]string) []byte {
+	if style == quoteStyles["english"] {
+		return in
+	}
+	straight := style == quoteStyles["straight"]
+	single := 0
+	return replaceInText(in, func(text, code string) string {
+		if code != "" {
+			return text
+		}
+		out := &strings.Builder{}
+		for len(text) > 0 {
+			switch {
+			case strings.HasPrefix(text, "&ldquo;"):
+				out.WriteString(style[0])
+				text = text[len("&ldquo;"):]
+			case strings.HasPrefix(text, "&rdquo;"):
+				out.WriteString(style[1])
+				text = text[len("&rdquo;"):]
+			case strings.HasPrefix(text, "&lsquo;"):
+				out.WriteString(style[2])
+				single++
+				text = text[len("&lsquo;"):]
+			case strings.HasPrefix(text, "&rsquo;"):
+				rest := text[len("&rsquo;"):]
+				r, _ := utf8.DecodeRuneInString(rest)
+				closing := single > 0 && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+				switch {
+				case straight:
+					out.WriteString(style[3])
+				case closing:
+					out.WriteString(style[3])
+				default:
+					out.WriteString("&rsquo;")
+				}
+				if closing {
+					single--
+				}
+				text = rest
+			default:
+				out.WriteByte(text[0])
+				text = text[1:]
+			}
+		}
+		return out.String()
+	})
+}
@@ -0,0 +1,65 @@
+package sitegen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuoteStyle(t *testing.T) {
+	style, err := MarkdownConfig{}.quoteStyle()
+	ok(t, err)
+	equals(t, quoteStyles["english"], style)
+
+	style, err = MarkdownConfig{lang: "de"}.quoteStyle()
+	ok(t, err)
+	equals(t, quoteStyles["german"], style)
+
+	style, err = MarkdownConfig{Quotes: "straight", lang: "de"}.quoteStyle()
+	ok(t, err)
+	equals(t, quoteStyles["straight"], style)
+
+	style, err = MarkdownConfig{Quotes: "nl"}.quoteStyle()
+	ok(t, err)
+	equals(t, quoteStyles["dutch"], style)
+
+	style, err = MarkdownConfig{Quotes: "»«›‹"}.quoteStyle()
+	ok(t, err)
+	equals(t, [4]string{"»", "«", "›", "‹"}, style)
+
+	_, err = MarkdownConfig{Quotes: "klingon"}.quoteStyle()
+	assert(t, err != nil, "Expected an error for an unknown style")
+	assert(t, MarkdownConfig{Quotes: "klingon"}.validate() != nil, "Expected validate to fail")
+}
+
+func TestLocalizeQuotes(t *testing.T) {
+	in := "<p>&ldquo;Hi,&rdquo; she said, &lsquo;it&rsquo;s <em>fine</em>&rsquo;.</p>\n<pre><code>&ldquo;x&rdquo;</code></pre>"
+
+	out := string(localizeQuotes([]byte(in), quoteStyles["german"]))
+	equals(t, "<p>„Hi,“ she said, ‚it&rsquo;s <em>fine</em>‘.</p>\n<pre><code>&ldquo;x&rdquo;</code></pre>", out)
+
+	out = string(localizeQuotes([]byte(in), quoteStyles["straight"]))
+	equals(t, "<p>\"Hi,\" she said, 'it's <em>fine</em>'.</p>\n<pre><code>&ldquo;x&rdquo;</code></pre>", out)
+
+	out = string(localizeQuotes([]byte(in), quoteStyles["english"]))
+	equals(t, in, out)
+}
+
+func TestPageQuotes(t *testing.T) {
+	site := NewSite(DefaultConfig())
+	site.Config.Languages["nl"] = &LanguageConfig{}
+
+	out, err := site.markdown([]byte(`"Hallo"`), "nl/index.md", Metadata{})
+	ok(t, err)
+	equals(t, "<p>„Hallo”</p>\n", string(out))
+
+	out, err = site.markdown([]byte(`"Hello"`), "about.md", Metadata{})
+	ok(t, err)
+	equals(t, "<p>&ldquo;Hello&rdquo;</p>\n", string(out))
+
+	out, err = site.markdown([]byte(`"Hello"`), "about.md", Metadata{Params: map[string]interface{}{
+		"markup": map[string]interface{}{"quotes": "straight"},
+	}})
+	ok(t, err)
+	equals(t, "<p>\"Hello\"</p>\n", string(out))
+	assert(t, !strings.Contains(site.Config.Markdown.Quotes, "straight"), "Site settings changed")
+}
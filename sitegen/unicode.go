@@ -0,0 +1,13 @@
+package sitegen
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizePath converts a path to Unicode NFC. macOS hands out file names
+// decomposed (NFD), which would otherwise end up in the output paths and
+// URLs, leaving links that differ from what's typed elsewhere (and from
+// what's deployed from another system).
+func normalizePath(p string) string {
+	return norm.NFC.String(p)
+}
@@ -0,0 +1,45 @@
+package sitegen
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	equals(t, normalizePath("café/résumé.md"), "café/résumé.md")
+	equals(t, normalizePath("café"), "café")
+}
+
+func TestNormalizeContentNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	// Decomposed, as written on macOS.
+	folder := filepath.Join(dir, "café")
+	ok(t, os.MkdirAll(folder, 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(folder, "ménu.md"), []byte("---\ntitle: Menu\n---\nHi\n"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(folder, "crème.jpg"), []byte("x"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	folderItem := root.child("café")
+	assert(t, folderItem != nil, "Expected normalized folder")
+	equals(t, folderItem.Path, "café")
+
+	page := folderItem.child("ménu.html")
+	assert(t, page != nil, "Expected normalized page")
+	equals(t, page.OutputPath(), "café/ménu.html")
+	equals(t, page.FullPath, filepath.Join(folder, "ménu.md"))
+
+	asset := folderItem.child("crème.jpg")
+	assert(t, asset != nil, "Expected normalized asset")
+	_, err = os.Stat(asset.FullPath)
+	ok(t, err)
+}
@@ -0,0 +1,92 @@
+package sitegen
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// absoluteURL turns a site URL (/blog/post/) into a full one, using the
+// configured baseURL. URLs that already have a scheme are kept. Used as the
+// absURL template function.
+func (s *Site) absoluteURL(u string) string {
+	return joinURL(s.Config.BaseURL, u)
+}
+
+// permalink returns the full URL of an item, using the baseURL of its
+// language if it has one.
+func (s *Site) permalink(c *ContentItem) string {
+	if base := s.Config.Language(c.Lang).BaseURL; base != "" {
+		return joinURL(base, c.Url)
+	}
+	return s.absoluteURL(c.Url)
+}
+
+func joinURL(base, u string) string {
+	if u == "" || strings.Contains(u, "://") || base == "" {
+		return u
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(u, "/")
+}
+
+// relativeURL turns a site URL into one relative to the host, which includes
+// the path of the baseURL when the site doesn't live at the root
+// (https://example.com/docs/ turns /css/site.css into /docs/css/site.css).
+// Used as the relURL template function.
+func (s *Site) relativeURL(u string) string {
+	if strings.Contains(u, "://") {
+		return u
+	}
+	return strings.TrimSuffix(s.basePath(), "/") + "/" + strings.TrimPrefix(u, "/")
+}
+
+// applyURLOverrides moves pages that set their URL in the front matter
+// (url: /custom/location/) to that location. URLs ending in a slash, or
+// without an extension, get an index.html.
+func (s *Site) applyURLOverrides(root *ContentItem) error {
+	moved := make([]*ContentItem, 0)
+	var walk func(c *ContentItem)
+	walk = func(c *ContentItem) {
+		children := c.Children[:0]
+		for _, v := range c.Children {
+			if v.Type == Directory {
+				walk(v)
+			}
+			if v.Type == Content && v.Metadata.String("url") != "" {
+				moved = append(moved, v)
+				continue
+			}
+			children = append(children, v)
+		}
+		c.Children = children
+	}
+	walk(root)
+
+	for _, v := range moved {
+		u := v.Metadata.String("url")
+		if existing := root.place(urlPath(u), v); existing != nil {
+			return fmt.Errorf("%s: url %s conflicts with %s", v.FullPath, u, existing.Path)
+		}
+	}
+	return nil
+}
+
+// urlPath returns the output path for a URL: URLs ending in a slash, or
+// without an extension, get an index.html.
+func urlPath(u string) string {
+	out := strings.TrimPrefix(path.Clean("/"+u), "/")
+	if out == "" || strings.HasSuffix(u, "/") || path.Ext(out) == "" {
+		out = path.Join(out, "index.html")
+	}
+	return out
+}
+
+// basePath returns the path of the baseURL, "/" when there's none.
+func (s *Site) basePath() string {
+	base, err := url.Parse(s.Config.BaseURL)
+	if err != nil || base.Path == "" {
+		return "/"
+	}
+	return base.Path
+}
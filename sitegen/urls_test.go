@@ -0,0 +1,97 @@
+package sitegen
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestURLs(t *testing.T) {
+	config := DefaultConfig()
+	site := NewSite(config)
+	equals(t, site.absoluteURL("/blog/"), "/blog/")
+	equals(t, site.relativeURL("css/site.css"), "/css/site.css")
+
+	config.BaseURL = "https://example.com/docs/"
+	equals(t, site.absoluteURL("/blog/"), "https://example.com/docs/blog/")
+	equals(t, site.absoluteURL("https://other.org/"), "https://other.org/")
+	equals(t, site.relativeURL("/css/site.css"), "/docs/css/site.css")
+	equals(t, site.relativeURL("css/site.css"), "/docs/css/site.css")
+
+	c := &ContentItem{Site: site, Filename: "post.html", Path: "blog/post.md", Type: Content}
+	c.Process()
+	equals(t, c.Url, "/blog/post.html")
+	equals(t, c.Permalink, "https://example.com/docs/blog/post.html")
+}
+
+func TestFeedPermalinks(t *testing.T) {
+	config := DefaultConfig()
+	config.BaseURL = "https://example.com"
+	site := NewSite(config)
+
+	root := &ContentItem{Site: site, Type: Directory}
+	post := &ContentItem{Site: site, Filename: "post.html", Path: "post.md", Type: Content, Metadata: Metadata{Title: "Post"}}
+	root.Children = append(root.Children, post)
+	site.addFeed(root.ensureDir("tags"), "Tags", []*ContentItem{post})
+	root.Process()
+
+	data, err := root.child("tags").child("index.xml").generate()
+	ok(t, err)
+	feed := rssFeed{}
+	ok(t, xml.Unmarshal(data, &feed))
+	equals(t, feed.Channel.Link, "https://example.com/tags/")
+	equals(t, feed.Channel.Items[0].Link, "https://example.com/post.html")
+}
+
+func TestInvalidBaseURL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "sitegen.yaml")
+	ok(t, ioutil.WriteFile(filename, []byte("baseURL: example.com\n"), 0644))
+	_, err = LoadConfig(filename)
+	equals(t, Category(err), ConfigError)
+}
+
+func TestCheckBaseURL(t *testing.T) {
+	ok(t, checkBaseURL("https://preview-123.example.com/"))
+	equals(t, Category(checkBaseURL("preview-123.example.com")), ConfigError)
+	equals(t, Category(checkBaseURL("/blog/")), ConfigError)
+}
+
+func TestURLOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sitegen")
+	ok(t, err)
+	defer os.RemoveAll(dir)
+
+	ok(t, os.MkdirAll(filepath.Join(dir, "blog"), 0755))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "a.md"), []byte("---\nurl: /custom/location/\n---\nA"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "b.md"), []byte("---\nurl: /b.html\n---\nB"), 0644))
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "c.md"), []byte("---\nurl: blog/c\n---\nC"), 0644))
+
+	config := DefaultConfig()
+	config.ContentDirs = []string{dir}
+	site := NewSite(config)
+
+	root, err := site.crawlContent()
+	ok(t, err)
+	ok(t, site.applyURLOverrides(root))
+	root.Process()
+
+	a := root.child("custom").child("location").child("index.html")
+	assert(t, a != nil, "Page a not moved")
+	equals(t, a.Url, "/custom/location/")
+	equals(t, a.OutputPath(), "custom/location/index.html")
+	equals(t, root.child("b.html").Url, "/b.html")
+	equals(t, root.child("blog").child("c").child("index.html").Url, "/blog/c/")
+	assert(t, root.child("blog").child("a.html") == nil, "Page a still in blog")
+
+	// Overriding to the place of another page fails.
+	ok(t, ioutil.WriteFile(filepath.Join(dir, "blog", "d.md"), []byte("---\nurl: /b.html\n---\nD"), 0644))
+	root, err = site.crawlContent()
+	ok(t, err)
+	assert(t, site.applyURLOverrides(root) != nil, "Expected conflict")
+}
@@ -0,0 +1,100 @@
+package sitegen
+
+import (
+	"fmt"
+)
+
+// Set stores a value on the item. Unlike Extra, which is set once by the
+// metadata processor, values can be set at any time and from multiple
+// goroutines.
+func (c *ContentItem) Set(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = value
+}
+
+// Get returns a value stored with Set, or nil.
+func (c *ContentItem) Get(key string) interface{} {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.values[key]
+}
+
+// Has reports whether a value was stored under key.
+func (c *ContentItem) Has(key string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	_, ok := c.values[key]
+	return ok
+}
+
+// GetString returns a stored value as a string, or "" if it's not set.
+func (c *ContentItem) GetString(key string) string {
+	switch v := c.Get(key).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// GetInt returns a stored value as an int, or 0 if it's not set or not a
+// number.
+func (c *ContentItem) GetInt(key string) int {
+	return toInt(c.Get(key))
+}
+
+// GetBool returns a stored value as a bool, or false if it's not set or not
+// a bool.
+func (c *ContentItem) GetBool(key string) bool {
+	b, _ := c.Get(key).(bool)
+	return b
+}
+
+// ExtraMap returns Extra as a map, if the metadata processor returned one
+// (as decoded from YAML or JSON). Returns nil otherwise.
+func (c *ContentItem) ExtraMap() map[string]interface{} {
+	switch v := c.Extra.(type) {
+	case map[string]interface{}:
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = val
+		}
+		return m
+	}
+	return nil
+}
+
+// ExtraString returns a field of ExtraMap as a string.
+func (c *ContentItem) ExtraString(key string) string {
+	v, ok := c.ExtraMap()[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// ExtraInt returns a field of ExtraMap as an int.
+func (c *ContentItem) ExtraInt(key string) int {
+	return toInt(c.ExtraMap()[key])
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
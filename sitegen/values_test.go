@@ -0,0 +1,42 @@
+package sitegen
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	c := &ContentItem{}
+	assert(t, !c.Has("count"), "Unexpected value")
+	equals(t, c.Get("count"), nil)
+	equals(t, c.GetString("count"), "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Set("key"+strconv.Itoa(i), i)
+			c.Get("count")
+		}(i)
+	}
+	wg.Wait()
+
+	c.Set("count", 3)
+	c.Set("draft", true)
+	equals(t, c.GetInt("count"), 3)
+	equals(t, c.GetString("count"), "3")
+	equals(t, c.GetBool("draft"), true)
+	equals(t, c.GetInt("key9"), 9)
+}
+
+func TestExtraAccessors(t *testing.T) {
+	c := &ContentItem{Extra: map[interface{}]interface{}{"author": "Ruben", "year": 2015}}
+	equals(t, c.ExtraString("author"), "Ruben")
+	equals(t, c.ExtraInt("year"), 2015)
+	equals(t, c.ExtraString("missing"), "")
+
+	c = &ContentItem{Extra: 42}
+	assert(t, c.ExtraMap() == nil, "Expected nil map")
+}
@@ -0,0 +1,32 @@
+package sitegen
+
+import (
+	"html"
+	"strings"
+)
+
+// Reading speed used for ReadingTime, in words per minute.
+const wordsPerMinute = 200
+
+// Plain returns the rendered content as text, without tags and with
+// whitespace collapsed. Useful for search indexes.
+func (c *ContentItem) Plain() string {
+	text := html.UnescapeString(tagRegex.ReplaceAllString(string(c.Content), " "))
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// WordCount returns the number of words in the rendered content.
+func (c *ContentItem) WordCount() int {
+	return len(strings.Fields(c.Plain()))
+}
+
+// FuzzyWordCount returns the word count rounded up to the next hundred, for
+// "about 600 words".
+func (c *ContentItem) FuzzyWordCount() int {
+	return (c.WordCount() + 99) / 100 * 100
+}
+
+// ReadingTime returns the estimated reading time in minutes, rounded up.
+func (c *ContentItem) ReadingTime() int {
+	return (c.WordCount() + wordsPerMinute - 1) / wordsPerMinute
+}
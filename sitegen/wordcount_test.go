@@ -0,0 +1,27 @@
+package sitegen
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestWordCount(t *testing.T) {
+	c := &ContentItem{
+		Content: template.HTML("<h1>Hello world</h1><p>One <em>two</em>&nbsp;three.</p><p>Four</p>"),
+	}
+	equals(t, c.Plain(), "Hello world One two three. Four")
+	equals(t, c.WordCount(), 6)
+	equals(t, c.FuzzyWordCount(), 100)
+	equals(t, c.ReadingTime(), 1)
+
+	c.Content = template.HTML("<p>" + strings.Repeat("word ", 401) + "</p>")
+	equals(t, c.WordCount(), 401)
+	equals(t, c.FuzzyWordCount(), 500)
+	equals(t, c.ReadingTime(), 3)
+
+	c.Content = ""
+	equals(t, c.WordCount(), 0)
+	equals(t, c.FuzzyWordCount(), 0)
+	equals(t, c.ReadingTime(), 0)
+}